@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/capture"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// recordedTopics returns the resource names of the topics given and of the
+// topics of the subscriptions given, looked up in the configuration or in
+// the emulator.
+func recordedTopics(client utils.ClientInterface, configuration internal.Configuration, project string, topics, subscriptions []string) ([]string, error) {
+	resourceNames := []string{}
+	seen := map[string]bool{}
+	add := func(resourceName string) {
+		if !seen[resourceName] {
+			seen[resourceName] = true
+			resourceNames = append(resourceNames, resourceName)
+		}
+	}
+
+	for _, topic := range topics {
+		if strings.HasPrefix(topic, "projects/") {
+			add(topic)
+		} else {
+			add(pubsub.GetResourceNameForTopic(project, topic))
+		}
+	}
+
+	for _, subscription := range subscriptions {
+		if topic, exists := configuration.SubscriptionTopic(project, subscription); exists {
+			add(pubsub.GetResourceNameForTopic(project, topic))
+			continue
+		}
+		topicResourceName, err := pubsub.GetSubscriptionTopic(client, project, pubsub.GetResourceNameForSubscription(project, subscription))
+		if err != nil {
+			return nil, fmt.Errorf("subscription '%s': %w", subscription, err)
+		}
+		add(topicResourceName)
+	}
+
+	return resourceNames, nil
+}
+
+func splitList(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, ",")
+}
+
+// runRecordCommand writes the messages published to the topics of the given
+// subscriptions to a capture file, until SIGINT/SIGTERM.
+func runRecordCommand(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project of the subscriptions, the first project of the configuration when empty")
+	subscriptions := flags.String("subscriptions", "", "Subscriptions whose topics are recorded, separated by commas")
+	topics := flags.String("topics", "", "Topics recorded, separated by commas")
+	output := flags.String("output", "", "Capture file written (JSON Lines), - for stdout")
+	maxMessages := flags.Int("max", 100, "Maximum number of messages pulled at once")
+	intervalMs := flags.Int("intervalMs", 500, "Time between pulls when there are no messages")
+	duration := flags.Duration("duration", 0, "Time recording, like 30s or 5m, until SIGINT/SIGTERM when 0")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return fmt.Errorf("the 'output' flag is required")
+	}
+	if *subscriptions == "" && *topics == "" {
+		return fmt.Errorf("either the 'subscriptions' or the 'topics' flag is required")
+	}
+	if *project, err = defaultProject(configuration, *project); err != nil {
+		return err
+	}
+
+	client := utils.NewClient(configuration.Host, "v1")
+	topicResourceNames, err := recordedTopics(client, configuration, *project, splitList(*topics), splitList(*subscriptions))
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	recorded, err := capture.Record(ctx, client, *project, topicResourceNames, writer, capture.RecordOptions{
+		MaxMessages: *maxMessages,
+		Interval:    time.Duration(*intervalMs) * time.Millisecond,
+	})
+	Llog.Info(fmt.Sprintf("Recorded %d messages", recorded))
+	return err
+}
+
+// runReplayCommand publishes the messages of a capture file again.
+func runReplayCommand(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project the messages are published to, the recorded one when empty")
+	input := flags.String("input", "", "Capture file written by record")
+	topicMap := flags.String("map", "", "Topics the recorded ones are published to, as recorded=target pairs separated by commas")
+	timing := flags.Bool("timing", false, "Wait between messages the time between their original publish times")
+	speed := flags.Float64("speed", 1, "Speed factor applied to the original timing")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	if *input == "" {
+		return fmt.Errorf("the 'input' flag is required")
+	}
+	topics, err := parseKeyValues(*topicMap)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	entries, err := capture.ReadEntries(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *input, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := utils.NewClient(configuration.Host, "v1")
+	replayed, err := capture.Replay(ctx, client, entries, capture.ReplayOptions{
+		Project:        *project,
+		TopicMap:       topics,
+		PreserveTiming: *timing,
+		Speed:          *speed,
+	})
+	Llog.Info(fmt.Sprintf("Replayed %d of %d messages", replayed, len(entries)))
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+type command struct {
+	Description string
+	Run         func(args []string) error
+}
+
+// commands are the subcommands accepted as first argument. When none of them
+// is given, the default behaviour (syncing the configuration) is executed.
+var commands = map[string]command{
+	"dump": {
+		Description: "Write the messages of a subscription to JSON Lines, CSV or a file per message",
+		Run:         runDumpCommand,
+	},
+	"export": {
+		Description: "Export the configuration to other formats (terraform)",
+		Run:         runExportCommand,
+	},
+	"graph": {
+		Description: "Render the configured or live topology as a Mermaid or Graphviz DOT diagram",
+		Run:         runGraphCommand,
+	},
+	"import": {
+		Description: "Import a configuration from other formats (gcloud)",
+		Run:         runImportCommand,
+	},
+	"loadgen": {
+		Description: "Publish generated messages at a given rate, reporting the throughput and latencies",
+		Run:         runLoadgenCommand,
+	},
+	"publish": {
+		Description: "Publish messages written as JSON, encoded with the schema of the topic",
+		Run:         runPublishCommand,
+	},
+	"pull": {
+		Description: "Print the messages of a subscription with their payloads decoded",
+		Run:         runPullCommand,
+	},
+	"purge": {
+		Description: "Remove the pending messages of subscriptions, keeping them and their settings",
+		Run:         runPurgeCommand,
+	},
+	"record": {
+		Description: "Record the messages published to topics to a capture file",
+		Run:         runRecordCommand,
+	},
+	"replay": {
+		Description: "Publish the messages of a capture file again",
+		Run:         runReplayCommand,
+	},
+	"schema": {
+		Description: "Manage schemas (revisions, rollback, delete-revision, check-compat, validate-message)",
+		Run:         runSchemaCommand,
+	},
+	"serve": {
+		Description: "Serve a JSON API to sync, plan, list, publish, pull, purge and snapshot over HTTP",
+		Run:         runServeCommand,
+	},
+	"wait": {
+		Description: "Wait until the emulator (and optionally the configuration) is ready",
+		Run:         runWaitCommand,
+	},
+}
+
+func printCommands() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n    \t%s\n", name, commands[name].Description)
+	}
+}
+
+// loadConfiguration loads the configuration file and applies the host
+// override when it is given.
+func loadConfiguration(configFile string, host string) (internal.Configuration, error) {
+	Llog.Debug(fmt.Sprintf("Using as 'config' flag value '%s'", configFile))
+	Llog.Debug(fmt.Sprintf("Using as 'host' flag value '%v'", host))
+
+	configuration, err := internal.LoadConfigurationFromFile(&utils.FileReader{}, configFile)
+	if err != nil {
+		return internal.Configuration{}, fmt.Errorf("there was an error when trying to load the configuration file: %w", err)
+	}
+
+	if host != "" {
+		Llog.Debug(
+			fmt.Sprintf(
+				"Host given, trying to replace actual value from '%s' to '%s'",
+				configuration.Host,
+				host,
+			),
+		)
+		if configuration, err = configuration.ReplaceHost(host); err != nil {
+			return internal.Configuration{}, err
+		}
+		Llog.Debug(fmt.Sprintf("Using host '%s'", host))
+	}
+
+	return configuration, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/dump"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// runDumpCommand writes the messages of a subscription, draining it or just
+// peeking them, with their data decoded.
+func runDumpCommand(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project of the subscription, the first project of the configuration when empty")
+	subscription := flags.String("subscription", "", "Subscription dumped")
+	format := flags.String("format", dump.FORMAT_JSONL, "Output format: jsonl, csv or files (a file per message)")
+	output := flags.String("output", "-", "File written, - for stdout. Directory written with the files format")
+	peek := flags.Bool("peek", false, "Make the messages available again instead of acknowledging them")
+	maxMessages := flags.Int("max", 0, "Maximum number of messages dumped, every message when 0")
+	batchSize := flags.Int("batch", 100, "Maximum number of messages pulled at once")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	if *subscription == "" {
+		return fmt.Errorf("the 'subscription' flag is required")
+	}
+	if *project, err = defaultProject(configuration, *project); err != nil {
+		return err
+	}
+
+	if *format == dump.FORMAT_FILES && *output == "-" {
+		return fmt.Errorf("the 'output' flag must be a directory with the %s format", dump.FORMAT_FILES)
+	}
+
+	var outputWriter io.Writer = os.Stdout
+	if *format != dump.FORMAT_FILES && *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		outputWriter = file
+	}
+
+	writer, err := dump.NewWriter(*format, outputWriter, *output)
+	if err != nil {
+		return err
+	}
+
+	client := utils.NewClient(configuration.Host, "v1")
+	dumped, err := dump.Dump(client, *project, pubsub.GetResourceNameForSubscription(*project, *subscription),
+		dump.Options{Ack: !*peek, MaxMessages: *maxMessages, BatchSize: *batchSize},
+		func(message pubsub.PubsubMessage) internal.DecodedMessage {
+			return configuration.DecodeReceivedMessage(*project, *subscription, message)
+		},
+		writer,
+	)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	action := "Drained"
+	if *peek {
+		action = "Peeked"
+	}
+	Llog.Info(fmt.Sprintf("%s %d messages of subscription '%s'", action, dumped, *subscription))
+	return err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/export"
+)
+
+func runExportCommand(args []string) error {
+	if len(args) == 0 || args[0] != "terraform" {
+		return fmt.Errorf("use: %s export terraform [options]", os.Args[0])
+	}
+
+	flags := flag.NewFlagSet("export terraform", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	output := flags.String("output", "", "File where the result is written, stdout when empty")
+	flags.Parse(args[1:])
+
+	configuration, err := loadConfiguration(*configFile, "")
+	if err != nil {
+		return err
+	}
+
+	hcl := export.Terraform(configuration)
+
+	if *output == "" {
+		fmt.Print(hcl)
+		return nil
+	}
+
+	return os.WriteFile(*output, []byte(hcl), 0644)
+}
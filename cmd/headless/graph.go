@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/graph"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// runGraphCommand renders the topology of the configuration, or the one of
+// the emulator, as a diagram.
+func runGraphCommand(args []string) error {
+	flags := flag.NewFlagSet("graph", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	live := flags.Bool("live", false, "Render the topology of the emulator instead of the configured one")
+	projects := flags.String("projects", "", "Projects rendered with -live, separated by commas, the ones of the configuration when empty")
+	format := flags.String("format", graph.FORMAT_MERMAID, "Format of the diagram: mermaid or dot")
+	output := flags.String("output", "", "File where the diagram is written, stdout when empty")
+	embed := flags.String("embed", "", "Markdown file where the diagram is written between the pubsub-graph markers")
+	check := flags.Bool("check", false, "With -embed, fail when the diagram of the file is outdated instead of writing it")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	topology := configuration.Projects
+	if *live {
+		projectNames := splitList(*projects)
+		if len(projectNames) == 0 {
+			for _, project := range configuration.Projects {
+				projectNames = append(projectNames, project.Name)
+			}
+		}
+
+		if topology, err = graph.ListProjects(utils.NewClient(configuration.Host, "v1"), projectNames); err != nil {
+			return err
+		}
+	} else if *projects != "" {
+		topology = []pubsub.Project{}
+		for _, projectName := range splitList(*projects) {
+			project, exists := findConfiguredProject(configuration.Projects, projectName)
+			if !exists {
+				return fmt.Errorf("project '%s' is not in the configuration", projectName)
+			}
+			topology = append(topology, project)
+		}
+	}
+
+	diagram, err := graph.Build(topology).Render(*format)
+	if err != nil {
+		return err
+	}
+
+	if *embed == "" {
+		if *output == "" {
+			fmt.Print(diagram)
+			return nil
+		}
+		return os.WriteFile(*output, []byte(diagram), 0644)
+	}
+
+	document, err := os.ReadFile(*embed)
+	if err != nil {
+		return err
+	}
+	embedded, err := graph.Embed(string(document), diagram, *format)
+	if err != nil {
+		return fmt.Errorf("'%s': %w", *embed, err)
+	}
+	if *check {
+		if embedded != string(document) {
+			return fmt.Errorf("the diagram of '%s' is outdated, run the graph command with -embed to update it", *embed)
+		}
+		return nil
+	}
+	return os.WriteFile(*embed, []byte(embedded), 0644)
+}
+
+func findConfiguredProject(projects []pubsub.Project, name string) (pubsub.Project, bool) {
+	for _, project := range projects {
+		if project.Name == name {
+			return project, true
+		}
+	}
+	return pubsub.Project{}, false
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/importer"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+func runImportCommand(args []string) error {
+	if len(args) == 0 || args[0] != "gcloud" {
+		return fmt.Errorf("use: %s import gcloud [options]", os.Args[0])
+	}
+
+	flags := flag.NewFlagSet("import gcloud", flag.ExitOnError)
+	project := flags.String("project", "", "Project where every imported resource is placed")
+	topicsFile := flags.String("topics", "", "Output of 'gcloud pubsub topics list --format=json'")
+	subscriptionsFile := flags.String("subscriptions", "", "Output of 'gcloud pubsub subscriptions list --format=json'")
+	schemasFile := flags.String("schemas", "", "Output of 'gcloud pubsub schemas list --format=json'")
+	output := flags.String("output", "", "File where the configuration is written, stdout when empty")
+	flags.Parse(args[1:])
+
+	configuration, err := importer.FromGcloud(
+		&utils.FileReader{},
+		*project,
+		importer.GcloudFiles{
+			Topics:        *topicsFile,
+			Subscriptions: *subscriptionsFile,
+			Schemas:       *schemasFile,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		fmt.Println(configuration.String())
+		return nil
+	}
+
+	return os.WriteFile(*output, []byte(configuration.String()+"\n"), 0644)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/fixtures"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/loadgen"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// runLoadgenCommand publishes generated messages at the given rate and
+// reports the throughput and publish latencies achieved.
+func runLoadgenCommand(args []string) error {
+	flags := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project of the topics, the first project of the configuration when empty")
+	topics := flags.String("topics", "", "Topics to publish to, separated by commas")
+	rate := flags.Float64("rate", 100, "Messages published per second, 0 for no limit")
+	burst := flags.Int("burst", 1, "Messages that can be published at once above the rate")
+	duration := flags.Duration("duration", 0, "Time publishing, like 30s or 5m, until SIGINT/SIGTERM when 0")
+	messages := flags.Int("messages", 0, "Messages to publish, 0 for no limit")
+	publishers := flags.Int("publishers", 4, "Concurrent publishers")
+	size := flags.Int("size", 256, "Size in bytes of the random text published when there is no template")
+	template := flags.String("template", "", "Template of the data of the messages, as the template of the messages of the configuration")
+	attributes := flags.String("attributes", "", "Attributes of the messages as key=value pairs separated by commas, templates as well")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	if *topics == "" {
+		return fmt.Errorf("the 'topics' flag is required")
+	}
+	if *project, err = defaultProject(configuration, *project); err != nil {
+		return err
+	}
+
+	messageAttributes, err := parseKeyValues(*attributes)
+	if err != nil {
+		return err
+	}
+	if *template == "" {
+		*template = fmt.Sprintf("{{ randString %d }}", *size)
+	}
+	messageTemplate, err := fixtures.NewGenerator(configuration.TemplateSeed).NewMessageTemplate(pubsub.Message{Template: *template, Attributes: messageAttributes})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := utils.NewClient(configuration.Host, "v1")
+	options := loadgen.Options{
+		Topics:     strings.Split(*topics, ","),
+		Rate:       *rate,
+		Burst:      *burst,
+		Duration:   *duration,
+		Messages:   *messages,
+		Publishers: *publishers,
+	}
+
+	Llog.Info(fmt.Sprintf("Publishing to %s with %d publishers", strings.Join(options.Topics, ", "), options.Publishers))
+	report, err := loadgen.Run(ctx, options,
+		func(index int) (pubsub.Message, error) {
+			return messageTemplate.Render(index, options.Messages)
+		},
+		func(topic string, message pubsub.Message) error {
+			_, err := configuration.PublishMessages(client, *project, topic, []pubsub.Message{message})
+			return err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	Llog.Info(fmt.Sprintf("Load generator %s", report))
+	if report.Failed > 0 {
+		Llog.Warn(fmt.Sprintf("First publish error: %s", report.FirstError))
+		if report.Published == 0 {
+			return fmt.Errorf("every message failed to be published")
+		}
+	}
+	return nil
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/emulator"
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
@@ -14,20 +20,37 @@ import (
 func main() {
 	Llog.Init()
 
+	if len(os.Args) > 1 {
+		if command, exists := commands[os.Args[1]]; exists {
+			if err := command.Run(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	configFile := flag.String("config", "./config.json", "Path to the json configuration")
 	host := flag.String("host", "", "Host to replace the one in the configuration file")
 	showHelp := flag.Bool("help", false, "Show help")
+	reconcile := flag.Bool("reconcile", false, "Update the emulator in place instead of removing everything first, preserving the messages")
+	watch := flag.Bool("watch", false, "Keep running and apply the changes done to the configuration files")
+	watchIntervalMs := flag.Int("watchIntervalMs", 1000, "Time between checks of the configuration files in watch mode")
+	daemon := flag.Bool("daemon", false, "Keep running and report the drift between the emulator and the configuration")
+	daemonIntervalMs := flag.Int("daemonIntervalMs", 5000, "Time between drift checks in daemon mode")
+	heal := flag.Bool("heal", false, "In daemon mode, create again the missing resources")
+	startEmulator := flag.Bool("startEmulator", false, "Start the emulator on a free port, provision it and keep it running until SIGINT/SIGTERM")
+	emulatorPath := flag.String("emulatorPath", "", "Executable or jar of the emulator to start, gcloud when empty")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Use: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Use: %s [command] [options]\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "Options:")
 		flag.PrintDefaults()
+		printCommands()
 	}
 
 	flag.Parse()
 
-	Llog.Debug(fmt.Sprintf("Using as 'config' flag value '%s'", *configFile))
-	Llog.Debug(fmt.Sprintf("Using as 'host' flag value '%v'", *host))
 	Llog.Debug(fmt.Sprintf("Using as 'showHelp' flag value '%v'", *showHelp))
 
 	if *showHelp {
@@ -36,27 +59,93 @@ func main() {
 		os.Exit(0)
 	}
 
-	configuration, err := internal.LoadConfigurationFromFile(&utils.FileReader{}, *configFile)
+	err := run(runOptions{
+		configFile:     *configFile,
+		host:           *host,
+		reconcile:      *reconcile,
+		watch:          *watch,
+		watchInterval:  time.Duration(*watchIntervalMs) * time.Millisecond,
+		daemon:         *daemon,
+		daemonInterval: time.Duration(*daemonIntervalMs) * time.Millisecond,
+		heal:           *heal,
+		startEmulator:  *startEmulator,
+		emulatorPath:   *emulatorPath,
+	})
 	if err != nil {
-		fmt.Println("There was an error when trying to load the configuration file:")
 		fmt.Println(err)
 		os.Exit(1)
 	}
+}
+
+type runOptions struct {
+	configFile     string
+	host           string
+	reconcile      bool
+	watch          bool
+	watchInterval  time.Duration
+	daemon         bool
+	daemonInterval time.Duration
+	heal           bool
+	startEmulator  bool
+	emulatorPath   string
+}
+
+// run syncs the configuration and keeps running in watch or daemon mode, or
+// while the emulator started by the helper runs, until SIGINT/SIGTERM.
+func run(options runOptions) error {
+	ctx := context.Background()
+	if options.watch || options.daemon || options.startEmulator {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+
+	configuration, err := loadConfiguration(options.configFile, options.host)
+	if err != nil {
+		return err
+	}
 
-	if *host != "" {
-		Llog.Debug(
-			fmt.Sprintf(
-				"Host given, trying to replace actual value from '%s' to '%s'",
-				configuration.Host,
-				*host,
-			),
-		)
-		configuration.ReplaceHost(*host)
-		Llog.Debug(fmt.Sprintf("Using host '%s'", *host))
+	// Stopping because the emulator exited is an error
+	exitedErr := func() error { return nil }
+	if options.startEmulator {
+		managed, err := emulator.Start(ctx, configuration.EmulatorOptions(options.emulatorPath))
+		if err != nil {
+			return err
+		}
+		defer managed.Stop()
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-managed.Exited():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		exitedErr = func() error {
+			select {
+			case <-managed.Exited():
+				return fmt.Errorf("the emulator exited unexpectedly")
+			default:
+				return nil
+			}
+		}
+
+		configuration.Host = managed.Host
+		Llog.Info(fmt.Sprintf("Emulator running on '%s', set PUBSUB_EMULATOR_HOST=%s to use it", managed.Host, managed.Host))
 	}
 
 	client := utils.NewClient(configuration.Host, "v1")
-	configuration.Sync(client)
+	if options.reconcile {
+		err = configuration.Reconcile(client)
+	} else {
+		err = configuration.Sync(client)
+	}
+	if err != nil {
+		return err
+	}
 
 	/**
 	  For debugging purposes, list topics and subscriptions
@@ -64,8 +153,7 @@ func main() {
 
 	topicsList, err := pubsub.ListTopics(client, configuration.Projects[0].Name)
 	if err != nil {
-		fmt.Println("There was some error while trying to list the topics")
-		os.Exit(1)
+		return fmt.Errorf("there was some error while trying to list the topics: %w", err)
 	}
 
 	for _, topic := range topicsList {
@@ -74,11 +162,58 @@ func main() {
 
 	subscriptionsList, err := pubsub.ListSubscriptions(client, configuration.Projects[0].Name)
 	if err != nil {
-		fmt.Println("There was some error while trying to list the subscriptions")
-		os.Exit(1)
+		return fmt.Errorf("there was some error while trying to list the subscriptions: %w", err)
 	}
 
 	for _, subscription := range subscriptionsList {
 		Llog.Debug(subscription.String())
 	}
+
+	if !options.watch && !options.daemon {
+		if options.startEmulator {
+			Llog.Info("Emulator provisioned, running until SIGINT/SIGTERM")
+			<-ctx.Done()
+		}
+		return exitedErr()
+	}
+
+	var wg sync.WaitGroup
+	driftDaemon := &internal.DriftDaemon{
+		Interval: options.daemonInterval,
+		Heal:     options.heal,
+	}
+	driftDaemon.SetConfiguration(configuration)
+
+	if options.daemon {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			driftDaemon.Run(ctx, client)
+		}()
+	}
+
+	if options.watch {
+		managedHost := configuration.Host
+		watcher := internal.ConfigurationWatcher{
+			FileReader: &utils.FileReader{},
+			Load: func() (internal.Configuration, error) {
+				configuration, err := loadConfiguration(options.configFile, options.host)
+				if options.startEmulator {
+					configuration.Host = managedHost
+				}
+				return configuration, err
+			},
+			Interval:  options.watchInterval,
+			OnApplied: driftDaemon.SetConfiguration,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watcher.Watch(ctx, client, configuration)
+		}()
+	}
+
+	wg.Wait()
+	return exitedErr()
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// defaultProject returns the project given or the first one of the
+// configuration.
+func defaultProject(configuration internal.Configuration, project string) (string, error) {
+	if project != "" {
+		return project, nil
+	}
+	if len(configuration.Projects) == 0 {
+		return "", fmt.Errorf("the 'project' flag is required when the configuration has no projects")
+	}
+	return configuration.Projects[0].Name, nil
+}
+
+// parseKeyValues parses key=value pairs separated by commas, like the
+// attributes of a message.
+func parseKeyValues(text string) (map[string]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	values := map[string]string{}
+	for _, pair := range strings.Split(text, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid pair '%s', use key=value", pair)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// runPublishCommand publishes messages written as JSON, encoding them with
+// the schema and encoding of the topic.
+func runPublishCommand(args []string) error {
+	flags := flag.NewFlagSet("publish", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project of the topic, the first project of the configuration when empty")
+	topic := flags.String("topic", "", "Topic to publish to")
+	data := flags.String("data", "", "Data of the message, JSON encoded with the schema of the topic")
+	messagesFile := flags.String("messagesFile", "", "JSON array or JSON Lines file with the messages to publish")
+	attributes := flags.String("attributes", "", "Attributes of the message as key=value pairs separated by commas")
+	orderingKey := flags.String("orderingKey", "", "Ordering key of the message")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	if *topic == "" {
+		return fmt.Errorf("the 'topic' flag is required")
+	}
+	if *project, err = defaultProject(configuration, *project); err != nil {
+		return err
+	}
+
+	messages := []pubsub.Message{}
+	if *data != "" {
+		messageAttributes, err := parseKeyValues(*attributes)
+		if err != nil {
+			return err
+		}
+
+		message := pubsub.Message{Data: json.RawMessage(*data), Attributes: messageAttributes, OrderingKey: *orderingKey}
+		if !json.Valid(message.Data) {
+			// Plain text
+			message.Data, _ = json.Marshal(*data)
+		}
+		messages = append(messages, message)
+	}
+	if *messagesFile != "" {
+		content, err := os.ReadFile(*messagesFile)
+		if err != nil {
+			return err
+		}
+		fileMessages, err := internal.ParseMessages(content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *messagesFile, err)
+		}
+		messages = append(messages, fileMessages...)
+	}
+	if messages, err = configuration.ExpandMessages(messages); err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("either the 'data' or the 'messagesFile' flag is required")
+	}
+
+	client := utils.NewClient(configuration.Host, "v1")
+	messageIds, err := configuration.PublishMessages(client, *project, *topic, messages)
+	if err != nil {
+		return err
+	}
+
+	Llog.Info(fmt.Sprintf("Published %d messages to topic '%s': %s", len(messageIds), *topic, strings.Join(messageIds, ", ")))
+	return nil
+}
+
+// runPullCommand prints the messages of a subscription as JSON Lines, with
+// the payloads decoded with the schema of the topic.
+func runPullCommand(args []string) error {
+	flags := flag.NewFlagSet("pull", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project of the subscription, the first project of the configuration when empty")
+	subscription := flags.String("subscription", "", "Subscription to pull from")
+	maxMessages := flags.Int("max", 10, "Maximum number of messages pulled at once")
+	ack := flags.Bool("ack", false, "Acknowledge the messages pulled")
+	follow := flags.Bool("follow", false, "Keep pulling until SIGINT/SIGTERM")
+	intervalMs := flags.Int("intervalMs", 1000, "Time between pulls when following")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	if *subscription == "" {
+		return fmt.Errorf("the 'subscription' flag is required")
+	}
+	if *project, err = defaultProject(configuration, *project); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := utils.NewClient(configuration.Host, "v1")
+	subscriptionResourceName := pubsub.GetResourceNameForSubscription(*project, *subscription)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+
+	// Messages not acknowledged are delivered again, they are printed once
+	printed := map[string]bool{}
+
+	for {
+		received, err := pubsub.Pull(client, *project, subscriptionResourceName, *maxMessages)
+		if err != nil {
+			return err
+		}
+
+		ackIds := []string{}
+		for _, receivedMessage := range received {
+			ackIds = append(ackIds, receivedMessage.AckId)
+			if printed[receivedMessage.Message.MessageId] {
+				continue
+			}
+			printed[receivedMessage.Message.MessageId] = true
+
+			if err := encoder.Encode(configuration.DecodeReceivedMessage(*project, *subscription, receivedMessage.Message)); err != nil {
+				return err
+			}
+		}
+
+		if len(ackIds) > 0 {
+			if *ack {
+				err = pubsub.Acknowledge(client, *project, subscriptionResourceName, ackIds)
+			} else {
+				// Available again right away for the real consumers
+				err = pubsub.ModifyAckDeadline(client, *project, subscriptionResourceName, ackIds, 0)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if !*follow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Duration(*intervalMs) * time.Millisecond):
+		}
+	}
+}
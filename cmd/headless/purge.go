@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// runPurgeCommand removes the pending messages of subscriptions, keeping the
+// subscriptions and their settings.
+func runPurgeCommand(args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	project := flags.String("project", "", "Project of the subscriptions and topics, the first project of the configuration when empty")
+	subscriptions := flags.String("subscriptions", "", "Subscriptions purged, separated by commas")
+	topics := flags.String("topics", "", "Topics whose subscriptions in the emulator are purged, separated by commas")
+	method := flags.String("method", internal.PURGE_METHOD_AUTO, "How the messages are removed: auto, seek (to now) or pull (and acknowledge)")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	client := utils.NewClient(configuration.Host, "v1")
+
+	targets := []internal.PurgeTarget{}
+	if *subscriptions == "" && *topics == "" {
+		// Every subscription of the configuration
+		targets = configuration.PurgeTargets()
+	} else {
+		if *project, err = defaultProject(configuration, *project); err != nil {
+			return err
+		}
+		for _, subscription := range splitList(*subscriptions) {
+			targets = append(targets, internal.PurgeTarget{Project: *project, Subscription: subscription})
+		}
+		for _, topic := range splitList(*topics) {
+			topicTargets, err := internal.TopicPurgeTargets(client, *project, topic)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, topicTargets...)
+		}
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("there are no subscriptions to purge")
+	}
+
+	return configuration.Purge(client, targets, *method)
+}
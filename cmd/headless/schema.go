@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/schema"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// schemaCommands are the subcommands of the schema command.
+var schemaCommands = map[string]command{
+	"revisions": {
+		Description: "List the revisions committed to a schema",
+		Run:         runSchemaRevisionsCommand,
+	},
+	"rollback": {
+		Description: "Commit again the definition of a revision of a schema",
+		Run:         runSchemaRollbackCommand,
+	},
+	"delete-revision": {
+		Description: "Delete a revision of a schema",
+		Run:         runSchemaDeleteRevisionCommand,
+	},
+	"check-compat": {
+		Description: "Check the compatibility between consecutive revisions of schemas",
+		Run:         runSchemaCheckCompatCommand,
+	},
+	"validate-message": {
+		Description: "Validate messages against a schema without an emulator",
+		Run:         runSchemaValidateMessageCommand,
+	},
+}
+
+func runSchemaCommand(args []string) error {
+	if len(args) > 0 {
+		if subcommand, exists := schemaCommands[args[0]]; exists {
+			return subcommand.Run(args[1:])
+		}
+	}
+
+	names := make([]string, 0, len(schemaCommands))
+	for name := range schemaCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("use: %s schema <%s> [options]", os.Args[0], strings.Join(names, "|"))
+}
+
+// schemaFlags are the flags shared by the schema subcommands.
+type schemaFlags struct {
+	configFile *string
+	host       *string
+	project    *string
+	schema     *string
+}
+
+func newSchemaFlags(flags *flag.FlagSet) schemaFlags {
+	return schemaFlags{
+		configFile: flags.String("config", "./config.json", "Path to the json configuration"),
+		host:       flags.String("host", "", "Host to replace the one in the configuration file"),
+		project:    flags.String("project", "", "Project of the schema, the first project of the configuration when empty"),
+		schema:     flags.String("schema", "", "Id or name of the schema"),
+	}
+}
+
+// load returns the configuration, the client and the project and id of the
+// schema the subcommand works with.
+func (f schemaFlags) load() (internal.Configuration, utils.ClientInterface, string, string, error) {
+	configuration, err := loadConfiguration(*f.configFile, *f.host)
+	if err != nil {
+		return internal.Configuration{}, nil, "", "", err
+	}
+
+	if *f.schema == "" {
+		return internal.Configuration{}, nil, "", "", fmt.Errorf("the 'schema' flag is required")
+	}
+
+	project := *f.project
+	if project == "" {
+		if len(configuration.Projects) == 0 {
+			return internal.Configuration{}, nil, "", "", fmt.Errorf("the 'project' flag is required when the configuration has no projects")
+		}
+		project = configuration.Projects[0].Name
+	}
+
+	client := utils.NewClient(configuration.Host, "v1")
+	return configuration, client, project, configuration.SchemaId(project, *f.schema), nil
+}
+
+func runSchemaRevisionsCommand(args []string) error {
+	flags := flag.NewFlagSet("schema revisions", flag.ExitOnError)
+	sharedFlags := newSchemaFlags(flags)
+	flags.Parse(args)
+
+	configuration, client, project, schemaId, err := sharedFlags.load()
+	if err != nil {
+		return err
+	}
+
+	revisions, err := pubsub.ListSchemaRevisions(client, project, schemaId)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "REVISION ID\tCREATED\tALIAS")
+	for _, revision := range revisions {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", revision.RevisionId, revision.RevisionCreateTime, configuration.SchemaRevisionAlias(project, schemaId, revision))
+	}
+	return writer.Flush()
+}
+
+func runSchemaRollbackCommand(args []string) error {
+	flags := flag.NewFlagSet("schema rollback", flag.ExitOnError)
+	sharedFlags := newSchemaFlags(flags)
+	revision := flags.String("revision", "", "Alias or id of the revision to roll back to")
+	flags.Parse(args)
+
+	configuration, client, project, schemaId, err := sharedFlags.load()
+	if err != nil {
+		return err
+	}
+
+	revisionId, err := configuration.ResolveSchemaRevision(client, project, schemaId, *revision)
+	if err != nil {
+		return err
+	}
+	if revisionId == "" {
+		return fmt.Errorf("the 'revision' flag is required")
+	}
+
+	committed, err := pubsub.RollbackSchema(client, project, schemaId, revisionId)
+	if err != nil {
+		return err
+	}
+
+	Llog.Info(fmt.Sprintf("Schema '%s' rolled back to revision '%s', committed as '%s'", schemaId, revisionId, committed.RevisionId))
+	return nil
+}
+
+func runSchemaDeleteRevisionCommand(args []string) error {
+	flags := flag.NewFlagSet("schema delete-revision", flag.ExitOnError)
+	sharedFlags := newSchemaFlags(flags)
+	revision := flags.String("revision", "", "Alias or id of the revision to delete")
+	flags.Parse(args)
+
+	configuration, client, project, schemaId, err := sharedFlags.load()
+	if err != nil {
+		return err
+	}
+
+	revisionId, err := configuration.ResolveSchemaRevision(client, project, schemaId, *revision)
+	if err != nil {
+		return err
+	}
+	if revisionId == "" {
+		return fmt.Errorf("the 'revision' flag is required")
+	}
+
+	if err := pubsub.DeleteSchemaRevision(client, project, schemaId, revisionId); err != nil {
+		return err
+	}
+
+	Llog.Info(fmt.Sprintf("Revision '%s' of schema '%s' deleted", revisionId, schemaId))
+	return nil
+}
+
+// runSchemaValidateMessageCommand validates the payload of every file given
+// against a definition file or the schema of a topic of the configuration.
+// Without files, loading the configuration validates the messages of its
+// topics.
+func runSchemaValidateMessageCommand(args []string) error {
+	flags := flag.NewFlagSet("schema validate-message", flag.ExitOnError)
+	definitionFile := flags.String("definitionFile", "", "Avro (.avsc) or Protocol Buffers (.proto) definition to validate against")
+	schemaType := flags.String("type", "", "Type of the definition (AVRO or PROTOCOL_BUFFER), taken from the extension when empty")
+	encoding := flags.String("encoding", schema.ENCODING_JSON, "Encoding of the messages (JSON or BINARY)")
+	configFile := flags.String("config", "", "Path to the json configuration, to validate against the schema of a topic")
+	project := flags.String("project", "", "Project of the topic, the first project of the configuration when empty")
+	topic := flags.String("topic", "", "Topic whose schema the messages are validated against")
+	flags.Parse(args)
+
+	var validate func(payload []byte) error
+
+	switch {
+	case *definitionFile != "":
+		if *schemaType == "" {
+			*schemaType = schema.TypeForFile(*definitionFile)
+		}
+
+		content, err := os.ReadFile(*definitionFile)
+		if err != nil {
+			return err
+		}
+
+		definition, err := schema.Parse(*schemaType, string(content))
+		if err != nil {
+			return fmt.Errorf("%s: %w", *definitionFile, err)
+		}
+
+		validate = func(payload []byte) error {
+			return definition.ValidateMessage(*encoding, payload)
+		}
+	case *configFile != "":
+		configuration, err := loadConfiguration(*configFile, "")
+		if err != nil {
+			return err
+		}
+
+		if flags.NArg() == 0 {
+			Llog.Info("The messages of the configuration are valid")
+			return nil
+		}
+
+		if *topic == "" {
+			return fmt.Errorf("the 'topic' flag is required to validate files against a configuration")
+		}
+		if *project == "" && len(configuration.Projects) > 0 {
+			*project = configuration.Projects[0].Name
+		}
+
+		validate = func(payload []byte) error {
+			return configuration.ValidateMessage(*project, *topic, payload)
+		}
+	default:
+		return fmt.Errorf("either the 'definitionFile' or the 'config' flag is required")
+	}
+
+	invalid := 0
+	for _, messageFile := range flags.Args() {
+		payload, err := os.ReadFile(messageFile)
+		if err != nil {
+			return err
+		}
+
+		if err := validate(payload); err != nil {
+			invalid++
+			fmt.Printf("%s: invalid\n", messageFile)
+
+			var validationErr *schema.ValidationError
+			if errors.As(err, &validationErr) {
+				for _, problem := range validationErr.Problems {
+					fmt.Printf("  - %s\n", problem)
+				}
+			} else {
+				fmt.Printf("  - %s\n", err)
+			}
+			continue
+		}
+		fmt.Printf("%s: valid\n", messageFile)
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d messages are invalid", invalid, flags.NArg())
+	}
+	return nil
+}
+
+// runSchemaCheckCompatCommand checks the consecutive revisions of the schemas
+// of the configuration, or two definition files, printing the problems found.
+func runSchemaCheckCompatCommand(args []string) error {
+	flags := flag.NewFlagSet("schema check-compat", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	project := flags.String("project", "", "Project of the schemas, the first project of the configuration when empty")
+	schemaReference := flags.String("schema", "", "Id or name of the schema to check, every schema of the project when empty")
+	mode := flags.String("mode", "", "BACKWARD, FORWARD, FULL or NONE. The mode configured for each schema (or BACKWARD) when empty")
+	previousFile := flags.String("previous", "", "Definition file of the previous revision, to compare files instead of the configuration")
+	currentFile := flags.String("current", "", "Definition file of the current revision, to compare files instead of the configuration")
+	flags.Parse(args)
+
+	if *mode != "" {
+		parsedMode, err := schema.ParseCompatibility(*mode)
+		if err != nil {
+			return err
+		}
+		*mode = parsedMode
+	}
+
+	var problems []string
+
+	if *previousFile != "" || *currentFile != "" {
+		definitions := []*schema.Definition{}
+		for _, definitionFile := range []string{*previousFile, *currentFile} {
+			if definitionFile == "" {
+				return fmt.Errorf("both the 'previous' and the 'current' flags are required to compare files")
+			}
+
+			content, err := os.ReadFile(definitionFile)
+			if err != nil {
+				return err
+			}
+			definition, err := schema.Parse(schema.TypeForFile(definitionFile), string(content))
+			if err != nil {
+				return fmt.Errorf("%s: %w", definitionFile, err)
+			}
+			definitions = append(definitions, definition)
+		}
+
+		if *mode == "" {
+			*mode = schema.COMPATIBILITY_BACKWARD
+		}
+		problems = schema.CheckCompatibility(*mode, definitions[0], definitions[1])
+	} else {
+		configuration, err := loadConfiguration(*configFile, "")
+		if err != nil {
+			return err
+		}
+
+		if *project == "" {
+			if len(configuration.Projects) == 0 {
+				return fmt.Errorf("the 'project' flag is required when the configuration has no projects")
+			}
+			*project = configuration.Projects[0].Name
+		}
+
+		problems, err = configuration.CheckSchemaCompatibility(*project, *schemaReference, *mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, problem := range problems {
+		fmt.Printf("- %s\n", problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d compatibility problems found", len(problems))
+	}
+
+	Llog.Info("The schema revisions are compatible")
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/api"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// runServeCommand serves the JSON API driving the emulator until
+// SIGINT/SIGTERM.
+func runServeCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configFile := flags.String("config", "", "Path to the json configuration, an empty configuration until one is synced when empty")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	listen := flags.String("listen", "localhost:8090", "Address the API is served on")
+	sync := flags.Bool("sync", false, "Sync the configuration before serving")
+	flags.Parse(args)
+
+	var (
+		configuration internal.Configuration
+		err           error
+	)
+	if *configFile != "" {
+		configuration, err = loadConfiguration(*configFile, *host)
+	} else {
+		configuration, err = internal.ParseConfiguration(&utils.FileReader{}, []byte("{}"), "")
+		if err == nil && *host != "" {
+			configuration, err = configuration.ReplaceHost(*host)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	client := utils.NewClient(configuration.Host, "v1")
+	if *sync {
+		if err := configuration.Sync(client); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *listen, Handler: api.NewServer(client, configuration).Handler()}
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- server.ListenAndServe()
+	}()
+	defer server.Close()
+
+	Llog.Info(fmt.Sprintf("Serving the API of the emulator at '%s' on http://%s/api/", configuration.Host, *listen))
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-serverErrors:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/readiness"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+func runWaitCommand(args []string) error {
+	flags := flag.NewFlagSet("wait", flag.ExitOnError)
+	configFile := flags.String("config", "./config.json", "Path to the json configuration")
+	host := flags.String("host", "", "Host to replace the one in the configuration file")
+	topology := flags.Bool("topology", false, "Also wait until every topic and subscription of the configuration exists")
+	timeoutMs := flags.Int("timeoutMs", 0, "Maximum time waiting, 'startTimeoutMs' from the configuration when 0")
+	readyFile := flags.String("readyFile", "", "File created once ready")
+	listen := flags.String("listen", "", "Address serving GET /ready, it keeps running until SIGINT/SIGTERM when given")
+	flags.Parse(args)
+
+	configuration, err := loadConfiguration(*configFile, *host)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	marker := &readiness.Marker{}
+	serverErrors := make(chan error, 1)
+	if *listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/ready", marker)
+		server := &http.Server{Addr: *listen, Handler: mux}
+
+		go func() {
+			serverErrors <- server.ListenAndServe()
+		}()
+		defer server.Close()
+
+		Llog.Info(fmt.Sprintf("Serving readiness on http://%s/ready", *listen))
+	}
+
+	options := configuration.ReadinessOptions()
+	if *timeoutMs > 0 {
+		options.Timeout = time.Duration(*timeoutMs) * time.Millisecond
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	client := utils.NewClient(configuration.Host, "v1")
+	if err := readiness.WaitUntilReady(waitCtx, client, options); err != nil {
+		return err
+	}
+
+	if *topology {
+		if err := configuration.WaitUntilProvisioned(waitCtx, client, options.Interval); err != nil {
+			return err
+		}
+	}
+
+	Llog.Info("Emulator ready")
+	marker.SetReady()
+
+	if *readyFile != "" {
+		if err := readiness.WriteReadyFile(*readyFile); err != nil {
+			return err
+		}
+	}
+
+	if *listen == "" {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-serverErrors:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
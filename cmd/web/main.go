@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/web"
+)
+
+func main() {
+	Llog.Init()
+
+	configFile := flag.String("config", "", "Path to the json configuration, used for the host and the projects")
+	host := flag.String("host", "", "Host of the emulator, replacing the one in the configuration file")
+	projects := flag.String("projects", "", "Comma separated projects to browse, added to the ones of the configuration")
+	listen := flag.String("listen", "localhost:8080", "Address the UI is served on")
+	intervalMs := flag.Int("intervalMs", 1000, "Time between pulls of the live viewer")
+	flag.Parse()
+
+	if err := run(*configFile, *host, *projects, *listen, time.Duration(*intervalMs)*time.Millisecond); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(configFile, host, projectList, listen string, pullInterval time.Duration) error {
+	configuration := internal.Configuration{}
+	if configFile != "" {
+		loaded, err := internal.LoadConfigurationFromFile(&utils.FileReader{}, configFile)
+		if err != nil {
+			return fmt.Errorf("there was an error when trying to load the configuration file: %w", err)
+		}
+		configuration = loaded
+	}
+	if host != "" {
+		if !utils.IsValidHost(host) {
+			return fmt.Errorf("the given host '%s' is invalid", host)
+		}
+		configuration.Host = host
+	}
+	if configuration.Host == "" {
+		return fmt.Errorf("the host of the emulator is required, use -host or -config")
+	}
+
+	projects := []string{}
+	seen := map[string]bool{}
+	addProject := func(project string) {
+		if project != "" && !seen[project] {
+			seen[project] = true
+			projects = append(projects, project)
+		}
+	}
+	for _, project := range configuration.Projects {
+		addProject(project.Name)
+	}
+	for _, project := range splitList(projectList) {
+		addProject(project)
+	}
+	if len(projects) == 0 {
+		return fmt.Errorf("no projects to browse, use -projects or -config")
+	}
+
+	server := &web.Server{
+		Client:        utils.NewClient(configuration.Host, "v1"),
+		Projects:      projects,
+		Configuration: configuration,
+		PullInterval:  pullInterval,
+	}
+
+	Llog.Info(fmt.Sprintf("Serving the UI of the emulator at '%s' on http://%s", configuration.Host, listen))
+	return http.ListenAndServe(listen, server.Handler())
+}
+
+func splitList(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, ",")
+}
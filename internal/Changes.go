@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+type ChangeAction string
+
+const (
+	CHANGE_ACTION_CREATE   ChangeAction = "CREATE"
+	CHANGE_ACTION_DELETE   ChangeAction = "DELETE"
+	CHANGE_ACTION_RECREATE ChangeAction = "RECREATE"
+	CHANGE_ACTION_UPDATE   ChangeAction = "UPDATE"
+)
+
+type ResourceKind string
+
+const (
+	RESOURCE_KIND_SCHEMA       ResourceKind = "SCHEMA"
+	RESOURCE_KIND_TOPIC        ResourceKind = "TOPIC"
+	RESOURCE_KIND_SUBSCRIPTION ResourceKind = "SUBSCRIPTION"
+)
+
+// Change is a single operation required to move the emulator from one
+// configuration to another. Name is the short name of the resource (the id
+// for schemas) and Topic is only filled for subscriptions.
+type Change struct {
+	Action  ChangeAction `json:"action"`
+	Kind    ResourceKind `json:"kind"`
+	Project string       `json:"project"`
+	Topic   string       `json:"topic,omitempty"`
+	Name    string       `json:"name"`
+}
+
+func (c Change) String() string {
+	if c.Kind == RESOURCE_KIND_SUBSCRIPTION {
+		return fmt.Sprintf("%s %s %s/%s (topic %s)", c.Action, c.Kind, c.Project, c.Name, c.Topic)
+	}
+	return fmt.Sprintf("%s %s %s/%s", c.Action, c.Kind, c.Project, c.Name)
+}
+
+// schemaKey returns the id used to create the schema in the emulator.
+func schemaKey(schema pubsub.Schema) string {
+	if schema.Id != "" {
+		return schema.Id
+	}
+	return schema.Name
+}
+
+// topicWithoutSubscriptions allows comparing topic settings ignoring the
+// subscriptions, which are compared one by one, and the messages to seed,
+// which don't change the topic itself.
+func topicWithoutSubscriptions(topic pubsub.Topic) pubsub.Topic {
+	topic.Subscriptions = nil
+	topic.Messages = nil
+	topic.MessagesFile = ""
+	return topic
+}
+
+// DiffConfigurations returns the changes required to go from previous to
+// current. Deletions come first, then creations, so they can be applied in
+// order. Changed topics and subscriptions are updated in place, changed
+// schemas get their new revisions committed and are only recreated when
+// their type changes.
+func DiffConfigurations(previous, current Configuration) []Change {
+	deletions := []Change{}
+	creations := []Change{}
+
+	previousProjects := map[string]pubsub.Project{}
+	for _, project := range previous.Projects {
+		previousProjects[project.Name] = project
+	}
+
+	currentProjects := map[string]pubsub.Project{}
+	for _, project := range current.Projects {
+		currentProjects[project.Name] = project
+	}
+
+	for _, previousProject := range previous.Projects {
+		currentProject := currentProjects[previousProject.Name]
+
+		currentTopics := map[string]pubsub.Topic{}
+		for _, topic := range currentProject.Topics {
+			currentTopics[topic.Name] = topic
+		}
+
+		for _, previousTopic := range previousProject.Topics {
+			currentTopic, exists := currentTopics[previousTopic.Name]
+
+			currentSubscriptions := map[string]pubsub.Subscription{}
+			if exists {
+				for _, subscription := range currentTopic.Subscriptions {
+					currentSubscriptions[subscription.Name] = subscription
+				}
+			}
+
+			for _, subscription := range previousTopic.Subscriptions {
+				if _, stillExists := currentSubscriptions[subscription.Name]; !stillExists {
+					deletions = append(deletions, Change{CHANGE_ACTION_DELETE, RESOURCE_KIND_SUBSCRIPTION, previousProject.Name, previousTopic.Name, subscription.Name})
+				}
+			}
+
+			if !exists {
+				deletions = append(deletions, Change{CHANGE_ACTION_DELETE, RESOURCE_KIND_TOPIC, previousProject.Name, "", previousTopic.Name})
+			}
+		}
+
+		currentSchemas := map[string]bool{}
+		for _, schema := range currentProject.Schemas {
+			currentSchemas[schemaKey(schema)] = true
+		}
+
+		for _, schema := range previousProject.Schemas {
+			if !currentSchemas[schemaKey(schema)] {
+				deletions = append(deletions, Change{CHANGE_ACTION_DELETE, RESOURCE_KIND_SCHEMA, previousProject.Name, "", schemaKey(schema)})
+			}
+		}
+	}
+
+	for _, currentProject := range current.Projects {
+		previousProject := previousProjects[currentProject.Name]
+
+		previousSchemas := map[string]pubsub.Schema{}
+		for _, schema := range previousProject.Schemas {
+			previousSchemas[schemaKey(schema)] = schema
+		}
+
+		for _, schema := range currentProject.Schemas {
+			previousSchema, exists := previousSchemas[schemaKey(schema)]
+			switch {
+			case !exists:
+				creations = append(creations, Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_SCHEMA, currentProject.Name, "", schemaKey(schema)})
+			case previousSchema.Type != schema.Type:
+				creations = append(creations, Change{CHANGE_ACTION_RECREATE, RESOURCE_KIND_SCHEMA, currentProject.Name, "", schemaKey(schema)})
+			case !reflect.DeepEqual(previousSchema, schema):
+				// New revisions are committed to the existing schema
+				creations = append(creations, Change{CHANGE_ACTION_UPDATE, RESOURCE_KIND_SCHEMA, currentProject.Name, "", schemaKey(schema)})
+			}
+		}
+
+		previousTopics := map[string]pubsub.Topic{}
+		for _, topic := range previousProject.Topics {
+			previousTopics[topic.Name] = topic
+		}
+
+		for _, topic := range currentProject.Topics {
+			previousTopic, exists := previousTopics[topic.Name]
+
+			switch {
+			case !exists:
+				creations = append(creations, Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_TOPIC, currentProject.Name, "", topic.Name})
+			case !reflect.DeepEqual(topicWithoutSubscriptions(previousTopic), topicWithoutSubscriptions(topic)):
+				creations = append(creations, Change{CHANGE_ACTION_UPDATE, RESOURCE_KIND_TOPIC, currentProject.Name, "", topic.Name})
+			}
+
+			previousSubscriptions := map[string]pubsub.Subscription{}
+			for _, subscription := range previousTopic.Subscriptions {
+				previousSubscriptions[subscription.Name] = subscription
+			}
+
+			for _, subscription := range topic.Subscriptions {
+				previousSubscription, subscriptionExists := previousSubscriptions[subscription.Name]
+				switch {
+				case !subscriptionExists:
+					creations = append(creations, Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_SUBSCRIPTION, currentProject.Name, topic.Name, subscription.Name})
+				case !reflect.DeepEqual(previousSubscription, subscription):
+					creations = append(creations, Change{CHANGE_ACTION_UPDATE, RESOURCE_KIND_SUBSCRIPTION, currentProject.Name, topic.Name, subscription.Name})
+				}
+			}
+		}
+	}
+
+	return append(deletions, creations...)
+}
+
+func (c Configuration) findProject(name string) (pubsub.Project, bool) {
+	for _, project := range c.Projects {
+		if project.Name == name {
+			return project, true
+		}
+	}
+	return pubsub.Project{}, false
+}
+
+func findSchema(project pubsub.Project, id string) (pubsub.Schema, bool) {
+	for _, schema := range project.Schemas {
+		if schemaKey(schema) == id {
+			return schema, true
+		}
+	}
+	return pubsub.Schema{}, false
+}
+
+func findTopic(project pubsub.Project, name string) (pubsub.Topic, bool) {
+	for _, topic := range project.Topics {
+		if topic.Name == name {
+			return topic, true
+		}
+	}
+	return pubsub.Topic{}, false
+}
+
+func findSubscription(topic pubsub.Topic, name string) (pubsub.Subscription, bool) {
+	for _, subscription := range topic.Subscriptions {
+		if subscription.Name == name {
+			return subscription, true
+		}
+	}
+	return pubsub.Subscription{}, false
+}
+
+func deleteResource(client utils.ClientInterface, change Change) error {
+	switch change.Kind {
+	case RESOURCE_KIND_SCHEMA:
+		return pubsub.DeleteSchema(client, change.Project, change.Name)
+	case RESOURCE_KIND_TOPIC:
+		return pubsub.DeleteTopic(client, change.Project, pubsub.GetResourceNameForTopic(change.Project, change.Name))
+	case RESOURCE_KIND_SUBSCRIPTION:
+		return pubsub.DeleteSubscription(client, change.Project, pubsub.GetResourceNameForSubscription(change.Project, change.Name))
+	default:
+		return fmt.Errorf("unknown resource kind '%s'", change.Kind)
+	}
+}
+
+func (c Configuration) createResource(client utils.ClientInterface, change Change) error {
+	project, exists := c.findProject(change.Project)
+	if !exists {
+		return fmt.Errorf("project '%s' not found in the configuration", change.Project)
+	}
+
+	switch change.Kind {
+	case RESOURCE_KIND_SCHEMA:
+		schema, exists := findSchema(project, change.Name)
+		if !exists {
+			return fmt.Errorf("schema '%s' not found in the configuration", change.Name)
+		}
+		return createSchema(client, project, schema)
+	case RESOURCE_KIND_TOPIC:
+		topic, exists := findTopic(project, change.Name)
+		if !exists {
+			return fmt.Errorf("topic '%s' not found in the configuration", change.Name)
+		}
+		return createTopic(client, project, topic)
+	case RESOURCE_KIND_SUBSCRIPTION:
+		topic, exists := findTopic(project, change.Topic)
+		if !exists {
+			return fmt.Errorf("topic '%s' not found in the configuration", change.Topic)
+		}
+		subscription, exists := findSubscription(topic, change.Name)
+		if !exists {
+			return fmt.Errorf("subscription '%s' not found in the configuration", change.Name)
+		}
+		return createSubscription(client, project, topic, subscription)
+	default:
+		return fmt.Errorf("unknown resource kind '%s'", change.Kind)
+	}
+}
+
+func (c Configuration) updateResource(client utils.ClientInterface, change Change) error {
+	project, exists := c.findProject(change.Project)
+	if !exists {
+		return fmt.Errorf("project '%s' not found in the configuration", change.Project)
+	}
+
+	switch change.Kind {
+	case RESOURCE_KIND_SCHEMA:
+		schema, exists := findSchema(project, change.Name)
+		if !exists {
+			return fmt.Errorf("schema '%s' not found in the configuration", change.Name)
+		}
+		return provisionSchema(client, project, schema)
+	case RESOURCE_KIND_TOPIC:
+		topic, exists := findTopic(project, change.Name)
+		if !exists {
+			return fmt.Errorf("topic '%s' not found in the configuration", change.Name)
+		}
+		return updateTopic(client, project, topic)
+	case RESOURCE_KIND_SUBSCRIPTION:
+		topic, exists := findTopic(project, change.Topic)
+		if !exists {
+			return fmt.Errorf("topic '%s' not found in the configuration", change.Topic)
+		}
+		subscription, exists := findSubscription(topic, change.Name)
+		if !exists {
+			return fmt.Errorf("subscription '%s' not found in the configuration", change.Name)
+		}
+		return updateSubscription(client, project, topic, subscription)
+	default:
+		return fmt.Errorf("resources of kind '%s' can't be updated", change.Kind)
+	}
+}
+
+// ApplyChanges applies the changes returned by DiffConfigurations using this
+// configuration as the desired state. It keeps going when a change fails and
+// returns every error found.
+func (c Configuration) ApplyChanges(client utils.ClientInterface, changes []Change) error {
+	errs := []error{}
+
+	for _, change := range changes {
+		// Updates are only logged when something was really updated
+		if change.Action == CHANGE_ACTION_UPDATE {
+			Llog.Debug(fmt.Sprintf("Applying change: %s", change))
+		} else {
+			Llog.Info(fmt.Sprintf("Applying change: %s", change))
+		}
+
+		var err error
+		switch change.Action {
+		case CHANGE_ACTION_DELETE:
+			err = deleteResource(client, change)
+		case CHANGE_ACTION_CREATE:
+			err = c.createResource(client, change)
+		case CHANGE_ACTION_RECREATE:
+			err = deleteResource(client, change)
+			if err == nil {
+				err = c.createResource(client, change)
+			}
+		case CHANGE_ACTION_UPDATE:
+			err = c.updateResource(client, change)
+		}
+
+		if err != nil {
+			Llog.Error(fmt.Sprintf("Change '%s' failed: %v", change, err))
+			errs = append(errs, fmt.Errorf("%s: %w", change, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
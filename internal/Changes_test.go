@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Changes_Diff_NothingChanged(t *testing.T) {
+	config := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{Name: "test-topic", Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, 0, len(DiffConfigurations(config, config)))
+}
+
+func Test_Changes_Diff_AddedAndRemoved(t *testing.T) {
+	previous := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{Name: "removed-topic", Subscriptions: []pubsub.Subscription{{Name: "removed-subscription"}}},
+				},
+			},
+		},
+	}
+	current := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name:    "test-project",
+				Schemas: []pubsub.Schema{{Id: "new-schema", Name: "new-schema", Type: "AVRO"}},
+				Topics: []pubsub.Topic{
+					{Name: "new-topic", Subscriptions: []pubsub.Subscription{{Name: "new-subscription"}}},
+				},
+			},
+		},
+	}
+
+	changes := DiffConfigurations(previous, current)
+	assert.Equal(t, []Change{
+		{CHANGE_ACTION_DELETE, RESOURCE_KIND_SUBSCRIPTION, "test-project", "removed-topic", "removed-subscription"},
+		{CHANGE_ACTION_DELETE, RESOURCE_KIND_TOPIC, "test-project", "", "removed-topic"},
+		{CHANGE_ACTION_CREATE, RESOURCE_KIND_SCHEMA, "test-project", "", "new-schema"},
+		{CHANGE_ACTION_CREATE, RESOURCE_KIND_TOPIC, "test-project", "", "new-topic"},
+		{CHANGE_ACTION_CREATE, RESOURCE_KIND_SUBSCRIPTION, "test-project", "new-topic", "new-subscription"},
+	}, changes)
+}
+
+func Test_Changes_Diff_ChangedTopicIsUpdated(t *testing.T) {
+	previous := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{Name: "test-topic", Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}}},
+				},
+			},
+		},
+	}
+	current := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name:          "test-topic",
+						Labels:        pubsub.Labels{"owner": "admin"},
+						Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}},
+					},
+				},
+			},
+		},
+	}
+
+	changes := DiffConfigurations(previous, current)
+	assert.Equal(t, []Change{
+		{CHANGE_ACTION_UPDATE, RESOURCE_KIND_TOPIC, "test-project", "", "test-topic"},
+	}, changes)
+}
+
+func Test_Changes_Apply(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	config := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name:   "test-project",
+				Topics: []pubsub.Topic{{Name: "new-topic"}},
+			},
+		},
+	}
+
+	err := config.ApplyChanges(mockClient, []Change{
+		{CHANGE_ACTION_DELETE, RESOURCE_KIND_TOPIC, "test-project", "", "removed-topic"},
+		{CHANGE_ACTION_CREATE, RESOURCE_KIND_TOPIC, "test-project", "", "new-topic"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/topics/removed-topic", mockClient.RequestHistory[0].Path)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[1].Method)
+	assert.Equal(t, "projects/test-project/topics/new-topic", mockClient.RequestHistory[1].Path)
+}
+
+func Test_Changes_Apply_ReportsErrors(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusNotFound}, Error: nil},
+		},
+	}
+
+	config := Configuration{}
+	err := config.ApplyChanges(mockClient, []Change{
+		{CHANGE_ACTION_DELETE, RESOURCE_KIND_SUBSCRIPTION, "test-project", "test-topic", "test-subscription"},
+	})
+	assert.Error(t, err)
+}
+
+func Test_Changes_Diff_NewSchemaRevisionIsCommitted(t *testing.T) {
+	previous := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Schemas: []pubsub.Schema{
+					{Name: "test-schema", Type: "AVRO", Revisions: []pubsub.SchemaRevision{{Alias: "v1", Definition: `{"type":"string"}`}}},
+				},
+			},
+		},
+	}
+	current := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Schemas: []pubsub.Schema{
+					{Name: "test-schema", Type: "AVRO", Revisions: []pubsub.SchemaRevision{{Alias: "v1", Definition: `{"type":"string"}`}, {Alias: "v2", Definition: `{"type":"int"}`}}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []Change{
+		{CHANGE_ACTION_UPDATE, RESOURCE_KIND_SCHEMA, "test-project", "", "test-schema"},
+	}, DiffConfigurations(previous, current))
+}
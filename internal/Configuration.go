@@ -1,14 +1,20 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/emulator"
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/readiness"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/schema"
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
 )
 
 type Configuration struct {
@@ -18,6 +24,45 @@ type Configuration struct {
 	Projects                   []pubsub.Project `json:"projects"`
 	TimeBetweenStartupChecksMs int              `json:"timeBetweenStartupChecksMs"`
 	DelayBeforeStartupCheckMs  int              `json:"delayBeforeStartupCheckMs"`
+
+	// Exponential backoff applied to TimeBetweenStartupChecksMs after every
+	// failed startup check, capped by MaxTimeBetweenStartupChecksMs.
+	StartupCheckBackoffMultiplier float64 `json:"startupCheckBackoffMultiplier"`
+	MaxTimeBetweenStartupChecksMs int     `json:"maxTimeBetweenStartupChecksMs"`
+
+	// Maximum number of resources created or deleted at the same time.
+	ProvisioningConcurrency int `json:"provisioningConcurrency"`
+
+	// Compatibility required between consecutive schema revisions before
+	// syncing. Not checked when empty.
+	SchemaCompatibility string `json:"schemaCompatibility,omitempty"`
+
+	// Sync keeps the existing resources, updating them in place, and removes
+	// their messages instead of deleting and creating them again.
+	PurgeOnSync bool `json:"purgeOnSync,omitempty"`
+
+	// Seed of the random values of the message templates. Random when 0.
+	TemplateSeed int64 `json:"templateSeed,omitempty"`
+
+	// Emulator started by the helper itself with -startEmulator.
+	Emulator *EmulatorSettings `json:"emulator,omitempty"`
+
+	// FilePath is the file the configuration was loaded from, if any.
+	FilePath string `json:"-"`
+}
+
+// EmulatorSettings configure the emulator process started by the helper.
+type EmulatorSettings struct {
+	// Executable or jar of the emulator, gcloud when empty.
+	Path string `json:"path,omitempty"`
+	// Arguments added to the command starting the emulator.
+	Args []string `json:"args,omitempty"`
+	// Host and port the emulator listens on, localhost and a free port when
+	// empty.
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// Time given to the emulator to exit before killing it.
+	StopTimeoutMs int `json:"stopTimeoutMs,omitempty"`
 }
 
 func (c Configuration) String() string {
@@ -30,18 +75,26 @@ func (c Configuration) String() string {
 }
 
 func LoadConfigurationFromFile(fileReader utils.FileReaderInterface, filepath string) (Configuration, error) {
-	// TODO: Create an intermediate configuration schema to decouple Configuration struct <=> file format
 	configurationFileBytes, err := fileReader.Read(filepath)
 	if err != nil {
 		return Configuration{}, err
 	}
 
+	return ParseConfiguration(fileReader, configurationFileBytes, filepath)
+}
+
+// ParseConfiguration parses and validates the content of a configuration
+// file. The files it references are read relative to filepath, or to the
+// working directory when it's empty.
+func ParseConfiguration(fileReader utils.FileReaderInterface, content []byte, filepath string) (Configuration, error) {
+	// TODO: Create an intermediate configuration schema to decouple Configuration struct <=> file format
 	var configuration Configuration
-	err = json.Unmarshal(configurationFileBytes, &configuration)
+	err := json.Unmarshal(content, &configuration)
 	if err != nil {
 		return Configuration{}, err
 	}
 
+	configuration.FilePath = filepath
 	configuration.Host = strings.Trim(configuration.Host, " ")
 
 	if configuration.Host == "" {
@@ -64,122 +117,466 @@ func LoadConfigurationFromFile(fileReader utils.FileReaderInterface, filepath st
 		configuration.TimeBetweenStartupChecksMs = 200
 	}
 
+	if configuration.StartupCheckBackoffMultiplier < 1 {
+		configuration.StartupCheckBackoffMultiplier = 1
+	}
+
+	configuration.ProvisioningConcurrency = utils.Concurrency(configuration.ProvisioningConcurrency)
+
+	if configuration.MaxTimeBetweenStartupChecksMs <= 0 {
+		configuration.MaxTimeBetweenStartupChecksMs = 5_000
+	}
+
 	for _, project := range configuration.Projects {
 		for _, topic := range project.Topics {
 			if topic.IngestionDataSourceSettings != nil {
 				if topic.IngestionDataSourceSettings.AwsKinesis != nil && topic.IngestionDataSourceSettings.CloudStorage != nil {
-					fmt.Println("You can't add both AwsKinesis and CloudStorage to IngestionDataSourceSettings in a Topic")
-					os.Exit(1)
+					return Configuration{}, fmt.Errorf("you can't add both AwsKinesis and CloudStorage to IngestionDataSourceSettings in a Topic")
 				}
 			}
 		}
 	}
 
 	if !utils.IsValidHost(configuration.Host) {
-		fmt.Println("The given host is invalid")
-		os.Exit(1)
+		return Configuration{}, fmt.Errorf("the given host is invalid")
+	}
+
+	for p := range configuration.Projects {
+		mergeLegacySchemaRevisions(&configuration.Projects[p])
+		if err := validateSchemaRevisions(configuration.Projects[p]); err != nil {
+			return Configuration{}, err
+		}
+	}
+
+	if err := configuration.loadSchemaDefinitionFiles(fileReader); err != nil {
+		return Configuration{}, err
+	}
+
+	if err := configuration.validateSchemaCompatibilityModes(); err != nil {
+		return Configuration{}, err
+	}
+
+	if err := configuration.loadMessagesFiles(fileReader); err != nil {
+		return Configuration{}, err
+	}
+
+	if err := configuration.expandMessageTemplates(); err != nil {
+		return Configuration{}, err
+	}
+
+	if err := configuration.ValidateMessages(); err != nil {
+		return Configuration{}, err
 	}
 
 	return configuration, nil
 }
 
-func (c Configuration) ReplaceHost(host string) Configuration {
+// loadSchemaDefinitionFiles reads the definition of the schemas (and schema
+// revisions) using definitionFile, checking its syntax before it's sent to
+// the emulator.
+func (c *Configuration) loadSchemaDefinitionFiles(fileReader utils.FileReaderInterface) error {
+	for p := range c.Projects {
+		for s := range c.Projects[p].Schemas {
+			pubsubSchema := &c.Projects[p].Schemas[s]
+
+			if err := c.loadSchemaDefinitionFile(fileReader, pubsubSchema, &pubsubSchema.Definition, pubsubSchema.DefinitionFile); err != nil {
+				return fmt.Errorf("schema '%s': %w", pubsubSchema.Name, err)
+			}
+
+			for r := range pubsubSchema.Revisions {
+				revision := &pubsubSchema.Revisions[r]
+				if err := c.loadSchemaDefinitionFile(fileReader, pubsubSchema, &revision.Definition, revision.DefinitionFile); err != nil {
+					return fmt.Errorf("schema '%s' revision '%s': %w", pubsubSchema.Name, revision.Alias, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Configuration) loadSchemaDefinitionFile(fileReader utils.FileReaderInterface, pubsubSchema *pubsub.Schema, definition *string, definitionFile string) error {
+	if definitionFile == "" {
+		return nil
+	}
+
+	if *definition != "" {
+		return fmt.Errorf("definition and definitionFile can't be used together")
+	}
+
+	if pubsubSchema.Type == "" {
+		pubsubSchema.Type = schema.TypeForFile(definitionFile)
+	}
+
+	definitionFilePath := c.resolveReferencedFile(definitionFile)
+	content, err := fileReader.Read(definitionFilePath)
+	if err != nil {
+		return fmt.Errorf("can't read the definition: %w", err)
+	}
+
+	if err := schema.Validate(pubsubSchema.Type, string(content)); err != nil {
+		return fmt.Errorf("%s: %w", definitionFilePath, err)
+	}
+
+	*definition = string(content)
+	return nil
+}
+
+// resolveReferencedFile returns the path of a file referenced from the
+// configuration, relative paths are relative to the configuration file.
+func (c Configuration) resolveReferencedFile(path string) string {
+	if filepath.IsAbs(path) || c.FilePath == "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.FilePath), path)
+}
+
+// ReplaceHost returns the configuration using the given host, or an error
+// when the host is invalid.
+func (c Configuration) ReplaceHost(host string) (Configuration, error) {
 	if !utils.IsValidHost(host) {
-		fmt.Println("The given host is invalid")
-		os.Exit(1)
+		return c, fmt.Errorf("the given host '%s' is invalid", host)
 	}
 
 	c.Host = host
-	return c
+	return c, nil
+}
+
+// ReadinessOptions returns the options used to wait for the emulator, probing
+// the first project of the configuration.
+func (c Configuration) ReadinessOptions() readiness.Options {
+	options := readiness.Options{
+		InitialDelay:      time.Duration(c.DelayBeforeStartupCheckMs) * time.Millisecond,
+		Interval:          time.Duration(c.TimeBetweenStartupChecksMs) * time.Millisecond,
+		BackoffMultiplier: c.StartupCheckBackoffMultiplier,
+		MaxInterval:       time.Duration(c.MaxTimeBetweenStartupChecksMs) * time.Millisecond,
+		Timeout:           time.Duration(c.StartTimeoutMs) * time.Millisecond,
+	}
+
+	if len(c.Projects) > 0 {
+		options.Project = c.Projects[0].Name
+	}
+
+	return options
+}
+
+// EmulatorOptions returns the options used to start the emulator, waiting
+// for it like the startup check does. path overrides the configured one when
+// given.
+func (c Configuration) EmulatorOptions(path string) emulator.Options {
+	options := emulator.Options{Readiness: c.ReadinessOptions()}
+	if c.Emulator != nil {
+		options.Path = c.Emulator.Path
+		options.Args = c.Emulator.Args
+		options.Host = c.Emulator.Host
+		options.Port = c.Emulator.Port
+		options.StopTimeout = time.Duration(c.Emulator.StopTimeoutMs) * time.Millisecond
+	}
+	if path != "" {
+		options.Path = path
+	}
+	return options
 }
 
 /**
-*	Sync will remove everything in the emulator and then apply the configuration
-* TODO: In the future, it should have an option to just update what is required
-* 	to preserve data in those topics/subscriptions
+*	Sync will remove everything in the emulator and then apply the configuration.
+*	With PurgeOnSync it reconciles instead (see Reconcile), updating the
+*	existing topics and subscriptions in place to preserve their settings, and
+*	then purges their messages. The -reconcile flag calls Reconcile directly.
  */
-func (c *Configuration) Sync(client utils.ClientInterface) {
+func (c *Configuration) Sync(client utils.ClientInterface) error {
+	if c.PurgeOnSync {
+		if err := c.Reconcile(client); err != nil {
+			return err
+		}
+		return errors.Join(c.publishTopicMessages(client)...)
+	}
+
+	// Nothing is touched when the schema revisions are not compatible
+	if err := c.guardSchemaCompatibility(); err != nil {
+		return err
+	}
+
 	// Wait until the emulator is running
 	if !c.AvoidStartupCheck {
-		startTime := time.Now()
-		for {
-			_, err := client.Get("")
-			if err != nil {
-				if time.Since(startTime).Milliseconds() > int64(c.StartTimeoutMs) {
-					fmt.Println("Time to start the emulator has been exceeded. Exiting...")
-					os.Exit(1)
-				}
-				time.Sleep(200 * time.Millisecond)
-				continue
-			}
-			break
+		if err := readiness.WaitUntilReady(context.Background(), client, c.ReadinessOptions()); err != nil {
+			return err
 		}
 	}
 
+	concurrency := utils.Concurrency(c.ProvisioningConcurrency)
+
 	// Cleaning first everything in the emulator
+	cleaningTasks := []func() error{}
 	for _, project := range c.Projects {
 		topics, err := pubsub.ListTopics(client, project.Name)
 		if err != nil {
 			topics = []pubsub.Topic{}
 		}
 
-		for _, topic := range topics {
-			pubsub.DeleteTopic(client, project.Name, topic.Name)
-		}
-
 		subscriptions, err := pubsub.ListSubscriptions(client, project.Name)
 		if err != nil {
 			subscriptions = []pubsub.Subscription{}
 		}
 
+		for _, topic := range topics {
+			cleaningTasks = append(cleaningTasks, func() error {
+				return pubsub.DeleteTopic(client, project.Name, topic.Name)
+			})
+		}
+
 		for _, subscription := range subscriptions {
-			pubsub.DeleteSubscription(client, project.Name, subscription.Name)
+			cleaningTasks = append(cleaningTasks, func() error {
+				return pubsub.DeleteSubscription(client, project.Name, subscription.Name)
+			})
 		}
 	}
+	utils.RunConcurrently(concurrency, cleaningTasks)
+
+	// Applying information in the configuration. Resources are created in
+	// phases, as topics may depend on schemas and subscriptions on topics
+	// (their own and the dead letter one), but each phase runs concurrently.
+	schemaTasks := []provisioningTask{}
+	topicTasks := []provisioningTask{}
+	subscriptionTasks := []provisioningTask{}
 
-	// Applying information in the configuration
 	for _, project := range c.Projects {
 		for _, schema := range project.Schemas {
-			pubsub.CreateSchema(
-				client,
-				project.Name,
-				schema.Id,
-				schema.Name,
-				schema.Type,
-				schema.Definition,
-			)
+			schemaTasks = append(schemaTasks, provisioningTask{
+				description: fmt.Sprintf("schema '%s' in project '%s'", schemaKey(schema), project.Name),
+				run: func() error {
+					return createSchema(client, project, schema)
+				},
+			})
 		}
 
 		for _, topic := range project.Topics {
-			pubsub.CreateTopic(
-				client,
-				project.Name,
-				pubsub.GetResourceNameForTopic(
-					project.Name,
-					topic.Name,
-				),
-				&topic.Labels,
-				&topic.MessageStoragePolicy,
-				topic.KmsKeyName,
-				topic.MessageRetentionDuration,
-				topic.IngestionDataSourceSettings,
-				topic.SchemaSettings,
-			)
+			topicTasks = append(topicTasks, provisioningTask{
+				description: fmt.Sprintf("topic '%s' in project '%s'", topic.Name, project.Name),
+				run: func() error {
+					return createTopic(client, project, topic)
+				},
+			})
+
 			for _, subscription := range topic.Subscriptions {
-				pubsub.CreateSubscription(
-					client,
-					project.Name,
-					pubsub.GetResourceNameForSubscription(
-						project.Name,
-						subscription.Name,
-					),
-					pubsub.GetResourceNameForTopic(
-						project.Name,
-						topic.Name,
-					),
-					&subscription.Labels,
-				)
+				subscriptionTasks = append(subscriptionTasks, provisioningTask{
+					description: fmt.Sprintf("subscription '%s' in project '%s'", subscription.Name, project.Name),
+					run: func() error {
+						return createSubscription(client, project, topic, subscription)
+					},
+				})
 			}
 		}
 	}
+
+	errs := []error{}
+	for _, phase := range [][]provisioningTask{schemaTasks, topicTasks, subscriptionTasks} {
+		errs = append(errs, runProvisioningTasks(concurrency, phase)...)
+	}
+
+	// Seeding the topics once the subscriptions exist, so they receive the
+	// messages.
+	errs = append(errs, c.publishTopicMessages(client)...)
+
+	return errors.Join(errs...)
+}
+
+// publishTopicMessages publishes the messages of every topic, returning the
+// errors found.
+func (c Configuration) publishTopicMessages(client utils.ClientInterface) []error {
+	errs := []error{}
+	for _, project := range c.Projects {
+		for _, topic := range project.Topics {
+			if len(topic.Messages) == 0 {
+				continue
+			}
+
+			if _, err := c.PublishMessages(client, project.Name, topic.Name, topic.Messages); err != nil {
+				errs = append(errs, fmt.Errorf("error publishing the messages of topic '%s' in project '%s': %w", topic.Name, project.Name, err))
+				continue
+			}
+			Llog.Info(fmt.Sprintf("Published %d messages to topic '%s'", len(topic.Messages), topic.Name))
+		}
+	}
+	return errs
+}
+
+type provisioningTask struct {
+	description string
+	run         func() error
+}
+
+// runProvisioningTasks runs the tasks concurrently, returning the errors in
+// the same order as the tasks.
+func runProvisioningTasks(concurrency int, tasks []provisioningTask) []error {
+	runs := make([]func() error, len(tasks))
+	for i, task := range tasks {
+		runs[i] = task.run
+	}
+
+	errs := []error{}
+	for i, err := range utils.RunConcurrently(concurrency, runs) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating %s: %w", tasks[i].description, err))
+		}
+	}
+	return errs
+}
+
+// ReferencedFiles returns every file the configuration was built from.
+func (c Configuration) ReferencedFiles() []string {
+	files := []string{}
+	if c.FilePath != "" {
+		files = append(files, c.FilePath)
+	}
+	for _, project := range c.Projects {
+		for _, pubsubSchema := range project.Schemas {
+			for _, revision := range pubsubSchema.SchemaRevisions() {
+				if revision.DefinitionFile != "" {
+					files = append(files, c.resolveReferencedFile(revision.DefinitionFile))
+				}
+			}
+		}
+		for _, topic := range project.Topics {
+			if topic.MessagesFile != "" {
+				files = append(files, c.resolveReferencedFile(topic.MessagesFile))
+			}
+		}
+	}
+	return files
+}
+
+func createSchema(client utils.ClientInterface, project pubsub.Project, schema pubsub.Schema) error {
+	return provisionSchema(client, project, schema)
+}
+
+func createTopic(client utils.ClientInterface, project pubsub.Project, topic pubsub.Topic) error {
+	schemaSettings, err := resolveSchemaSettings(client, project, topic.SchemaSettings)
+	if err != nil {
+		return err
+	}
+
+	return pubsub.CreateTopic(
+		client,
+		project.Name,
+		pubsub.GetResourceNameForTopic(
+			project.Name,
+			topic.Name,
+		),
+		&topic.Labels,
+		&topic.MessageStoragePolicy,
+		topic.KmsKeyName,
+		topic.MessageRetentionDuration,
+		topic.IngestionDataSourceSettings,
+		schemaSettings,
+	)
+}
+
+func createSubscription(client utils.ClientInterface, project pubsub.Project, topic pubsub.Topic, subscription pubsub.Subscription) error {
+	return pubsub.CreateSubscription(
+		client,
+		project.Name,
+		pubsub.GetResourceNameForSubscription(
+			project.Name,
+			subscription.Name,
+		),
+		pubsub.GetResourceNameForTopic(
+			project.Name,
+			topic.Name,
+		),
+		&subscription.Labels,
+		subscription.DeadLetterPolicy,
+		subscription.PushConfig,
+	)
+}
+
+func updateTopic(client utils.ClientInterface, project pubsub.Project, topic pubsub.Topic) error {
+	schemaSettings, err := resolveSchemaSettings(client, project, topic.SchemaSettings)
+	if err != nil {
+		return err
+	}
+
+	updateMask, err := pubsub.UpdateTopic(
+		client,
+		project.Name,
+		pubsub.GetResourceNameForTopic(
+			project.Name,
+			topic.Name,
+		),
+		&topic.Labels,
+		&topic.MessageStoragePolicy,
+		topic.KmsKeyName,
+		topic.MessageRetentionDuration,
+		topic.IngestionDataSourceSettings,
+		schemaSettings,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(updateMask) > 0 {
+		Llog.Info(fmt.Sprintf("Topic '%s' updated (%s)", topic.Name, strings.Join(updateMask, ", ")))
+	}
+	return nil
+}
+
+// topicUpdateMask returns the settings of an existing topic that differ from
+// the configuration.
+func topicUpdateMask(client utils.ClientInterface, project pubsub.Project, topic pubsub.Topic) ([]string, error) {
+	schemaSettings, err := resolveSchemaSettings(client, project, topic.SchemaSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	return pubsub.TopicUpdateMask(
+		client,
+		project.Name,
+		pubsub.GetResourceNameForTopic(project.Name, topic.Name),
+		&topic.Labels,
+		&topic.MessageStoragePolicy,
+		topic.KmsKeyName,
+		topic.MessageRetentionDuration,
+		topic.IngestionDataSourceSettings,
+		schemaSettings,
+	)
+}
+
+// subscriptionUpdateMask returns the settings of an existing subscription
+// that differ from the configuration.
+func subscriptionUpdateMask(client utils.ClientInterface, project pubsub.Project, topic pubsub.Topic, subscription pubsub.Subscription) ([]string, error) {
+	return pubsub.SubscriptionUpdateMask(
+		client,
+		project.Name,
+		pubsub.GetResourceNameForSubscription(project.Name, subscription.Name),
+		pubsub.GetResourceNameForTopic(project.Name, topic.Name),
+		&subscription.Labels,
+		subscription.DeadLetterPolicy,
+		subscription.PushConfig,
+	)
+}
+
+func updateSubscription(client utils.ClientInterface, project pubsub.Project, topic pubsub.Topic, subscription pubsub.Subscription) error {
+	updateMask, err := pubsub.UpdateSubscription(
+		client,
+		project.Name,
+		pubsub.GetResourceNameForSubscription(
+			project.Name,
+			subscription.Name,
+		),
+		pubsub.GetResourceNameForTopic(
+			project.Name,
+			topic.Name,
+		),
+		&subscription.Labels,
+		subscription.DeadLetterPolicy,
+		subscription.PushConfig,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(updateMask) > 0 {
+		Llog.Info(fmt.Sprintf("Subscription '%s' updated (%s)", subscription.Name, strings.Join(updateMask, ", ")))
+	}
+	return nil
 }
@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
@@ -289,17 +291,80 @@ func Test_Configuration_LoadFile_WithProjectWithTopicWithTwoLabelsTheSameAndWith
 	assert.Equal(t, "labelValue2", config.Projects[0].Topics[0].Labels["firstLabel"])
 }
 
+func Test_Configuration_LoadFile_WithSchemaDefinitionFiles(t *testing.T) {
+	mockReader := &utils.FileReaderMock{
+		ReadFunc: func(filePath string) ([]byte, error) {
+			switch filePath {
+			case "config/test_config.json":
+				return []byte(`{
+          "projects": [{
+            "name": "first-project",
+            "schemas": [
+              {"id": "avroSchema", "name": "avro", "definitionFile": "schemas/product.avsc"},
+              {"id": "protoSchema", "name": "proto", "definitionFile": "/shared/product.proto"}
+            ]
+          }]
+        }`), nil
+			case "config/schemas/product.avsc":
+				return []byte(`{"type":"record","name":"Product","fields":[{"name":"sku","type":"int"}]}`), nil
+			case "/shared/product.proto":
+				return []byte(`syntax = "proto3"; message Product { int32 sku = 1; }`), nil
+			default:
+				return nil, fmt.Errorf("file '%s' not found", filePath)
+			}
+		},
+	}
+
+	config, err := LoadConfigurationFromFile(mockReader, "config/test_config.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "AVRO", config.Projects[0].Schemas[0].Type)
+	assert.Equal(t, `{"type":"record","name":"Product","fields":[{"name":"sku","type":"int"}]}`, config.Projects[0].Schemas[0].Definition)
+	assert.Equal(t, "PROTOCOL_BUFFER", config.Projects[0].Schemas[1].Type)
+	assert.Equal(t, []string{"config/test_config.json", "config/schemas/product.avsc", "/shared/product.proto"}, config.ReferencedFiles())
+}
+
+func Test_Configuration_LoadFile_WithInvalidSchemaDefinitionFile(t *testing.T) {
+	mockReader := &utils.FileReaderMock{
+		ReadFunc: func(filePath string) ([]byte, error) {
+			if filePath == "product.avsc" {
+				return []byte(`{"type":"record","name":"Product"}`), nil
+			}
+			return []byte(`{"projects": [{"name": "first-project", "schemas": [{"name": "avro", "definitionFile": "product.avsc"}]}]}`), nil
+		},
+	}
+
+	_, err := LoadConfigurationFromFile(mockReader, "test_config.json")
+	assert.ErrorContains(t, err, "record 'Product' without 'fields'")
+}
+
+func Test_Configuration_LoadFile_WithSchemaDefinitionAndDefinitionFile(t *testing.T) {
+	mockReader := utils.NewFileReaderMockBasic(
+		`{"projects": [{"name": "first-project", "schemas": [{"name": "avro", "type": "AVRO", "definition": "{}", "definitionFile": "product.avsc"}]}]}`,
+	)
+
+	_, err := LoadConfigurationFromFile(mockReader, "test_config.json")
+	assert.Error(t, err)
+}
+
 func Test_Configuration_ReplaceHost(t *testing.T) {
 	config := Configuration{Host: "localhost:8085"}
 	newHost := "0.0.0.0:8085"
-	config = config.ReplaceHost(newHost)
+	config, err := config.ReplaceHost(newHost)
+	assert.NoError(t, err)
 	assert.Equal(t, newHost, config.Host)
 }
 
+func Test_Configuration_ReplaceHost_Invalid(t *testing.T) {
+	config := Configuration{Host: "localhost:8085"}
+	config, err := config.ReplaceHost("not a host")
+	assert.Error(t, err)
+	assert.Equal(t, "localhost:8085", config.Host)
+}
+
 func Test_Configuration_Sync(t *testing.T) {
 	mockClient := &utils.MockClient{
 		ResponseHistory: []utils.MockClientHistoryResponse{
-			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[]}`)}, Error: nil},
 			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[]}`)}, Error: nil},
 			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
 			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
@@ -324,16 +389,70 @@ func Test_Configuration_Sync(t *testing.T) {
 		},
 	}
 
-	config.Sync(mockClient)
+	err := config.Sync(mockClient)
+	assert.NoError(t, err)
 	assert.Equal(t, 5, len(mockClient.RequestHistory))
 	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[0].Method)
-	assert.Equal(t, "", mockClient.RequestHistory[0].Path)
+	assert.Equal(t, "projects/test-project/topics", mockClient.RequestHistory[0].Path)
 	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[1].Method)
 	assert.Equal(t, "projects/test-project/topics", mockClient.RequestHistory[1].Path)
 	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[2].Method)
 	assert.Equal(t, "projects/test-project/subscriptions", mockClient.RequestHistory[2].Path)
-	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[3].Method)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[3].Method)
 	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[3].Path)
-	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[4].Method)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[4].Method)
 	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[4].Path)
 }
+
+func Test_Configuration_Sync_ReportsErrorsInOrder(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusBadRequest}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusBadRequest}, Error: nil},
+		},
+	}
+
+	config := Configuration{
+		AvoidStartupCheck:       true,
+		ProvisioningConcurrency: 4,
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{Name: "first-topic"},
+					{Name: "second-topic"},
+				},
+			},
+		},
+	}
+
+	err := config.Sync(mockClient)
+	assert.EqualError(
+		t,
+		err,
+		"error creating topic 'first-topic' in project 'test-project': error creating topic: status code 400\n"+
+			"error creating topic 'second-topic' in project 'test-project': error creating topic: status code 400",
+	)
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+}
+
+func Test_Configuration_EmulatorOptions(t *testing.T) {
+	config := Configuration{
+		StartTimeoutMs: 30000,
+		Projects:       []pubsub.Project{{Name: "first-project"}},
+		Emulator:       &EmulatorSettings{Path: "emulator.jar", Args: []string{"--verbose"}, Port: 8681, StopTimeoutMs: 500},
+	}
+
+	options := config.EmulatorOptions("")
+	assert.Equal(t, "emulator.jar", options.Path)
+	assert.Equal(t, []string{"--verbose"}, options.Args)
+	assert.Equal(t, 8681, options.Port)
+	assert.Equal(t, 500*time.Millisecond, options.StopTimeout)
+	assert.Equal(t, 30*time.Second, options.Readiness.Timeout)
+	assert.Equal(t, "first-project", options.Readiness.Project)
+
+	assert.Equal(t, "/opt/cloud-pubsub-emulator", config.EmulatorOptions("/opt/cloud-pubsub-emulator").Path)
+	assert.Equal(t, "", Configuration{}.EmulatorOptions("").Path)
+}
@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// DriftReport describes the differences between the emulator and the
+// configuration. Missing contains the changes that would create the
+// resources again, Unexpected the resource names not in the configuration
+// and Moved the changes recreating the subscriptions attached to another
+// topic than the configured one.
+type DriftReport struct {
+	Missing    []Change
+	Unexpected []string
+	Moved      []Change
+	// Configured is the number of resources in the configuration, used to
+	// know if everything is missing.
+	Configured int
+}
+
+func (r DriftReport) HasDrift() bool {
+	return len(r.Missing) > 0 || len(r.Unexpected) > 0 || len(r.Moved) > 0
+}
+
+// EverythingMissing is true when none of the configured resources exist,
+// which happens when the emulator has been restarted and lost its state.
+func (r DriftReport) EverythingMissing() bool {
+	return r.Configured > 0 && len(r.Missing) == r.Configured
+}
+
+// DetectDrift compares the resources in the emulator with the configuration.
+func DetectDrift(client utils.ClientInterface, c Configuration) (DriftReport, error) {
+	report := DriftReport{Missing: []Change{}, Unexpected: []string{}, Moved: []Change{}}
+	schemaChanges := []Change{}
+	topicChanges := []Change{}
+	subscriptionChanges := []Change{}
+
+	for _, project := range c.Projects {
+		topics, err := pubsub.ListTopics(client, project.Name)
+		if err != nil {
+			return DriftReport{}, err
+		}
+
+		subscriptions, err := pubsub.ListSubscriptions(client, project.Name)
+		if err != nil {
+			return DriftReport{}, err
+		}
+
+		existingTopics := map[string]bool{}
+		for _, topic := range topics {
+			existingTopics[topic.Name] = true
+		}
+
+		existingSubscriptions := map[string]pubsub.Subscription{}
+		for _, subscription := range subscriptions {
+			existingSubscriptions[subscription.Name] = subscription
+		}
+
+		if len(project.Schemas) > 0 {
+			schemas, err := pubsub.ListSchemas(client, project.Name)
+			if err != nil {
+				Llog.Warn(fmt.Sprintf("Can't list the schemas of project '%s', ignoring them: %v", project.Name, err))
+			} else {
+				existingSchemas := map[string]bool{}
+				for _, schema := range schemas {
+					existingSchemas[schema.Name] = true
+				}
+
+				for _, schema := range project.Schemas {
+					report.Configured++
+					if !existingSchemas[pubsub.GetResourceNameForSchema(project.Name, schemaKey(schema))] {
+						schemaChanges = append(schemaChanges, Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_SCHEMA, project.Name, "", schemaKey(schema)})
+					}
+				}
+			}
+		}
+
+		configuredTopics := map[string]bool{}
+		configuredSubscriptions := map[string]bool{}
+
+		for _, topic := range project.Topics {
+			topicResourceName := pubsub.GetResourceNameForTopic(project.Name, topic.Name)
+			configuredTopics[topicResourceName] = true
+
+			report.Configured++
+			if !existingTopics[topicResourceName] {
+				topicChanges = append(topicChanges, Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_TOPIC, project.Name, "", topic.Name})
+			}
+
+			for _, subscription := range topic.Subscriptions {
+				subscriptionResourceName := pubsub.GetResourceNameForSubscription(project.Name, subscription.Name)
+				configuredSubscriptions[subscriptionResourceName] = true
+
+				report.Configured++
+				existing, exists := existingSubscriptions[subscriptionResourceName]
+				switch {
+				case !exists:
+					subscriptionChanges = append(subscriptionChanges, Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_SUBSCRIPTION, project.Name, topic.Name, subscription.Name})
+				case existing.Topic != "" && existing.Topic != topicResourceName:
+					// The topic of a subscription can't be updated
+					report.Moved = append(report.Moved, Change{CHANGE_ACTION_RECREATE, RESOURCE_KIND_SUBSCRIPTION, project.Name, topic.Name, subscription.Name})
+				}
+			}
+		}
+
+		for _, topic := range topics {
+			if !configuredTopics[topic.Name] {
+				report.Unexpected = append(report.Unexpected, topic.Name)
+			}
+		}
+
+		for _, subscription := range subscriptions {
+			if !configuredSubscriptions[subscription.Name] {
+				report.Unexpected = append(report.Unexpected, subscription.Name)
+			}
+		}
+	}
+
+	report.Missing = append(report.Missing, schemaChanges...)
+	report.Missing = append(report.Missing, topicChanges...)
+	report.Missing = append(report.Missing, subscriptionChanges...)
+
+	return report, nil
+}
+
+// DriftDaemon periodically checks the emulator against the configuration,
+// logging the drift found and, when Heal is enabled, creating the missing
+// resources again. When every resource is missing, the emulator is
+// considered restarted and fully synced.
+type DriftDaemon struct {
+	Interval time.Duration
+	Heal     bool
+
+	mutex         sync.Mutex
+	configuration Configuration
+}
+
+// SetConfiguration replaces the configuration used as desired state, so the
+// daemon can be combined with the configuration watcher.
+func (d *DriftDaemon) SetConfiguration(configuration Configuration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.configuration = configuration
+}
+
+func (d *DriftDaemon) getConfiguration() Configuration {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.configuration
+}
+
+// Check runs a single drift detection, healing it if enabled. It returns if
+// the emulator was reachable.
+func (d *DriftDaemon) Check(client utils.ClientInterface, wasReachable bool) bool {
+	configuration := d.getConfiguration()
+
+	report, err := DetectDrift(client, configuration)
+	if err != nil {
+		if wasReachable {
+			Llog.Warn(fmt.Sprintf("Emulator unreachable while checking drift: %v", err))
+		}
+		return false
+	}
+
+	// A failed probe alone is not a restart, syncing would delete the
+	// resources that survived it along with their messages
+	restarted := report.EverythingMissing()
+	if !wasReachable {
+		Llog.Info("Emulator reachable again")
+	}
+
+	if !report.HasDrift() {
+		Llog.Debug("No drift detected")
+		return true
+	}
+
+	for _, change := range report.Missing {
+		Llog.Warn(fmt.Sprintf("Drift detected, missing resource: %s %s/%s", change.Kind, change.Project, change.Name))
+	}
+	for _, name := range report.Unexpected {
+		Llog.Warn(fmt.Sprintf("Drift detected, resource not in the configuration: %s", name))
+	}
+	for _, change := range report.Moved {
+		Llog.Warn(fmt.Sprintf("Drift detected, subscription attached to another topic: %s/%s", change.Project, change.Name))
+	}
+
+	if !d.Heal {
+		return true
+	}
+
+	if restarted {
+		Llog.Info("Emulator seems to have been restarted, provisioning the whole configuration")
+		if err := configuration.Sync(client); err != nil {
+			Llog.Error(fmt.Sprintf("Provisioning after the restart failed: %v", err))
+		}
+		return true
+	}
+
+	if err := configuration.ApplyChanges(client, report.Missing); err != nil {
+		Llog.Error(fmt.Sprintf("Some missing resources couldn't be created: %v", err))
+	}
+
+	return true
+}
+
+// Run blocks until the context is cancelled.
+func (d *DriftDaemon) Run(ctx context.Context, client utils.ClientInterface) error {
+	Llog.Info(fmt.Sprintf("Checking drift every %s (heal: %v)", d.Interval, d.Heal))
+
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	reachable := true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		reachable = d.Check(client, reachable)
+	}
+}
+
+// WaitUntilProvisioned blocks until every resource of the configuration
+// exists in the emulator or the context is done.
+func (c Configuration) WaitUntilProvisioned(ctx context.Context, client utils.ClientInterface, interval time.Duration) error {
+	for {
+		report, err := DetectDrift(client, c)
+		if err == nil && len(report.Missing) == 0 {
+			return nil
+		}
+
+		if err != nil {
+			Llog.Info(fmt.Sprintf("Waiting for the configuration to be provisioned: %v", err))
+		} else {
+			Llog.Info(fmt.Sprintf("Waiting for the configuration to be provisioned, %d resource(s) missing", len(report.Missing)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("configuration wasn't provisioned: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
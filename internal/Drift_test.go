@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDriftTestConfiguration() Configuration {
+	return Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name: "test-topic",
+						Subscriptions: []pubsub.Subscription{
+							{Name: "test-subscription"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_Drift_Detect_MissingAndUnexpected(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"},{"name":"projects/test-project/topics/manual-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+		},
+	}
+
+	report, err := DetectDrift(mockClient, newDriftTestConfiguration())
+	assert.NoError(t, err)
+	assert.True(t, report.HasDrift())
+	assert.False(t, report.EverythingMissing())
+	assert.Equal(t, []Change{
+		{CHANGE_ACTION_CREATE, RESOURCE_KIND_SUBSCRIPTION, "test-project", "test-topic", "test-subscription"},
+	}, report.Missing)
+	assert.Equal(t, []string{"projects/test-project/topics/manual-topic"}, report.Unexpected)
+}
+
+func Test_Drift_Detect_EverythingMissing(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+		},
+	}
+
+	report, err := DetectDrift(mockClient, newDriftTestConfiguration())
+	assert.NoError(t, err)
+	assert.True(t, report.EverythingMissing())
+	assert.Equal(t, 2, len(report.Missing))
+}
+
+func Test_Drift_Daemon_HealsMissingResources(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	daemon := DriftDaemon{Heal: true}
+	daemon.SetConfiguration(newDriftTestConfiguration())
+
+	reachable := daemon.Check(mockClient, true)
+	assert.True(t, reachable)
+	assert.Equal(t, 3, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[2].Method)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[2].Path)
+}
+
+func Test_Drift_Daemon_UnreachableIsNotARestart(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			// A probe timing out
+			{Response: utils.Response{}, Error: context.DeadlineExceeded},
+			// Back with the topic and its backlog, the subscription is missing
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	daemon := DriftDaemon{Heal: true}
+	daemon.SetConfiguration(newDriftTestConfiguration())
+
+	reachable := daemon.Check(mockClient, true)
+	assert.False(t, reachable)
+
+	reachable = daemon.Check(mockClient, reachable)
+	assert.True(t, reachable)
+
+	// Only the missing subscription is created, nothing is deleted
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+	for _, request := range mockClient.RequestHistory {
+		assert.NotEqual(t, http.MethodDelete, request.Method)
+	}
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[3].Method)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[3].Path)
+}
+
+func Test_Drift_WaitUntilProvisioned(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"projects/test-project/subscriptions/test-subscription"}]}`)}, Error: nil},
+		},
+	}
+
+	err := newDriftTestConfiguration().WaitUntilProvisioned(context.Background(), mockClient, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+}
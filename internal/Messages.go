@@ -0,0 +1,416 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/fixtures"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/schema"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// ParseMessages parses the messages of a messages file, either a JSON array
+// of messages or a message per line (JSON Lines).
+func ParseMessages(content []byte) ([]pubsub.Message, error) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 {
+		return []pubsub.Message{}, nil
+	}
+
+	messages := []pubsub.Message{}
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, err
+		}
+		return messages, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var message pubsub.Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, scanner.Err()
+}
+
+// loadMessagesFiles reads the messages of the topics using messagesFile,
+// which are published after the ones declared in the topic.
+func (c *Configuration) loadMessagesFiles(fileReader utils.FileReaderInterface) error {
+	for p := range c.Projects {
+		for t := range c.Projects[p].Topics {
+			topic := &c.Projects[p].Topics[t]
+			if topic.MessagesFile == "" {
+				continue
+			}
+
+			messagesFilePath := c.resolveReferencedFile(topic.MessagesFile)
+			content, err := fileReader.Read(messagesFilePath)
+			if err != nil {
+				return fmt.Errorf("topic '%s': can't read the messages: %w", topic.Name, err)
+			}
+
+			messages, err := ParseMessages(content)
+			if err != nil {
+				return fmt.Errorf("topic '%s': %s: %w", topic.Name, messagesFilePath, err)
+			}
+			topic.Messages = append(topic.Messages, messages...)
+		}
+	}
+	return nil
+}
+
+// expandMessageTemplates replaces the messages of the topics using a template
+// by the messages it generates. A single generator is used, so named
+// sequences continue between topics.
+func (c *Configuration) expandMessageTemplates() error {
+	generator := fixtures.NewGenerator(c.TemplateSeed)
+	for p := range c.Projects {
+		for t := range c.Projects[p].Topics {
+			topic := &c.Projects[p].Topics[t]
+			messages, err := generator.Expand(topic.Messages)
+			if err != nil {
+				return fmt.Errorf("topic '%s' %w", topic.Name, err)
+			}
+			topic.Messages = messages
+		}
+	}
+	return nil
+}
+
+// ExpandMessages returns the messages generated by the templates of the
+// messages given, using the seed of the configuration.
+func (c Configuration) ExpandMessages(messages []pubsub.Message) ([]pubsub.Message, error) {
+	return fixtures.NewGenerator(c.TemplateSeed).Expand(messages)
+}
+
+// topicMessageDefinitions returns the parsed definitions a message published
+// to the topic can match, oldest first. There are none when the topic has no
+// schema or its schema is not in the configuration.
+func topicMessageDefinitions(project pubsub.Project, topic pubsub.Topic) ([]*schema.Definition, error) {
+	settings := topic.SchemaSettings
+	if settings == nil {
+		return nil, nil
+	}
+
+	reference := settings.Schema
+	if settings.FirstSchemaId != "" {
+		reference = settings.FirstSchemaId
+	} else if settings.LastSchemaId != "" {
+		reference = settings.LastSchemaId
+	}
+
+	pubsubSchema, exists := findSchemaByReference(project, reference)
+	if !exists {
+		Llog.Debug(fmt.Sprintf("Schema '%s' of topic '%s' is not in the configuration, messages are not validated", reference, topic.Name))
+		return nil, nil
+	}
+
+	revisions := pubsubSchema.SchemaRevisions()
+	first, last := 0, len(revisions)-1
+	if len(pubsubSchema.Revisions) > 0 {
+		for _, reference := range []struct {
+			alias string
+			index *int
+		}{{settings.FirstRevisionId, &first}, {settings.LastRevisionId, &last}} {
+			if reference.alias == "" {
+				continue
+			}
+			index, exists := revisionIndex(revisions, reference.alias)
+			if !exists {
+				return nil, fmt.Errorf("revision '%s' is not a revision of schema '%s'", reference.alias, pubsubSchema.Name)
+			}
+			*reference.index = index
+		}
+		if first > last {
+			return nil, fmt.Errorf("first revision '%s' is newer than last revision '%s' of schema '%s'", settings.FirstRevisionId, settings.LastRevisionId, pubsubSchema.Name)
+		}
+	}
+
+	definitions := []*schema.Definition{}
+	for _, revision := range revisions[first : last+1] {
+		definition, err := schema.Parse(pubsubSchema.Type, revision.Definition)
+		if err != nil {
+			return nil, fmt.Errorf("schema '%s': %w", pubsubSchema.Name, err)
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
+}
+
+// revisionIndex returns the position of the revision with the given alias.
+func revisionIndex(revisions []pubsub.SchemaRevision, alias string) (int, bool) {
+	for i, revision := range revisions {
+		if revision.Alias == alias {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// validateTopicMessage checks the payload matches any of the definitions.
+// When none does, the problems found with the newest one are returned.
+func validateTopicMessage(definitions []*schema.Definition, encoding pubsub.SchemaEncoding, payload []byte) error {
+	var err error
+	for i := len(definitions) - 1; i >= 0; i-- {
+		validationErr := definitions[i].ValidateMessage(string(encoding), payload)
+		if validationErr == nil {
+			return nil
+		}
+		if err == nil {
+			err = validationErr
+		}
+	}
+	return err
+}
+
+// messageJSON returns the data of a message as JSON. A JSON string holding
+// an escaped object or array is unwrapped.
+func messageJSON(message pubsub.Message) []byte {
+	var text string
+	if err := json.Unmarshal(message.Data, &text); err == nil {
+		trimmed := strings.TrimSpace(text)
+		if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+			return []byte(trimmed)
+		}
+	}
+	return message.Data
+}
+
+// encodeTopicMessage returns the payload to publish for a message written as
+// JSON in data, encoded with the newest revision accepting it. When none
+// does, the problems found with the newest one are returned.
+func encodeTopicMessage(definitions []*schema.Definition, encoding pubsub.SchemaEncoding, message pubsub.Message) ([]byte, error) {
+	var err error
+	for i := len(definitions) - 1; i >= 0; i-- {
+		payload, encodingErr := definitions[i].EncodeMessage(string(encoding), messageJSON(message))
+		if encodingErr == nil {
+			return payload, nil
+		}
+		if err == nil {
+			err = encodingErr
+		}
+	}
+	return nil, err
+}
+
+// encodeTopicMessages returns the messages as published to the topic. The
+// data of the messages is encoded with the schema and encoding of the topic,
+// while the payloads given in base64 are only validated.
+func encodeTopicMessages(project pubsub.Project, topic pubsub.Topic, messages []pubsub.Message) ([]pubsub.Message, error) {
+	definitions, err := topicMessageDefinitions(project, topic)
+	if err != nil {
+		return nil, fmt.Errorf("topic '%s': %w", topic.Name, err)
+	}
+
+	encoded := make([]pubsub.Message, len(messages))
+	for i, message := range messages {
+		encoded[i] = message
+
+		payload, err := message.Payload()
+		if err != nil {
+			return nil, fmt.Errorf("topic '%s' message %d: %w", topic.Name, i, err)
+		}
+		if len(definitions) == 0 {
+			continue
+		}
+
+		if message.DataBase64 != "" {
+			err = validateTopicMessage(definitions, topic.SchemaSettings.Encoding, payload)
+		} else {
+			payload, err = encodeTopicMessage(definitions, topic.SchemaSettings.Encoding, message)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("topic '%s' message %d: %w", topic.Name, i, err)
+		}
+
+		encoded[i].Data = nil
+		encoded[i].DataBase64 = base64.StdEncoding.EncodeToString(payload)
+	}
+	return encoded, nil
+}
+
+// ValidateMessages checks the messages of every topic against the schema of
+// the topic, if any.
+func (c Configuration) ValidateMessages() error {
+	for _, project := range c.Projects {
+		for _, topic := range project.Topics {
+			if _, err := encodeTopicMessages(project, topic, topic.Messages); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateMessage checks a payload can be published to a topic of the
+// configuration, matching the schema of the topic.
+func (c Configuration) ValidateMessage(projectName, topicName string, payload []byte) error {
+	project, topic, err := c.findProjectTopic(projectName, topicName)
+	if err != nil {
+		return err
+	}
+
+	definitions, err := topicMessageDefinitions(project, topic)
+	if err != nil || len(definitions) == 0 {
+		return err
+	}
+	return validateTopicMessage(definitions, topic.SchemaSettings.Encoding, payload)
+}
+
+// PublishMessages encodes the messages with the schema and encoding of the
+// topic and publishes them, returning the ids of the published messages.
+// Messages to topics not in the configuration are published as they are.
+func (c Configuration) PublishMessages(client utils.ClientInterface, projectName, topicName string, messages []pubsub.Message) ([]string, error) {
+	project, topic, err := c.findProjectTopic(projectName, topicName)
+	if err != nil {
+		return pubsub.Publish(client, projectName, pubsub.GetResourceNameForTopic(projectName, topicName), messages)
+	}
+
+	encoded, err := encodeTopicMessages(project, topic, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return pubsub.Publish(client, project.Name, pubsub.GetResourceNameForTopic(project.Name, topic.Name), encoded)
+}
+
+// DecodeMessage returns the data of a message published to a topic of the
+// configuration as JSON, decoding it with the schema of the topic when its
+// encoding is BINARY. The data is returned as is when it can't be decoded.
+func (c Configuration) DecodeMessage(projectName, topicName string, data []byte) []byte {
+	project, topic, err := c.findProjectTopic(projectName, topicName)
+	if err != nil {
+		return data
+	}
+
+	definitions, err := topicMessageDefinitions(project, topic)
+	if err != nil {
+		return data
+	}
+	for i := len(definitions) - 1; i >= 0; i-- {
+		if decoded, err := definitions[i].DecodeMessage(string(topic.SchemaSettings.Encoding), data); err == nil {
+			return decoded
+		}
+	}
+	return data
+}
+
+// DecodedMessage is a message pulled from a subscription with its data
+// decoded, in the same format as the messages of the configuration: JSON
+// data as is, text as a JSON string and anything else in base64.
+type DecodedMessage struct {
+	MessageId   string            `json:"messageId,omitempty"`
+	PublishTime string            `json:"publishTime,omitempty"`
+	Data        json.RawMessage   `json:"data,omitempty"`
+	DataBase64  string            `json:"dataBase64,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// DecodeReceivedMessage decodes a message pulled from a subscription, using
+// the schema of its topic when the subscription is in the configuration.
+func (c Configuration) DecodeReceivedMessage(projectName, subscriptionName string, message pubsub.PubsubMessage) DecodedMessage {
+	topicName, _ := c.SubscriptionTopic(projectName, subscriptionName)
+	return c.DecodeTopicMessage(projectName, topicName, message)
+}
+
+// DecodeTopicMessage decodes a message published to a topic, using the schema
+// of the topic when it's in the configuration.
+func (c Configuration) DecodeTopicMessage(projectName, topicName string, message pubsub.PubsubMessage) DecodedMessage {
+	decoded := DecodedMessage{
+		MessageId:   message.MessageId,
+		PublishTime: message.PublishTime,
+		Attributes:  message.Attributes,
+		OrderingKey: message.OrderingKey,
+	}
+
+	data := c.DecodeMessage(projectName, topicName, message.Data)
+
+	switch {
+	case json.Valid(data):
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err == nil {
+			decoded.Data = compact.Bytes()
+			break
+		}
+		decoded.Data = data
+	case pubsub.IsText(data):
+		decoded.Data, _ = json.Marshal(string(data))
+	default:
+		decoded.DataBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	return decoded
+}
+
+// PullMessages pulls up to maxMessages messages of a subscription and returns
+// them decoded. They are acknowledged when ack is true, and made available
+// again right away otherwise.
+func (c Configuration) PullMessages(client utils.ClientInterface, projectName, subscriptionName string, maxMessages int, ack bool) ([]DecodedMessage, error) {
+	subscriptionResourceName := pubsub.GetResourceNameForSubscription(projectName, subscriptionName)
+	received, err := pubsub.Pull(client, projectName, subscriptionResourceName, maxMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []DecodedMessage{}
+	ackIds := []string{}
+	for _, receivedMessage := range received {
+		messages = append(messages, c.DecodeReceivedMessage(projectName, subscriptionName, receivedMessage.Message))
+		ackIds = append(ackIds, receivedMessage.AckId)
+	}
+
+	if len(ackIds) > 0 {
+		if ack {
+			err = pubsub.Acknowledge(client, projectName, subscriptionResourceName, ackIds)
+		} else {
+			err = pubsub.ModifyAckDeadline(client, projectName, subscriptionResourceName, ackIds, 0)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// SubscriptionTopic returns the name of the topic of a subscription of the
+// configuration.
+func (c Configuration) SubscriptionTopic(projectName, subscriptionName string) (string, bool) {
+	project, exists := c.findProject(projectName)
+	if !exists {
+		return "", false
+	}
+	for _, topic := range project.Topics {
+		if _, exists := findSubscription(topic, subscriptionName); exists {
+			return topic.Name, true
+		}
+	}
+	return "", false
+}
+
+func (c Configuration) findProjectTopic(projectName, topicName string) (pubsub.Project, pubsub.Topic, error) {
+	project, exists := c.findProject(projectName)
+	if !exists {
+		return pubsub.Project{}, pubsub.Topic{}, fmt.Errorf("project '%s' is not in the configuration", projectName)
+	}
+	topic, exists := findTopic(project, topicName)
+	if !exists {
+		return pubsub.Project{}, pubsub.Topic{}, fmt.Errorf("topic '%s' is not in project '%s'", topicName, projectName)
+	}
+	return project, topic, nil
+}
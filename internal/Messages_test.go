@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const messagesTestConfiguration = `{
+  "projects": [{
+    "name": "first-project",
+    "schemas": [{
+      "name": "product",
+      "type": "AVRO",
+      "revisions": [
+        {"alias": "v1", "definition": "{\"type\":\"record\",\"name\":\"Product\",\"fields\":[{\"name\":\"ProductName\",\"type\":\"string\"}]}"},
+        {"alias": "v2", "definition": "{\"type\":\"record\",\"name\":\"Product\",\"fields\":[{\"name\":\"ProductTitle\",\"type\":\"string\"}]}"}
+      ]
+    }],
+    "topics": [{
+      "name": "products",
+      "schemaSettings": {"schema": "product", "encoding": "JSON", "firstRevisionId": "v1", "lastRevisionId": "v2"},
+      "messages": [{"data": {"ProductName": "Shoe"}}],
+      "messagesFile": "messages.jsonl",
+      "subscriptions": [{"name": "products-subscription"}]
+    }]
+  }]
+}`
+
+func newMessagesTestReader(messages string) *utils.FileReaderMock {
+	return &utils.FileReaderMock{
+		ReadFunc: func(filePath string) ([]byte, error) {
+			switch filePath {
+			case "config/test_config.json":
+				return []byte(messagesTestConfiguration), nil
+			case "config/messages.jsonl":
+				return []byte(messages), nil
+			default:
+				return nil, fmt.Errorf("file '%s' not found", filePath)
+			}
+		},
+	}
+}
+
+func Test_Messages_Parse(t *testing.T) {
+	messages, err := ParseMessages([]byte(`[{"data": "a"}, {"dataBase64": "AAE="}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(messages))
+
+	messages, err = ParseMessages([]byte("{\"data\": \"a\"}\n\n{\"data\": \"b\", \"orderingKey\": \"k\"}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "k", messages[1].OrderingKey)
+
+	_, err = ParseMessages([]byte("{\"data\": \"a\"}\n{\"data\": "))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func Test_Messages_LoadFile(t *testing.T) {
+	config, err := LoadConfigurationFromFile(newMessagesTestReader(`{"data": {"ProductTitle": "Boot"}}`), "config/test_config.json")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(config.Projects[0].Topics[0].Messages))
+	assert.Equal(t, []string{"config/test_config.json", "config/messages.jsonl"}, config.ReferencedFiles())
+}
+
+func Test_Messages_LoadFile_WithInvalidMessage(t *testing.T) {
+	_, err := LoadConfigurationFromFile(newMessagesTestReader(`{"data": {"ProductTitle": 1}}`), "config/test_config.json")
+	assert.EqualError(t, err, "topic 'products' message 1: field 'ProductTitle': expected string, found number 1")
+}
+
+func Test_Messages_ValidateMessage(t *testing.T) {
+	config, err := LoadConfigurationFromFile(newMessagesTestReader(``), "config/test_config.json")
+	assert.NoError(t, err)
+
+	assert.NoError(t, config.ValidateMessage("first-project", "products", []byte(`{"ProductName": "Shoe"}`)))
+	assert.EqualError(t, config.ValidateMessage("first-project", "products", []byte(`{}`)), "message: missing field 'ProductTitle'")
+	assert.Error(t, config.ValidateMessage("first-project", "orders", []byte(`{}`)))
+}
+
+func Test_Messages_PublishMessages(t *testing.T) {
+	config, err := LoadConfigurationFromFile(newMessagesTestReader(``), "config/test_config.json")
+	assert.NoError(t, err)
+
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}, Error: nil},
+		},
+	}
+
+	_, err = config.PublishMessages(mockClient, "first-project", "products", []pubsub.Message{{Data: json.RawMessage(`{"Name": "Shoe"}`)}})
+	assert.Error(t, err)
+	assert.Equal(t, 0, len(mockClient.RequestHistory))
+
+	messageIds, err := config.PublishMessages(mockClient, "first-project", "products", config.Projects[0].Topics[0].Messages)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, messageIds)
+	assert.Equal(t, "projects/first-project/topics/products:publish", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Messages_PublishMessages_EncodesBinary(t *testing.T) {
+	config, err := LoadConfigurationFromFile(newMessagesTestReader(``), "config/test_config.json")
+	assert.NoError(t, err)
+	config.Projects[0].Topics[0].SchemaSettings.Encoding = pubsub.SchemaEncoding("BINARY")
+
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}, Error: nil},
+		},
+	}
+
+	_, err = config.PublishMessages(mockClient, "first-project", "products", []pubsub.Message{{Data: json.RawMessage(`{"ProductTitle": "Shoe"}`)}})
+	assert.NoError(t, err)
+	// Avro binary of "Shoe"
+	assert.JSONEq(t, `{"messages":[{"data":"CFNob2U="}]}`, string(mockClient.RequestHistory[0].Body))
+
+	decoded := config.DecodeReceivedMessage("first-project", "products-subscription", pubsub.PubsubMessage{Data: []byte("\x08Shoe"), MessageId: "1"})
+	assert.Equal(t, DecodedMessage{MessageId: "1", Data: json.RawMessage(`{"ProductTitle":"Shoe"}`)}, decoded)
+
+	decoded = config.DecodeReceivedMessage("first-project", "unknown-subscription", pubsub.PubsubMessage{Data: []byte("\x08Shoe")})
+	assert.Equal(t, DecodedMessage{DataBase64: "CFNob2U="}, decoded)
+
+	decoded = config.DecodeReceivedMessage("first-project", "unknown-subscription", pubsub.PubsubMessage{Data: []byte("hello")})
+	assert.Equal(t, DecodedMessage{Data: json.RawMessage(`"hello"`)}, decoded)
+}
+
+func Test_Messages_LoadFile_WithTemplates(t *testing.T) {
+	config, err := LoadConfigurationFromFile(newMessagesTestReader(`{"template": "{\"ProductTitle\": \"{{ word }} {{ seq }}\"}", "count": 100}`), "config/test_config.json")
+	assert.NoError(t, err)
+	assert.Equal(t, 101, len(config.Projects[0].Topics[0].Messages))
+	assert.Equal(t, "", config.Projects[0].Topics[0].Messages[100].Template)
+
+	_, err = LoadConfigurationFromFile(newMessagesTestReader(`{"template": "{\"ProductTitle\": {{ seq }}}", "count": 2}`), "config/test_config.json")
+	assert.EqualError(t, err, "topic 'products' message 1: field 'ProductTitle': expected string, found number 1")
+}
+
+func Test_Messages_PullMessages(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"aGVsbG8=","messageId":"1"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	messages, err := Configuration{}.PullMessages(mockClient, "first-project", "products-subscription", 5, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []DecodedMessage{{MessageId: "1", Data: json.RawMessage(`"hello"`)}}, messages)
+	assert.Equal(t, "projects/first-project/subscriptions/products-subscription:modifyAckDeadline", mockClient.RequestHistory[1].Path)
+}
+
+func Test_Messages_LoadFile_WithInvalidRevisionRange(t *testing.T) {
+	newReader := func(schemaSettings string) *utils.FileReaderMock {
+		configuration := strings.Replace(messagesTestConfiguration, `"firstRevisionId": "v1", "lastRevisionId": "v2"`, schemaSettings, 1)
+		return &utils.FileReaderMock{
+			ReadFunc: func(filePath string) ([]byte, error) {
+				if filePath == "config/messages.jsonl" {
+					return []byte(`{"data": {"ProductTitle": "Boot"}}`), nil
+				}
+				return []byte(configuration), nil
+			},
+		}
+	}
+
+	_, err := LoadConfigurationFromFile(newReader(`"firstRevisionId": "v2", "lastRevisionId": "v1"`), "config/test_config.json")
+	assert.EqualError(t, err, "topic 'products': first revision 'v2' is newer than last revision 'v1' of schema 'product'")
+
+	_, err = LoadConfigurationFromFile(newReader(`"firstRevisionId": "v1", "lastRevisionId": "v9"`), "config/test_config.json")
+	assert.EqualError(t, err, "topic 'products': revision 'v9' is not a revision of schema 'product'")
+}
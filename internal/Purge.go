@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+const (
+	// Seeks to now, pulling and acknowledging when seeking fails.
+	PURGE_METHOD_AUTO = "auto"
+	PURGE_METHOD_SEEK = "seek"
+	PURGE_METHOD_PULL = "pull"
+)
+
+// purgeBatchSize is the number of messages pulled at once when purging.
+const purgeBatchSize = 1000
+
+// PurgeTarget is a subscription to purge.
+type PurgeTarget struct {
+	Project      string
+	Subscription string
+}
+
+// PurgeSubscription removes the pending messages of a subscription, keeping
+// the subscription and its settings.
+func PurgeSubscription(client utils.ClientInterface, project, subscriptionResourceName, method string) error {
+	switch method {
+	case PURGE_METHOD_SEEK:
+		return pubsub.Seek(client, project, subscriptionResourceName, time.Now())
+	case PURGE_METHOD_PULL:
+		return purgeByPulling(client, project, subscriptionResourceName)
+	case PURGE_METHOD_AUTO, "":
+		if err := pubsub.Seek(client, project, subscriptionResourceName, time.Now()); err != nil {
+			Llog.Debug(fmt.Sprintf("Can't seek subscription '%s', pulling its messages instead: %s", subscriptionResourceName, err))
+			return purgeByPulling(client, project, subscriptionResourceName)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown purge method '%s', use %s, %s or %s", method, PURGE_METHOD_AUTO, PURGE_METHOD_SEEK, PURGE_METHOD_PULL)
+	}
+}
+
+// purgeByPulling acknowledges every message of the subscription.
+func purgeByPulling(client utils.ClientInterface, project, subscriptionResourceName string) error {
+	for {
+		received, err := pubsub.Pull(client, project, subscriptionResourceName, purgeBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(received) == 0 {
+			return nil
+		}
+
+		ackIds := make([]string, len(received))
+		for i, receivedMessage := range received {
+			ackIds[i] = receivedMessage.AckId
+		}
+		if err := pubsub.Acknowledge(client, project, subscriptionResourceName, ackIds); err != nil {
+			return err
+		}
+	}
+}
+
+// PurgeTargets returns every subscription of the configuration.
+func (c Configuration) PurgeTargets() []PurgeTarget {
+	targets := []PurgeTarget{}
+	for _, project := range c.Projects {
+		for _, topic := range project.Topics {
+			for _, subscription := range topic.Subscriptions {
+				targets = append(targets, PurgeTarget{Project: project.Name, Subscription: subscription.Name})
+			}
+		}
+	}
+	return targets
+}
+
+// TopicPurgeTargets returns the subscriptions attached to a topic in the
+// emulator, including the ones not in the configuration or in other
+// projects.
+func TopicPurgeTargets(client utils.ClientInterface, projectName, topicName string) ([]PurgeTarget, error) {
+	subscriptions, err := pubsub.ListTopicSubscriptions(client, projectName, pubsub.GetResourceNameForTopic(projectName, topicName))
+	if err != nil {
+		return nil, fmt.Errorf("can't list the subscriptions of topic '%s' in project '%s': %w", topicName, projectName, err)
+	}
+
+	targets := []PurgeTarget{}
+	for _, subscriptionResourceName := range subscriptions {
+		parts := strings.Split(subscriptionResourceName, "/")
+		if len(parts) != 4 || parts[0] != "projects" || parts[2] != "subscriptions" {
+			return nil, fmt.Errorf("unexpected subscription name '%s' of topic '%s'", subscriptionResourceName, topicName)
+		}
+		targets = append(targets, PurgeTarget{Project: parts[1], Subscription: parts[3]})
+	}
+	return targets, nil
+}
+
+// Purge removes the pending messages of the subscriptions concurrently,
+// returning every error found.
+func (c Configuration) Purge(client utils.ClientInterface, targets []PurgeTarget, method string) error {
+	tasks := []func() error{}
+	for _, target := range targets {
+		tasks = append(tasks, func() error {
+			if err := PurgeSubscription(client, target.Project, pubsub.GetResourceNameForSubscription(target.Project, target.Subscription), method); err != nil {
+				return fmt.Errorf("error purging subscription '%s' in project '%s': %w", target.Subscription, target.Project, err)
+			}
+			Llog.Info(fmt.Sprintf("Purged subscription '%s' in project '%s'", target.Subscription, target.Project))
+			return nil
+		})
+	}
+
+	return errors.Join(utils.RunConcurrently(utils.Concurrency(c.ProvisioningConcurrency), tasks)...)
+}
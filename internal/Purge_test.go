@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Purge_Seek(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	assert.NoError(t, PurgeSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", PURGE_METHOD_SEEK))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:seek", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Purge_AutoFallsBackToPull(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusNotImplemented}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"messageId":"1"}},{"ackId":"a2","message":{"messageId":"2"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	assert.NoError(t, PurgeSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", PURGE_METHOD_AUTO))
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:pull", mockClient.RequestHistory[1].Path)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:acknowledge", mockClient.RequestHistory[2].Path)
+	assert.JSONEq(t, `{"ackIds":["a1","a2"]}`, string(mockClient.RequestHistory[2].Body))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:pull", mockClient.RequestHistory[3].Path)
+
+	assert.Error(t, PurgeSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", "wipe"))
+}
+
+func Test_Purge_Targets(t *testing.T) {
+	config := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{Name: "orders", Subscriptions: []pubsub.Subscription{{Name: "orders-a"}, {Name: "orders-b"}}},
+					{Name: "payments", Subscriptions: []pubsub.Subscription{{Name: "payments-a"}}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, 3, len(config.PurgeTargets()))
+
+	// Live subscriptions, even the ones not in the configuration
+	topicClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":["projects/test-project/subscriptions/orders-a","projects/other-project/subscriptions/orders-audit"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusNotFound}, Error: nil},
+		},
+	}
+	targets, err := TopicPurgeTargets(topicClient, "test-project", "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []PurgeTarget{{Project: "test-project", Subscription: "orders-a"}, {Project: "other-project", Subscription: "orders-audit"}}, targets)
+	assert.Equal(t, "projects/test-project/topics/orders/subscriptions", topicClient.RequestHistory[0].Path)
+
+	_, err = TopicPurgeTargets(topicClient, "test-project", "refunds")
+	assert.Error(t, err)
+
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusNotFound}, Error: nil},
+		},
+	}
+	config.ProvisioningConcurrency = 1
+	err = config.Purge(mockClient, targets, PURGE_METHOD_SEEK)
+	assert.EqualError(t, err, "error purging subscription 'orders-audit' in project 'other-project': error seeking subscription: status code 404")
+}
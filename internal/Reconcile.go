@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/readiness"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// changeForResourceName builds the change deleting a resource given its full
+// name, like projects/{project}/topics/{topic}.
+func changeForResourceName(resourceName string) (Change, bool) {
+	parts := strings.Split(resourceName, "/")
+	if len(parts) != 4 || parts[0] != "projects" {
+		return Change{}, false
+	}
+
+	switch parts[2] {
+	case "topics":
+		return Change{CHANGE_ACTION_DELETE, RESOURCE_KIND_TOPIC, parts[1], "", parts[3]}, true
+	case "subscriptions":
+		return Change{CHANGE_ACTION_DELETE, RESOURCE_KIND_SUBSCRIPTION, parts[1], "", parts[3]}, true
+	default:
+		return Change{}, false
+	}
+}
+
+// reconcileChanges returns the changes moving the emulator to the
+// configuration given its drift: deleting the resources not in the
+// configuration, creating the missing ones, recreating the subscriptions
+// attached to another topic and updating the rest. Existing schemas are
+// updated, committing the revisions they are missing.
+func (c Configuration) reconcileChanges(report DriftReport) []Change {
+	// Subscriptions first, so topics are not deleted while they still have
+	// subscriptions attached.
+	changes := []Change{}
+	for _, resourceName := range report.Unexpected {
+		if change, ok := changeForResourceName(resourceName); ok && change.Kind == RESOURCE_KIND_SUBSCRIPTION {
+			changes = append(changes, change)
+		}
+	}
+	for _, resourceName := range report.Unexpected {
+		if change, ok := changeForResourceName(resourceName); ok && change.Kind == RESOURCE_KIND_TOPIC {
+			changes = append(changes, change)
+		}
+	}
+
+	missing := map[Change]bool{}
+	for _, change := range report.Missing {
+		missing[change] = true
+	}
+
+	moved := map[Change]bool{}
+	for _, change := range report.Moved {
+		moved[change] = true
+	}
+
+	for _, project := range c.Projects {
+		for _, schema := range project.Schemas {
+			create := Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_SCHEMA, project.Name, "", schemaKey(schema)}
+			if missing[create] {
+				changes = append(changes, create)
+			} else {
+				changes = append(changes, Change{CHANGE_ACTION_UPDATE, RESOURCE_KIND_SCHEMA, project.Name, "", schemaKey(schema)})
+			}
+		}
+	}
+
+	for _, project := range c.Projects {
+		for _, topic := range project.Topics {
+			create := Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_TOPIC, project.Name, "", topic.Name}
+			if missing[create] {
+				changes = append(changes, create)
+			} else {
+				changes = append(changes, Change{CHANGE_ACTION_UPDATE, RESOURCE_KIND_TOPIC, project.Name, "", topic.Name})
+			}
+		}
+	}
+
+	for _, project := range c.Projects {
+		for _, topic := range project.Topics {
+			for _, subscription := range topic.Subscriptions {
+				create := Change{CHANGE_ACTION_CREATE, RESOURCE_KIND_SUBSCRIPTION, project.Name, topic.Name, subscription.Name}
+				recreate := Change{CHANGE_ACTION_RECREATE, RESOURCE_KIND_SUBSCRIPTION, project.Name, topic.Name, subscription.Name}
+				switch {
+				case missing[create]:
+					changes = append(changes, create)
+				case moved[recreate]:
+					changes = append(changes, recreate)
+				default:
+					changes = append(changes, Change{CHANGE_ACTION_UPDATE, RESOURCE_KIND_SUBSCRIPTION, project.Name, topic.Name, subscription.Name})
+				}
+			}
+		}
+	}
+
+	return changes
+}
+
+// Plan returns the changes Reconcile would make, without touching the
+// emulator. The existing resources are only listed as updated when some of
+// their settings differ from the configuration, or when they are schemas
+// missing revisions.
+func (c Configuration) Plan(client utils.ClientInterface) ([]Change, error) {
+	report, err := DetectDrift(client, c)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	// Schemas getting revisions or created, the topics using them change
+	changedSchemas := map[string]bool{}
+	for _, change := range c.reconcileChanges(report) {
+		if change.Action == CHANGE_ACTION_UPDATE {
+			updated, err := c.updatesSettings(client, change, changedSchemas)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", change, err)
+			}
+			if !updated {
+				continue
+			}
+		}
+		if change.Kind == RESOURCE_KIND_SCHEMA {
+			changedSchemas[change.Project+"/"+change.Name] = true
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// updatesSettings is true when applying the update would change the existing
+// resource.
+func (c Configuration) updatesSettings(client utils.ClientInterface, change Change, changedSchemas map[string]bool) (bool, error) {
+	project, exists := c.findProject(change.Project)
+	if !exists {
+		return false, fmt.Errorf("project '%s' not found in the configuration", change.Project)
+	}
+
+	switch change.Kind {
+	case RESOURCE_KIND_SCHEMA:
+		schema, exists := findSchema(project, change.Name)
+		if !exists {
+			return false, fmt.Errorf("schema '%s' not found in the configuration", change.Name)
+		}
+		committed, err := pubsub.ListSchemaRevisions(client, project.Name, schemaKey(schema))
+		if err != nil {
+			return false, err
+		}
+		for _, revision := range schema.SchemaRevisions() {
+			if _, exists := findCommittedRevision(committed, revision); !exists {
+				return true, nil
+			}
+		}
+		return false, nil
+	case RESOURCE_KIND_TOPIC:
+		topic, exists := findTopic(project, change.Name)
+		if !exists {
+			return false, fmt.Errorf("topic '%s' not found in the configuration", change.Name)
+		}
+		if topic.SchemaSettings != nil {
+			// The revisions of the schema can't be resolved before being committed
+			if schema, exists := findSchemaByReference(project, topic.SchemaSettings.Schema); exists && changedSchemas[project.Name+"/"+schemaKey(schema)] {
+				return true, nil
+			}
+		}
+		updateMask, err := topicUpdateMask(client, project, topic)
+		return len(updateMask) > 0, err
+	case RESOURCE_KIND_SUBSCRIPTION:
+		topic, exists := findTopic(project, change.Topic)
+		if !exists {
+			return false, fmt.Errorf("topic '%s' not found in the configuration", change.Topic)
+		}
+		subscription, exists := findSubscription(topic, change.Name)
+		if !exists {
+			return false, fmt.Errorf("subscription '%s' not found in the configuration", change.Name)
+		}
+		updateMask, err := subscriptionUpdateMask(client, project, topic, subscription)
+		return len(updateMask) > 0, err
+	default:
+		return false, fmt.Errorf("resources of kind '%s' can't be updated", change.Kind)
+	}
+}
+
+// Reconcile applies the configuration keeping the data of the resources that
+// already exist: the missing resources are created, the existing ones
+// updated in place and the ones not in the configuration deleted. With
+// PurgeOnSync, the messages of the subscriptions are removed afterwards.
+func (c *Configuration) Reconcile(client utils.ClientInterface) error {
+	if err := c.guardSchemaCompatibility(); err != nil {
+		return err
+	}
+
+	if !c.AvoidStartupCheck {
+		if err := readiness.WaitUntilReady(context.Background(), client, c.ReadinessOptions()); err != nil {
+			return err
+		}
+	}
+
+	report, err := DetectDrift(client, *c)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ApplyChanges(client, c.reconcileChanges(report)); err != nil {
+		return err
+	}
+
+	if c.PurgeOnSync {
+		return c.Purge(client, c.PurgeTargets(), PURGE_METHOD_AUTO)
+	}
+	return nil
+}
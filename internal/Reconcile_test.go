@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reconcile(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"},{"name":"projects/test-project/topics/old-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			// Deleting the topic not in the configuration
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			// Updating the existing topic
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			// Creating the missing subscription
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	config := Configuration{
+		AvoidStartupCheck: true,
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name:          "test-topic",
+						Labels:        pubsub.Labels{"owner": "admin"},
+						Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}},
+					},
+				},
+			},
+		},
+	}
+
+	err := config.Reconcile(mockClient)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[2].Method)
+	assert.Equal(t, "projects/test-project/topics/old-topic", mockClient.RequestHistory[2].Path)
+	assert.Equal(t, http.MethodPatch, mockClient.RequestHistory[4].Method)
+	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[4].Path)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[5].Method)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[5].Path)
+}
+
+func Test_Reconcile_Plan(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"},{"name":"projects/test-project/topics/kept-topic"},{"name":"projects/test-project/topics/old-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			// Settings of the existing topics, only the first one differs
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic","labels":{"owner":"nobody"}}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/kept-topic"}`)}, Error: nil},
+		},
+	}
+
+	config := Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name:          "test-topic",
+						Labels:        pubsub.Labels{"owner": "admin"},
+						Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}},
+					},
+					{Name: "kept-topic"},
+				},
+			},
+		},
+	}
+
+	changes, err := config.Plan(mockClient)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{CHANGE_ACTION_DELETE, RESOURCE_KIND_TOPIC, "test-project", "", "old-topic"},
+		{CHANGE_ACTION_UPDATE, RESOURCE_KIND_TOPIC, "test-project", "", "test-topic"},
+		{CHANGE_ACTION_CREATE, RESOURCE_KIND_SUBSCRIPTION, "test-project", "test-topic", "test-subscription"},
+	}, changes)
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+	for _, request := range mockClient.RequestHistory {
+		assert.Equal(t, http.MethodGet, request.Method)
+	}
+}
+
+func Test_Reconcile_SyncWithPurgeOnSync(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"projects/test-project/subscriptions/test-subscription"}]}`)}, Error: nil},
+			// Updating the existing topic and subscription
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/subscriptions/test-subscription","topic":"projects/test-project/topics/test-topic"}`)}, Error: nil},
+			// Purging the subscription
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			// Seeding the topic
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}, Error: nil},
+		},
+	}
+
+	config := Configuration{
+		AvoidStartupCheck: true,
+		PurgeOnSync:       true,
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name:          "test-topic",
+						Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}},
+						Messages:      []pubsub.Message{{Data: []byte(`"seed"`)}},
+					},
+				},
+			},
+		},
+	}
+
+	err := config.Sync(mockClient)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(mockClient.RequestHistory))
+	for _, request := range mockClient.RequestHistory {
+		assert.NotEqual(t, http.MethodDelete, request.Method)
+	}
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:seek", mockClient.RequestHistory[4].Path)
+	assert.Equal(t, "projects/test-project/topics/test-topic:publish", mockClient.RequestHistory[5].Path)
+}
+
+func Test_Reconcile_CommitsRevisionsAndRecreatesMovedSubscriptions(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"projects/test-project/subscriptions/test-subscription","topic":"projects/test-project/topics/other-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"name":"projects/test-project/schemas/test-schema"}]}`)}, Error: nil},
+			// Committing the missing revision of the existing schema
+			{Response: utils.Response{StatusCode: http.StatusConflict}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"a1","definition":"{\"type\":\"string\"}"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			// Updating the existing topic
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic"}`)}, Error: nil},
+			// Recreating the subscription on its topic
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	config := Configuration{
+		AvoidStartupCheck: true,
+		Projects: []pubsub.Project{
+			{
+				Name:    "test-project",
+				Schemas: []pubsub.Schema{newSchemaWithRevisions()},
+				Topics: []pubsub.Topic{
+					{
+						Name:          "test-topic",
+						Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}},
+					},
+				},
+			},
+		},
+	}
+
+	err := config.Reconcile(mockClient)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/schemas/test-schema:commit", mockClient.RequestHistory[5].Path)
+	assert.Contains(t, string(mockClient.RequestHistory[5].Body), `{\"type\":\"int\"}`)
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[7].Method)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[7].Path)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[8].Method)
+	assert.Contains(t, string(mockClient.RequestHistory[8].Body), `"topic":"projects/test-project/topics/test-topic"`)
+	for _, request := range mockClient.RequestHistory {
+		assert.NotEqual(t, http.MethodPatch, request.Method)
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/schema"
+)
+
+// schemaCompatibilityMode returns the compatibility mode of a schema, its own
+// one or the given default.
+func schemaCompatibilityMode(pubsubSchema pubsub.Schema, defaultMode string) (string, error) {
+	mode := pubsubSchema.Compatibility
+	if mode == "" {
+		mode = defaultMode
+	}
+	return schema.ParseCompatibility(mode)
+}
+
+// schemaRevisionsCompatibility compares every revision of the schema with
+// the previous one, returning the problems found prefixed by the schema and
+// the aliases compared.
+func schemaRevisionsCompatibility(pubsubSchema pubsub.Schema, mode string) ([]string, error) {
+	problems := []string{}
+	revisions := pubsubSchema.SchemaRevisions()
+
+	var previous *schema.Definition
+	for i, revision := range revisions {
+		current, err := schema.Parse(pubsubSchema.Type, revision.Definition)
+		if err != nil {
+			return nil, fmt.Errorf("schema '%s' revision '%s': %w", pubsubSchema.Name, revision.Alias, err)
+		}
+
+		if previous != nil {
+			for _, problem := range schema.CheckCompatibility(mode, previous, current) {
+				problems = append(problems, fmt.Sprintf("schema '%s' %s -> %s (%s): %s", pubsubSchema.Name, revisions[i-1].Alias, revision.Alias, mode, problem))
+			}
+		}
+		previous = current
+	}
+	return problems, nil
+}
+
+// CheckSchemaCompatibility returns the compatibility problems between the
+// consecutive revisions of the schemas of a project, or of a single schema
+// when schemaReference is given. An empty mode uses the one of each schema,
+// then the one of the configuration and BACKWARD otherwise.
+func (c Configuration) CheckSchemaCompatibility(projectName, schemaReference, mode string) ([]string, error) {
+	project, exists := c.findProject(projectName)
+	if !exists {
+		return nil, fmt.Errorf("project '%s' is not in the configuration", projectName)
+	}
+
+	schemas := project.Schemas
+	if schemaReference != "" {
+		pubsubSchema, exists := findSchemaByReference(project, schemaReference)
+		if !exists {
+			return nil, fmt.Errorf("schema '%s' is not in project '%s'", schemaReference, projectName)
+		}
+		schemas = []pubsub.Schema{pubsubSchema}
+	}
+
+	problems := []string{}
+	for _, pubsubSchema := range schemas {
+		schemaMode := mode
+		if schemaMode == "" {
+			var err error
+			if schemaMode, err = schemaCompatibilityMode(pubsubSchema, c.SchemaCompatibility); err != nil {
+				return nil, fmt.Errorf("schema '%s': %w", pubsubSchema.Name, err)
+			}
+		}
+
+		schemaProblems, err := schemaRevisionsCompatibility(pubsubSchema, schemaMode)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, schemaProblems...)
+	}
+	return problems, nil
+}
+
+// guardSchemaCompatibility fails when the revisions of a schema break the
+// compatibility required by the configuration (or by the schema itself).
+// Nothing is checked for schemas without a compatibility mode.
+func (c Configuration) guardSchemaCompatibility() error {
+	problems := []string{}
+	for _, project := range c.Projects {
+		for _, pubsubSchema := range project.Schemas {
+			if pubsubSchema.Compatibility == "" && c.SchemaCompatibility == "" {
+				continue
+			}
+
+			mode, err := schemaCompatibilityMode(pubsubSchema, c.SchemaCompatibility)
+			if err != nil {
+				return fmt.Errorf("schema '%s': %w", pubsubSchema.Name, err)
+			}
+
+			schemaProblems, err := schemaRevisionsCompatibility(pubsubSchema, mode)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, schemaProblems...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("incompatible schema revisions: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateSchemaCompatibilityModes checks the compatibility modes of the
+// configuration are known.
+func (c Configuration) validateSchemaCompatibilityModes() error {
+	if _, err := schema.ParseCompatibility(c.SchemaCompatibility); err != nil {
+		return err
+	}
+	for _, project := range c.Projects {
+		for _, pubsubSchema := range project.Schemas {
+			if _, err := schema.ParseCompatibility(pubsubSchema.Compatibility); err != nil {
+				return fmt.Errorf("schema '%s': %w", pubsubSchema.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSchemaCompatibilityTestConfiguration(schemaCompatibility string) Configuration {
+	return Configuration{
+		Host:                "localhost:8085",
+		AvoidStartupCheck:   true,
+		SchemaCompatibility: schemaCompatibility,
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Schemas: []pubsub.Schema{
+					{
+						Name: "product",
+						Type: "AVRO",
+						Revisions: []pubsub.SchemaRevision{
+							{Alias: "v1", Definition: `{"type":"record","name":"Avro","fields":[{"name":"ProductName","type":"string","default":""}]}`},
+							{Alias: "v2", Definition: `{"type":"record","name":"Avro","fields":[{"name":"ProductTitle","type":"string","default":""}]}`},
+						},
+					},
+					{
+						Name:       "order",
+						Type:       "AVRO",
+						Definition: `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`,
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_SchemaCompatibility_Check(t *testing.T) {
+	config := newSchemaCompatibilityTestConfiguration("")
+
+	problems, err := config.CheckSchemaCompatibility("test-project", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`schema 'product' v1 -> v2 (BACKWARD): message: field 'ProductName' was renamed to 'ProductTitle', add "aliases": ["ProductName"] to keep reading it`,
+	}, problems)
+
+	problems, err = config.CheckSchemaCompatibility("test-project", "product", "NONE")
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	_, err = config.CheckSchemaCompatibility("test-project", "customer", "")
+	assert.Error(t, err)
+}
+
+func Test_SchemaCompatibility_SyncGuard(t *testing.T) {
+	mockClient := &utils.MockClient{}
+
+	config := newSchemaCompatibilityTestConfiguration("FULL")
+	err := config.Sync(mockClient)
+	assert.ErrorContains(t, err, "incompatible schema revisions: schema 'product' v1 -> v2 (FULL)")
+	assert.Equal(t, 0, len(mockClient.RequestHistory))
+
+	config.Projects[0].Schemas[0].Compatibility = "NONE"
+	assert.NoError(t, config.guardSchemaCompatibility())
+}
+
+func Test_SchemaCompatibility_LoadFile_WithUnknownMode(t *testing.T) {
+	mockReader := utils.NewFileReaderMockBasic(`{"schemaCompatibility": "SOMETIMES", "projects": []}`)
+
+	_, err := LoadConfigurationFromFile(mockReader, "test_config.json")
+	assert.EqualError(t, err, "unknown compatibility mode 'SOMETIMES'")
+}
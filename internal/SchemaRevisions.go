@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// sameSchemaDefinition compares two definitions ignoring the formatting of
+// JSON (Avro) definitions and the surrounding whitespace.
+func sameSchemaDefinition(a, b string) bool {
+	a = strings.TrimSpace(a)
+	b = strings.TrimSpace(b)
+	if a == b {
+		return true
+	}
+
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, []byte(a)) != nil || json.Compact(&compactB, []byte(b)) != nil {
+		return false
+	}
+	return compactA.String() == compactB.String()
+}
+
+// findSchemaByReference returns the schema a topic references, by its id or
+// its name, with or without the projects/{project}/schemas/ prefix.
+func findSchemaByReference(project pubsub.Project, reference string) (pubsub.Schema, bool) {
+	reference = strings.TrimPrefix(reference, pubsub.GetResourceNameForSchema(project.Name, ""))
+
+	if schema, exists := findSchema(project, reference); exists {
+		return schema, true
+	}
+	for _, schema := range project.Schemas {
+		if schema.Name == reference {
+			return schema, true
+		}
+	}
+	return pubsub.Schema{}, false
+}
+
+// provisionSchema creates the schema with its first revision and commits
+// the rest in order. When the schema already exists, only the revisions
+// whose definition was not committed yet are committed.
+func provisionSchema(client utils.ClientInterface, project pubsub.Project, schema pubsub.Schema) error {
+	revisions := schema.SchemaRevisions()
+
+	_, err := pubsub.CreateSchema(client, project.Name, schemaKey(schema), schema.Type, revisions[0].Definition)
+	switch {
+	case errors.Is(err, pubsub.ErrSchemaAlreadyExists):
+		committed, err := pubsub.ListSchemaRevisions(client, project.Name, schemaKey(schema))
+		if err != nil {
+			return err
+		}
+
+		for _, revision := range revisions {
+			if _, exists := findCommittedRevision(committed, revision); exists {
+				continue
+			}
+			if err := commitSchemaRevision(client, project, schema, revision); err != nil {
+				return err
+			}
+		}
+		return nil
+	case err != nil:
+		return err
+	}
+
+	for _, revision := range revisions[1:] {
+		if err := commitSchemaRevision(client, project, schema, revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func commitSchemaRevision(client utils.ClientInterface, project pubsub.Project, schema pubsub.Schema, revision pubsub.SchemaRevision) error {
+	committed, err := pubsub.CommitSchema(client, project.Name, schemaKey(schema), schema.Type, revision.Definition)
+	if err != nil {
+		return fmt.Errorf("error committing revision '%s': %w", revision.Alias, err)
+	}
+
+	Llog.Debug(fmt.Sprintf("Schema '%s' revision '%s' committed as '%s'", schemaKey(schema), revision.Alias, committed.RevisionId))
+	return nil
+}
+
+// findCommittedRevision returns the newest committed revision with the
+// definition of the given revision. Revisions are listed newest first.
+func findCommittedRevision(committed []pubsub.Schema, revision pubsub.SchemaRevision) (pubsub.Schema, bool) {
+	for _, committedRevision := range committed {
+		if sameSchemaDefinition(committedRevision.Definition, revision.Definition) {
+			return committedRevision, true
+		}
+	}
+	return pubsub.Schema{}, false
+}
+
+// ResolveSchemaRevision returns the revision id of a revision of a schema in
+// the configuration. The revision is referenced by its alias; anything that
+// is not an alias is considered a revision id already.
+func (c Configuration) ResolveSchemaRevision(client utils.ClientInterface, projectName, schemaReference, revisionReference string) (string, error) {
+	project, exists := c.findProject(projectName)
+	if !exists {
+		return revisionReference, nil
+	}
+	schema, exists := findSchemaByReference(project, schemaReference)
+	if !exists {
+		return revisionReference, nil
+	}
+	return resolveSchemaRevision(client, project, schema, revisionReference, nil)
+}
+
+// resolveSchemaRevision finds the revision id of the revision with the given
+// alias, matching its definition with the ones committed. The committed
+// revisions are listed when nil.
+func resolveSchemaRevision(client utils.ClientInterface, project pubsub.Project, schema pubsub.Schema, alias string, committed []pubsub.Schema) (string, error) {
+	for _, revision := range schema.Revisions {
+		if revision.Alias != alias {
+			continue
+		}
+
+		if committed == nil {
+			var err error
+			committed, err = pubsub.ListSchemaRevisions(client, project.Name, schemaKey(schema))
+			if err != nil {
+				return "", err
+			}
+		}
+
+		committedRevision, exists := findCommittedRevision(committed, revision)
+		if !exists {
+			return "", fmt.Errorf("revision '%s' of schema '%s' is not committed", alias, schemaKey(schema))
+		}
+		return committedRevision.RevisionId, nil
+	}
+
+	return alias, nil
+}
+
+// resolveSchemaSettings returns the settings to send to the emulator, with
+// the schema referenced by the id it was created with and the revision
+// aliases replaced by the revision ids.
+func resolveSchemaSettings(client utils.ClientInterface, project pubsub.Project, settings *pubsub.SchemaSettings) (*pubsub.SchemaSettings, error) {
+	if settings == nil || settings.FirstSchemaId != "" || settings.LastSchemaId != "" {
+		return settings, nil
+	}
+
+	schema, exists := findSchemaByReference(project, settings.Schema)
+	if !exists {
+		return settings, nil
+	}
+
+	resolved := *settings
+	resolved.Schema = schemaKey(schema)
+
+	if len(schema.Revisions) == 0 || (settings.FirstRevisionId == "" && settings.LastRevisionId == "") {
+		return &resolved, nil
+	}
+
+	committed, err := pubsub.ListSchemaRevisions(client, project.Name, schemaKey(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.FirstRevisionId != "" {
+		if resolved.FirstRevisionId, err = resolveSchemaRevision(client, project, schema, settings.FirstRevisionId, committed); err != nil {
+			return nil, err
+		}
+	}
+	if settings.LastRevisionId != "" {
+		if resolved.LastRevisionId, err = resolveSchemaRevision(client, project, schema, settings.LastRevisionId, committed); err != nil {
+			return nil, err
+		}
+	}
+
+	return &resolved, nil
+}
+
+// mergeLegacySchemaRevisions converts the schemas sharing the same name,
+// which is how revisions used to be declared, into a single schema with one
+// revision per entry using the ids as aliases. Topics referencing these ids
+// are moved to reference the revisions.
+func mergeLegacySchemaRevisions(project *pubsub.Project) {
+	entries := map[string]int{}
+	for _, schema := range project.Schemas {
+		if len(schema.Revisions) == 0 && schema.Id != "" {
+			entries[schema.Name]++
+		}
+	}
+
+	merged := []pubsub.Schema{}
+	mergedIndexes := map[string]int{}
+	aliases := map[string]string{}
+
+	for _, schema := range project.Schemas {
+		if len(schema.Revisions) > 0 || schema.Id == "" || entries[schema.Name] < 2 {
+			merged = append(merged, schema)
+			continue
+		}
+
+		index, exists := mergedIndexes[schema.Name]
+		if !exists {
+			Llog.Warn(fmt.Sprintf("Schemas named '%s' in project '%s' are declared as separate entries, use 'revisions' instead", schema.Name, project.Name))
+			index = len(merged)
+			mergedIndexes[schema.Name] = index
+			merged = append(merged, pubsub.Schema{Name: schema.Name, Type: schema.Type})
+		}
+
+		merged[index].Revisions = append(merged[index].Revisions, pubsub.SchemaRevision{
+			Alias:          schema.Id,
+			Definition:     schema.Definition,
+			DefinitionFile: schema.DefinitionFile,
+		})
+		aliases[schema.Id] = schema.Name
+	}
+
+	project.Schemas = merged
+
+	for i := range project.Topics {
+		settings := project.Topics[i].SchemaSettings
+		if settings == nil {
+			continue
+		}
+
+		if schemaName, exists := aliases[settings.FirstSchemaId]; exists {
+			settings.Schema = schemaName
+			settings.FirstRevisionId = settings.FirstSchemaId
+			settings.FirstSchemaId = ""
+		}
+		if schemaName, exists := aliases[settings.LastSchemaId]; exists {
+			settings.Schema = schemaName
+			settings.LastRevisionId = settings.LastSchemaId
+			settings.LastSchemaId = ""
+		}
+	}
+}
+
+// validateSchemaRevisions checks the revisions of the schemas can be told
+// apart.
+func validateSchemaRevisions(project pubsub.Project) error {
+	for _, schema := range project.Schemas {
+		if len(schema.Revisions) == 0 {
+			continue
+		}
+
+		if schema.Definition != "" || schema.DefinitionFile != "" {
+			return fmt.Errorf("schema '%s' can't have both revisions and a definition", schema.Name)
+		}
+
+		aliases := map[string]bool{}
+		for _, revision := range schema.Revisions {
+			if revision.Alias == "" {
+				return fmt.Errorf("every revision of schema '%s' requires an alias", schema.Name)
+			}
+			if aliases[revision.Alias] {
+				return fmt.Errorf("alias '%s' is used by more than one revision of schema '%s'", revision.Alias, schema.Name)
+			}
+			aliases[revision.Alias] = true
+		}
+	}
+	return nil
+}
+
+// SchemaId returns the id the schema referenced by id or name was created
+// with, the reference itself when it's not in the configuration.
+func (c Configuration) SchemaId(projectName, schemaReference string) string {
+	project, exists := c.findProject(projectName)
+	if !exists {
+		return schemaReference
+	}
+	schema, exists := findSchemaByReference(project, schemaReference)
+	if !exists {
+		return schemaReference
+	}
+	return schemaKey(schema)
+}
+
+// SchemaRevisionAlias returns the alias of the configured revision with the
+// definition of a committed revision, empty when there's none.
+func (c Configuration) SchemaRevisionAlias(projectName, schemaReference string, committed pubsub.Schema) string {
+	project, exists := c.findProject(projectName)
+	if !exists {
+		return ""
+	}
+	schema, exists := findSchemaByReference(project, schemaReference)
+	if !exists {
+		return ""
+	}
+
+	for _, revision := range schema.Revisions {
+		if sameSchemaDefinition(revision.Definition, committed.Definition) {
+			return revision.Alias
+		}
+	}
+	return ""
+}
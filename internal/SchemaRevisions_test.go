@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSchemaWithRevisions() pubsub.Schema {
+	return pubsub.Schema{
+		Name: "test-schema",
+		Type: "AVRO",
+		Revisions: []pubsub.SchemaRevision{
+			{Alias: "v1", Definition: `{"type":"string"}`},
+			{Alias: "v2", Definition: `{"type":"int"}`},
+		},
+	}
+}
+
+func Test_SchemaRevisions_Provision(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	err := provisionSchema(mockClient, pubsub.Project{Name: "test-project"}, newSchemaWithRevisions())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/schemas?schemaId=test-schema", mockClient.RequestHistory[0].Path)
+	assert.Contains(t, string(mockClient.RequestHistory[0].Body), `{\"type\":\"string\"}`)
+	assert.Equal(t, "projects/test-project/schemas/test-schema:commit", mockClient.RequestHistory[1].Path)
+	assert.Contains(t, string(mockClient.RequestHistory[1].Body), `{\"type\":\"int\"}`)
+}
+
+func Test_SchemaRevisions_Provision_CommitsOnlyMissingRevisions(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusConflict}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"a1","definition":"{ \"type\": \"string\" }"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	err := provisionSchema(mockClient, pubsub.Project{Name: "test-project"}, newSchemaWithRevisions())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/schemas/test-schema:commit", mockClient.RequestHistory[2].Path)
+	assert.Contains(t, string(mockClient.RequestHistory[2].Body), `{\"type\":\"int\"}`)
+}
+
+func Test_SchemaRevisions_ResolveSchemaSettings(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"b2","definition":"{\"type\":\"int\"}"},{"revisionId":"a1","definition":"{\"type\":\"string\"}"}]}`)}, Error: nil},
+		},
+	}
+
+	project := pubsub.Project{Name: "test-project", Schemas: []pubsub.Schema{newSchemaWithRevisions()}}
+	settings, err := resolveSchemaSettings(mockClient, project, &pubsub.SchemaSettings{
+		Schema:          "projects/test-project/schemas/test-schema",
+		Encoding:        pubsub.SCHEMA_ENCODING_JSON,
+		FirstRevisionId: "v1",
+		LastRevisionId:  "v2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &pubsub.SchemaSettings{
+		Schema:          "test-schema",
+		Encoding:        pubsub.SCHEMA_ENCODING_JSON,
+		FirstRevisionId: "a1",
+		LastRevisionId:  "b2",
+	}, settings)
+}
+
+func Test_SchemaRevisions_ResolveSchemaSettings_NotCommitted(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"a1","definition":"{\"type\":\"string\"}"}]}`)}, Error: nil},
+		},
+	}
+
+	project := pubsub.Project{Name: "test-project", Schemas: []pubsub.Schema{newSchemaWithRevisions()}}
+	_, err := resolveSchemaSettings(mockClient, project, &pubsub.SchemaSettings{Schema: "test-schema", LastRevisionId: "v2"})
+	assert.Error(t, err)
+}
+
+func Test_SchemaRevisions_MergeLegacySchemas(t *testing.T) {
+	project := pubsub.Project{
+		Name: "test-project",
+		Schemas: []pubsub.Schema{
+			{Id: "schemaV1", Name: "test-schema", Type: "AVRO", Definition: `{"type":"string"}`},
+			{Id: "schemaV2", Name: "test-schema", Type: "AVRO", Definition: `{"type":"int"}`},
+			{Id: "other", Name: "other-schema", Type: "AVRO", Definition: `{"type":"long"}`},
+		},
+		Topics: []pubsub.Topic{
+			{Name: "test-topic", SchemaSettings: &pubsub.SchemaSettings{Schema: "test-schema", FirstSchemaId: "schemaV1", LastSchemaId: "schemaV2"}},
+		},
+	}
+
+	mergeLegacySchemaRevisions(&project)
+	assert.Equal(t, []pubsub.Schema{
+		{
+			Name: "test-schema",
+			Type: "AVRO",
+			Revisions: []pubsub.SchemaRevision{
+				{Alias: "schemaV1", Definition: `{"type":"string"}`},
+				{Alias: "schemaV2", Definition: `{"type":"int"}`},
+			},
+		},
+		{Id: "other", Name: "other-schema", Type: "AVRO", Definition: `{"type":"long"}`},
+	}, project.Schemas)
+	assert.Equal(t, &pubsub.SchemaSettings{Schema: "test-schema", FirstRevisionId: "schemaV1", LastRevisionId: "schemaV2"}, project.Topics[0].SchemaSettings)
+}
+
+func Test_SchemaRevisions_Validate(t *testing.T) {
+	withoutAlias := pubsub.Project{Schemas: []pubsub.Schema{{Name: "test-schema", Revisions: []pubsub.SchemaRevision{{Definition: "{}"}}}}}
+	assert.Error(t, validateSchemaRevisions(withoutAlias))
+
+	repeatedAlias := pubsub.Project{Schemas: []pubsub.Schema{{Name: "test-schema", Revisions: []pubsub.SchemaRevision{{Alias: "v1"}, {Alias: "v1"}}}}}
+	assert.Error(t, validateSchemaRevisions(repeatedAlias))
+
+	withDefinition := pubsub.Project{Schemas: []pubsub.Schema{{Name: "test-schema", Definition: "{}", Revisions: []pubsub.SchemaRevision{{Alias: "v1"}}}}}
+	assert.Error(t, validateSchemaRevisions(withDefinition))
+}
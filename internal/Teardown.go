@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// Teardown deletes every snapshot, subscription, topic and schema of the
+// projects of the configuration, including the ones not in it, returning
+// every error found. Resources are deleted in that order, so nothing is in
+// use when deleted.
+func (c Configuration) Teardown(client utils.ClientInterface) error {
+	concurrency := utils.Concurrency(c.ProvisioningConcurrency)
+
+	phases := []func(project string) ([]func() error, error){
+		func(project string) ([]func() error, error) {
+			snapshots, err := pubsub.ListSnapshots(client, project)
+			tasks := []func() error{}
+			for _, snapshot := range snapshots {
+				tasks = append(tasks, func() error {
+					return pubsub.DeleteSnapshot(client, project, snapshot.Name)
+				})
+			}
+			return tasks, err
+		},
+		func(project string) ([]func() error, error) {
+			subscriptions, err := pubsub.ListSubscriptions(client, project)
+			tasks := []func() error{}
+			for _, subscription := range subscriptions {
+				tasks = append(tasks, func() error {
+					return pubsub.DeleteSubscription(client, project, subscription.Name)
+				})
+			}
+			return tasks, err
+		},
+		func(project string) ([]func() error, error) {
+			topics, err := pubsub.ListTopics(client, project)
+			tasks := []func() error{}
+			for _, topic := range topics {
+				tasks = append(tasks, func() error {
+					return pubsub.DeleteTopic(client, project, topic.Name)
+				})
+			}
+			return tasks, err
+		},
+		func(project string) ([]func() error, error) {
+			schemas, err := pubsub.ListSchemas(client, project)
+			tasks := []func() error{}
+			for _, schema := range schemas {
+				tasks = append(tasks, func() error {
+					return pubsub.DeleteSchema(client, project, strings.TrimPrefix(schema.Name, pubsub.GetResourceNameForSchema(project, "")))
+				})
+			}
+			return tasks, err
+		},
+	}
+
+	errs := []error{}
+	for _, phase := range phases {
+		tasks := []func() error{}
+		for _, project := range c.Projects {
+			projectTasks, err := phase(project.Name)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error tearing down project '%s': %w", project.Name, err))
+			}
+			tasks = append(tasks, projectTasks...)
+		}
+		errs = append(errs, utils.RunConcurrently(concurrency, tasks)...)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Teardown(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"snapshots":[{"name":"projects/first-project/snapshots/before"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"projects/first-project/subscriptions/products-subscription"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/first-project/topics/products"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusInternalServerError}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"name":"projects/first-project/schemas/products-schema"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+	config := Configuration{ProvisioningConcurrency: 1, Projects: []pubsub.Project{{Name: "first-project"}}}
+
+	err := config.Teardown(mockClient)
+	assert.EqualError(t, err, "error deleting topic: status code 500")
+
+	deleted := []string{}
+	for _, request := range mockClient.RequestHistory {
+		if request.Method == http.MethodDelete {
+			deleted = append(deleted, request.Path)
+		}
+	}
+	assert.Equal(t, []string{
+		"projects/first-project/snapshots/before",
+		"projects/first-project/subscriptions/products-subscription",
+		"projects/first-project/topics/products",
+		"projects/first-project/schemas/products-schema",
+	}, deleted)
+}
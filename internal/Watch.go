@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// ConfigurationWatcher polls the files a configuration was built from and,
+// when any of them changes, reloads the configuration and applies only the
+// differences to the emulator.
+type ConfigurationWatcher struct {
+	FileReader utils.FileReaderInterface
+	// Load builds the configuration again, applying any override (like the
+	// host) given when the helper was started.
+	Load     func() (Configuration, error)
+	Interval time.Duration
+	// OnApplied, when set, is called with every configuration applied.
+	OnApplied func(Configuration)
+}
+
+// fingerprints reads every file and returns a hash of its content. Files that
+// can't be read are kept with an empty hash, so they are detected as changed
+// once they become readable again.
+func (w *ConfigurationWatcher) fingerprints(files []string) map[string][32]byte {
+	hashes := map[string][32]byte{}
+	for _, file := range files {
+		content, err := w.FileReader.Read(file)
+		if err != nil {
+			Llog.Warn(fmt.Sprintf("Can't read watched file '%s': %v", file, err))
+			hashes[file] = [32]byte{}
+			continue
+		}
+		hashes[file] = sha256.Sum256(content)
+	}
+	return hashes
+}
+
+func fingerprintsChanged(previous, current map[string][32]byte) bool {
+	if len(previous) != len(current) {
+		return true
+	}
+	for file, hash := range current {
+		if previous[file] != hash {
+			return true
+		}
+	}
+	return false
+}
+
+// outstandingChanges drops the changes between applied and configuration
+// that a previous, partly failed, apply already made on the emulator: the
+// resources created or deleted since are left out and the recreated ones that
+// are missing are just created.
+func outstandingChanges(client utils.ClientInterface, applied, configuration Configuration, changes []Change) ([]Change, error) {
+	previousReport, err := DetectDrift(client, applied)
+	if err != nil {
+		return nil, err
+	}
+	currentReport, err := DetectDrift(client, configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	missingBefore := map[Change]bool{}
+	for _, change := range previousReport.Missing {
+		missingBefore[change] = true
+	}
+	missingNow := map[Change]bool{}
+	for _, change := range currentReport.Missing {
+		missingNow[change] = true
+	}
+
+	outstanding := []Change{}
+	for _, change := range changes {
+		create := Change{CHANGE_ACTION_CREATE, change.Kind, change.Project, change.Topic, change.Name}
+		switch change.Action {
+		case CHANGE_ACTION_CREATE:
+			if missingNow[create] {
+				outstanding = append(outstanding, change)
+			}
+		case CHANGE_ACTION_DELETE:
+			if !missingBefore[create] {
+				outstanding = append(outstanding, change)
+			}
+		case CHANGE_ACTION_RECREATE:
+			if missingNow[create] {
+				outstanding = append(outstanding, create)
+			} else {
+				outstanding = append(outstanding, change)
+			}
+		default:
+			outstanding = append(outstanding, change)
+		}
+	}
+	return outstanding, nil
+}
+
+// Watch blocks until the context is cancelled. The given configuration must
+// be the one already applied to the emulator. When some changes can't be
+// applied, they are tried again on every tick until they succeed.
+func (w *ConfigurationWatcher) Watch(ctx context.Context, client utils.ClientInterface, applied Configuration) error {
+	files := applied.ReferencedFiles()
+	hashes := w.fingerprints(files)
+	retry := false
+
+	Llog.Info(fmt.Sprintf("Watching %d file(s) for changes", len(files)))
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		currentHashes := w.fingerprints(files)
+		if !retry && !fingerprintsChanged(hashes, currentHashes) {
+			continue
+		}
+		hashes = currentHashes
+
+		configuration, err := w.Load()
+		if err != nil {
+			Llog.Error(fmt.Sprintf("Configuration changed but can't be loaded, keeping the previous one: %v", err))
+			continue
+		}
+
+		changes := DiffConfigurations(applied, configuration)
+		if retry {
+			// Part of the changes may have been applied by the failed attempt
+			if changes, err = outstandingChanges(client, applied, configuration, changes); err != nil {
+				Llog.Error(fmt.Sprintf("Can't check the emulator to retry the changes: %v", err))
+				continue
+			}
+			Llog.Info(fmt.Sprintf("Retrying %d change(s)", len(changes)))
+		} else {
+			Llog.Info(fmt.Sprintf("Configuration changed, %d change(s) to apply", len(changes)))
+		}
+
+		if err := configuration.ApplyChanges(client, changes); err != nil {
+			Llog.Error(fmt.Sprintf("Some changes couldn't be applied, retrying them: %v", err))
+			retry = true
+			continue
+		}
+		retry = false
+
+		applied = configuration
+		if w.OnApplied != nil {
+			w.OnApplied(applied)
+		}
+		files = applied.ReferencedFiles()
+		hashes = w.fingerprints(files)
+	}
+}
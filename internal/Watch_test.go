@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Watch_AppliesChangesWhenFileChanges(t *testing.T) {
+	var mutex sync.Mutex
+	content := `{"projects":[{"name":"test-project","topics":[]}]}`
+
+	mockReader := &utils.FileReaderMock{
+		ReadFunc: func(filePath string) ([]byte, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return []byte(content), nil
+		},
+	}
+
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	applied := Configuration{
+		FilePath: "config.json",
+		Projects: []pubsub.Project{{Name: "test-project"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher := ConfigurationWatcher{
+		FileReader: mockReader,
+		Load: func() (Configuration, error) {
+			return LoadConfigurationFromFile(mockReader, "config.json")
+		},
+		Interval: 5 * time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- watcher.Watch(ctx, mockClient, applied)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	mutex.Lock()
+	content = `{"projects":[{"name":"test-project","topics":[{"name":"new-topic"}]}]}`
+	mutex.Unlock()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/topics/new-topic", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Watch_RetriesFailedChanges(t *testing.T) {
+	var mutex sync.Mutex
+	content := `{"projects":[{"name":"test-project","topics":[]}]}`
+
+	mockReader := &utils.FileReaderMock{
+		ReadFunc: func(filePath string) ([]byte, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return []byte(content), nil
+		},
+	}
+
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			// First attempt, the second topic fails
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusInternalServerError}, Error: nil},
+			// Drift of the previous and the new configuration
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/first-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/first-topic"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[]}`)}, Error: nil},
+			// Retry, only the topic that failed
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	applied := Configuration{
+		FilePath: "config.json",
+		Projects: []pubsub.Project{{Name: "test-project"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher := ConfigurationWatcher{
+		FileReader: mockReader,
+		Load: func() (Configuration, error) {
+			return LoadConfigurationFromFile(mockReader, "config.json")
+		},
+		Interval: 5 * time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- watcher.Watch(ctx, mockClient, applied)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	mutex.Lock()
+	content = `{"projects":[{"name":"test-project","topics":[{"name":"first-topic"},{"name":"second-topic"}]}]}`
+	mutex.Unlock()
+	time.Sleep(80 * time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 7, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/topics/second-topic", mockClient.RequestHistory[1].Path)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[6].Method)
+	assert.Equal(t, "projects/test-project/topics/second-topic", mockClient.RequestHistory[6].Path)
+}
@@ -0,0 +1,369 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// Server exposes the functionality of the helper as a JSON API, so test
+// harnesses written in any language can drive the emulator over HTTP.
+type Server struct {
+	client utils.ClientInterface
+
+	// Held while the configuration is read or replaced, and during syncs, so
+	// only one sync runs at a time.
+	mutex         sync.Mutex
+	configuration internal.Configuration
+}
+
+func NewServer(client utils.ClientInterface, configuration internal.Configuration) *Server {
+	return &Server{client: client, configuration: configuration}
+}
+
+// Handler returns the handler of the API, under /api/.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/configuration", s.handleConfiguration)
+	mux.HandleFunc("POST /api/sync", s.handleSync)
+	mux.HandleFunc("POST /api/plan", s.handlePlan)
+	mux.HandleFunc("POST /api/purge", s.handlePurge)
+
+	mux.HandleFunc("GET /api/projects/{project}/topics", s.handleListTopics)
+	mux.HandleFunc("GET /api/projects/{project}/subscriptions", s.handleListSubscriptions)
+	mux.HandleFunc("GET /api/projects/{project}/schemas", s.handleListSchemas)
+	mux.HandleFunc("GET /api/projects/{project}/snapshots", s.handleListSnapshots)
+
+	mux.HandleFunc("POST /api/projects/{project}/topics/{topic}/publish", s.handlePublish)
+	mux.HandleFunc("POST /api/projects/{project}/subscriptions/{subscription}/pull", s.handlePull)
+	mux.HandleFunc("POST /api/projects/{project}/subscriptions/{subscription}/purge", s.handlePurgeSubscription)
+	mux.HandleFunc("POST /api/projects/{project}/subscriptions/{subscription}/seek", s.handleSeek)
+	mux.HandleFunc("POST /api/projects/{project}/snapshots", s.handleCreateSnapshot)
+	mux.HandleFunc("DELETE /api/projects/{project}/snapshots/{snapshot}", s.handleDeleteSnapshot)
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		Llog.Warn(fmt.Sprintf("Can't write the response: %s", err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) currentConfiguration() internal.Configuration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.configuration
+}
+
+// requestConfiguration returns the configuration in the body of the request,
+// or the current one when the body is empty. Files referenced by the
+// configuration are read relative to the working directory of the server.
+// The client of the server only talks to its emulator, so the host of the
+// configuration can't differ from the current one.
+func (s *Server) requestConfiguration(r *http.Request) (internal.Configuration, bool, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return internal.Configuration{}, false, err
+	}
+	if len(body) == 0 {
+		return s.configuration, false, nil
+	}
+
+	configuration, err := internal.ParseConfiguration(&utils.FileReader{}, body, "")
+	if err != nil {
+		return internal.Configuration{}, false, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// The host is filled in by default when missing
+	var fields struct {
+		Host *string `json:"host"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return internal.Configuration{}, false, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if fields.Host != nil && configuration.Host != s.configuration.Host {
+		return internal.Configuration{}, false, fmt.Errorf("invalid configuration: the host '%s' differs from the one of the emulator served, '%s'", configuration.Host, s.configuration.Host)
+	}
+	configuration.Host = s.configuration.Host
+
+	return configuration, true, nil
+}
+
+func (s *Server) handleConfiguration(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.currentConfiguration())
+}
+
+// handleSync syncs the configuration of the body, which replaces the current
+// one once synced, or the current one when the body is empty. Like Sync, the
+// resources are deleted and created again unless reconcile=true, which keeps
+// the existing ones.
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	configuration, replaced, err := s.requestConfiguration(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reconcile, _ := strconv.ParseBool(r.URL.Query().Get("reconcile"))
+	if reconcile {
+		err = configuration.Reconcile(s.client)
+	} else {
+		err = configuration.Sync(s.client)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if replaced {
+		s.configuration = configuration
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "synced"})
+}
+
+// handlePlan returns the changes a reconcile of the configuration of the
+// body, or of the current one, would make. It describes a sync with
+// reconcile=true, a sync without it recreates everything.
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	configuration, _, err := s.requestConfiguration(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	changes, err := configuration.Plan(s.client)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]internal.Change{"changes": changes})
+}
+
+func (s *Server) purge(w http.ResponseWriter, r *http.Request, targets []internal.PurgeTarget) {
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = internal.PURGE_METHOD_AUTO
+	}
+
+	if err := s.currentConfiguration().Purge(s.client, targets, method); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"purged": len(targets)})
+}
+
+// handlePurge purges every subscription of the configuration.
+func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	s.purge(w, r, s.currentConfiguration().PurgeTargets())
+}
+
+func (s *Server) handlePurgeSubscription(w http.ResponseWriter, r *http.Request) {
+	s.purge(w, r, []internal.PurgeTarget{{Project: r.PathValue("project"), Subscription: r.PathValue("subscription")}})
+}
+
+func (s *Server) handleListTopics(w http.ResponseWriter, r *http.Request) {
+	topics, err := pubsub.ListTopics(s.client, r.PathValue("project"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if topics == nil {
+		topics = []pubsub.Topic{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]pubsub.Topic{"topics": topics})
+}
+
+func (s *Server) handleListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := pubsub.ListSubscriptions(s.client, r.PathValue("project"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []pubsub.Subscription{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]pubsub.Subscription{"subscriptions": subscriptions})
+}
+
+func (s *Server) handleListSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := pubsub.ListSchemas(s.client, r.PathValue("project"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if schemas == nil {
+		schemas = []pubsub.Schema{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]pubsub.Schema{"schemas": schemas})
+}
+
+func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := pubsub.ListSnapshots(s.client, r.PathValue("project"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]pubsub.Snapshot{"snapshots": snapshots})
+}
+
+// handlePublish publishes the messages of the body, in the format of the
+// messages of the configuration (a message, a JSON array or JSON Lines),
+// encoded with the schema of the topic when it's in the configuration.
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	configuration := s.currentConfiguration()
+	messages, err := internal.ParseMessages(body)
+	if err == nil {
+		messages, err = configuration.ExpandMessages(messages)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid messages: %w", err))
+		return
+	}
+	if len(messages) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no messages to publish"))
+		return
+	}
+
+	messageIds, err := configuration.PublishMessages(s.client, r.PathValue("project"), r.PathValue("topic"), messages)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"messageIds": messageIds})
+}
+
+// handlePull returns up to "max" (10) messages of a subscription with their
+// data decoded. They are made available again right away unless "ack" is
+// true.
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	project, subscription := r.PathValue("project"), r.PathValue("subscription")
+	query := r.URL.Query()
+
+	maxMessages := 10
+	if query.Has("max") {
+		var err error
+		if maxMessages, err = strconv.Atoi(query.Get("max")); err != nil || maxMessages < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max '%s'", query.Get("max")))
+			return
+		}
+	}
+	ack, _ := strconv.ParseBool(query.Get("ack"))
+
+	messages, err := s.currentConfiguration().PullMessages(s.client, project, subscription, maxMessages, ack)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]internal.DecodedMessage{"messages": messages})
+}
+
+// handleSeek seeks a subscription to the snapshot ({"snapshot": name}) or to
+// the time ({"time": RFC 3339}) of the body.
+func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Snapshot string `json:"snapshot"`
+		Time     string `json:"time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if (body.Snapshot == "") == (body.Time == "") {
+		writeError(w, http.StatusBadRequest, errors.New("either snapshot or time is required"))
+		return
+	}
+
+	project := r.PathValue("project")
+	subscriptionResourceName := pubsub.GetResourceNameForSubscription(project, r.PathValue("subscription"))
+
+	var err error
+	if body.Snapshot != "" {
+		err = pubsub.SeekToSnapshot(s.client, project, subscriptionResourceName, pubsub.GetResourceNameForSnapshot(project, body.Snapshot))
+	} else {
+		seekTime, parseErr := time.Parse(time.RFC3339Nano, body.Time)
+		if parseErr != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid time '%s': %w", body.Time, parseErr))
+			return
+		}
+		err = pubsub.Seek(s.client, project, subscriptionResourceName, seekTime)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "sought"})
+}
+
+// handleCreateSnapshot creates the snapshot of the body ({"name": name,
+// "subscription": name, "labels": {}}).
+func (s *Server) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name         string        `json:"name"`
+		Subscription string        `json:"subscription"`
+		Labels       pubsub.Labels `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Name == "" || body.Subscription == "" {
+		writeError(w, http.StatusBadRequest, errors.New("name and subscription are required"))
+		return
+	}
+
+	project := r.PathValue("project")
+	snapshot, err := pubsub.CreateSnapshot(
+		s.client,
+		project,
+		pubsub.GetResourceNameForSnapshot(project, body.Name),
+		pubsub.GetResourceNameForSubscription(project, body.Subscription),
+		&body.Labels,
+	)
+	switch {
+	case errors.Is(err, pubsub.ErrSnapshotAlreadyExists):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err)
+	default:
+		writeJSON(w, http.StatusOK, snapshot)
+	}
+}
+
+func (s *Server) handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+	if err := pubsub.DeleteSnapshot(s.client, project, pubsub.GetResourceNameForSnapshot(project, r.PathValue("snapshot"))); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
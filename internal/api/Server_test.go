@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(server *Server, method, target, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func testConfiguration() internal.Configuration {
+	return internal.Configuration{
+		AvoidStartupCheck:       true,
+		ProvisioningConcurrency: 1,
+		Projects: []pubsub.Project{{
+			Name: "test-project",
+			Topics: []pubsub.Topic{{
+				Name:          "test-topic",
+				Subscriptions: []pubsub.Subscription{{Name: "test-subscription"}},
+			}},
+		}},
+	}
+}
+
+func Test_Api_Plan(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/test-project/topics/test-topic"}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			// The existing topic is up to date
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic"}`)}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/plan", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"changes":[{"action":"CREATE","kind":"SUBSCRIPTION","project":"test-project","topic":"test-topic","name":"test-subscription"}]}`, recorder.Body.String())
+}
+
+func Test_Api_SyncReplacesConfiguration(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			// Reconcile: listing topics and subscriptions, then creating the topic
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/sync?reconcile=true", `{"avoidStartupCheck":true,"projects":[{"name":"other-project","topics":[{"name":"other-topic"}]}]}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, 3, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[2].Method)
+	assert.Equal(t, "projects/other-project/topics/other-topic", mockClient.RequestHistory[2].Path)
+
+	recorder = serve(server, http.MethodGet, "/api/configuration", "")
+	assert.Contains(t, recorder.Body.String(), `"other-project"`)
+}
+
+func Test_Api_SyncInvalidConfiguration(t *testing.T) {
+	server := NewServer(&utils.MockClient{}, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/sync", `{"projects":`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid configuration")
+}
+
+func Test_Api_SyncFailureKeepsConfiguration(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusInternalServerError}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/sync?reconcile=true", `{"avoidStartupCheck":true,"projects":[{"name":"other-project","topics":[{"name":"other-topic"}]}]}`)
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+
+	recorder = serve(server, http.MethodGet, "/api/configuration", "")
+	assert.Contains(t, recorder.Body.String(), `"test-project"`)
+	assert.NotContains(t, recorder.Body.String(), `"other-project"`)
+}
+
+func Test_Api_SyncRejectsAnotherHost(t *testing.T) {
+	configuration := testConfiguration()
+	configuration.Host = "localhost:8085"
+	server := NewServer(&utils.MockClient{}, configuration)
+
+	recorder := serve(server, http.MethodPost, "/api/sync", `{"host":"localhost:9999","projects":[{"name":"test-project"}]}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "the host 'localhost:9999' differs from the one of the emulator served, 'localhost:8085'")
+
+	recorder = serve(server, http.MethodPost, "/api/plan", `{"host":"localhost:9999","projects":[{"name":"test-project"}]}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func Test_Api_ListSubscriptions(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"projects/test-project/subscriptions/test-subscription"}]}`)}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodGet, "/api/projects/test-project/subscriptions", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"subscriptions":[{"name":"projects/test-project/subscriptions/test-subscription","labels":null}]}`, recorder.Body.String())
+}
+
+func Test_Api_PublishAndPull(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1","2"]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"eyJpZCI6MX0=","messageId":"1"}}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/projects/test-project/topics/test-topic/publish", `[{"data":{"id":1}},{"data":"text"}]`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"messageIds":["1","2"]}`, recorder.Body.String())
+
+	recorder = serve(server, http.MethodPost, "/api/projects/test-project/subscriptions/test-subscription/pull?max=5&ack=true", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"messages":[{"messageId":"1","data":{"id":1}}]}`, recorder.Body.String())
+	assert.JSONEq(t, `{"returnImmediately":true,"maxMessages":5}`, string(mockClient.RequestHistory[1].Body))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:acknowledge", mockClient.RequestHistory[2].Path)
+}
+
+func Test_Api_PullInvalidMax(t *testing.T) {
+	server := NewServer(&utils.MockClient{}, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/projects/test-project/subscriptions/test-subscription/pull?max=none", "")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func Test_Api_PurgeSubscription(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/projects/test-project/subscriptions/test-subscription/purge?method=seek", "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"purged":1}`, recorder.Body.String())
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:seek", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Api_SnapshotAndSeek(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/snapshots/before","topic":"projects/test-project/topics/test-topic"}`)}},
+			{Response: utils.Response{StatusCode: http.StatusConflict}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+		},
+	}
+	server := NewServer(mockClient, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/projects/test-project/snapshots", `{"name":"before","subscription":"test-subscription"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "projects/test-project/snapshots/before", mockClient.RequestHistory[0].Path)
+
+	recorder = serve(server, http.MethodPost, "/api/projects/test-project/snapshots", `{"name":"before","subscription":"test-subscription"}`)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = serve(server, http.MethodPost, "/api/projects/test-project/subscriptions/test-subscription/seek", `{"snapshot":"before"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"snapshot":"projects/test-project/snapshots/before"}`, string(mockClient.RequestHistory[2].Body))
+
+	recorder = serve(server, http.MethodDelete, "/api/projects/test-project/snapshots/before", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[3].Method)
+}
+
+func Test_Api_SeekRequiresTarget(t *testing.T) {
+	server := NewServer(&utils.MockClient{}, testConfiguration())
+
+	recorder := serve(server, http.MethodPost, "/api/projects/test-project/subscriptions/test-subscription/seek", `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "either snapshot or time is required")
+}
@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+)
+
+// Entry is a message of a capture file, a line of JSON with the topic it was
+// published to and its payload exactly as published.
+type Entry struct {
+	Project     string `json:"project"`
+	Topic       string `json:"topic"`
+	MessageId   string `json:"messageId,omitempty"`
+	PublishTime string `json:"publishTime,omitempty"`
+
+	pubsub.Message
+}
+
+// NewEntry returns the entry of a message pulled from a subscription of the
+// topic with the given resource name.
+func NewEntry(topicResourceName string, message pubsub.PubsubMessage) Entry {
+	project, topic := SplitTopicResourceName(topicResourceName)
+
+	entry := Entry{
+		Project:     project,
+		Topic:       topic,
+		MessageId:   message.MessageId,
+		PublishTime: message.PublishTime,
+		Message:     pubsub.NewMessage(message.Data),
+	}
+	entry.Attributes = message.Attributes
+	entry.OrderingKey = message.OrderingKey
+	return entry
+}
+
+// publishTime returns the time the message was published, zero if unknown.
+func (e Entry) publishTime() time.Time {
+	publishTime, err := time.Parse(time.RFC3339Nano, e.PublishTime)
+	if err != nil {
+		return time.Time{}
+	}
+	return publishTime
+}
+
+// ReadEntries parses the lines of a capture file.
+func ReadEntries(content []byte) ([]Entry, error) {
+	entries := []Entry{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if entry.Topic == "" {
+			return nil, fmt.Errorf("line %d: the topic is missing", line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// SplitTopicResourceName returns the project and the name of a topic from its
+// resource name, projects/{project}/topics/{topic}.
+func SplitTopicResourceName(topicResourceName string) (string, string) {
+	parts := strings.Split(topicResourceName, "/")
+	if len(parts) == 4 && parts[0] == "projects" && parts[2] == "topics" {
+		return parts[1], parts[3]
+	}
+	return "", topicResourceName
+}
@@ -0,0 +1,136 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Capture_NewEntry(t *testing.T) {
+	entry := NewEntry("projects/test-project/topics/test-topic", pubsub.PubsubMessage{
+		Data:        []byte(`{"a":1}`),
+		Attributes:  map[string]string{"origin": "test"},
+		MessageId:   "1",
+		PublishTime: "2025-03-03T10:00:00Z",
+		OrderingKey: "key",
+	})
+
+	raw, err := json.Marshal(entry)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"project":"test-project","topic":"test-topic","messageId":"1","publishTime":"2025-03-03T10:00:00Z","data":{"a":1},"attributes":{"origin":"test"},"orderingKey":"key"}`, string(raw))
+
+	for _, payload := range [][]byte{[]byte(`{"a":1}`), []byte(`{ "a": 1 }`), []byte(`"quoted"`), []byte("text"), {0x08, 'S'}, {}} {
+		entries, err := ReadEntries(append(mustMarshal(t, NewEntry("test-topic", pubsub.PubsubMessage{Data: payload})), '\n'))
+		assert.NoError(t, err)
+		replayed, err := entries[0].Payload()
+		assert.NoError(t, err)
+		assert.Equal(t, payload, replayed)
+	}
+}
+
+func mustMarshal(t *testing.T, entry Entry) []byte {
+	raw, err := json.Marshal(entry)
+	assert.NoError(t, err)
+	return raw
+}
+
+func Test_Capture_ReadEntries(t *testing.T) {
+	entries, err := ReadEntries([]byte("{\"topic\":\"a\",\"data\":\"x\"}\n\n{\"topic\":\"b\",\"dataBase64\":\"AAE=\"}\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "b", entries[1].Topic)
+
+	_, err = ReadEntries([]byte("{\"topic\":\"a\"}\n{\"data\":\"x\"}"))
+	assert.EqualError(t, err, "line 2: the topic is missing")
+}
+
+func Test_Capture_Record(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"aGVsbG8=","messageId":"1","publishTime":"2025-03-03T10:00:00Z"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	// Stops once nothing else is received
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var output bytes.Buffer
+	recorded, err := Record(ctx, mockClient, "test-project", []string{"projects/test-project/topics/test-topic"}, &output, RecordOptions{Suffix: "recording"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recorded)
+	assert.JSONEq(t, `{"project":"test-project","topic":"test-topic","messageId":"1","publishTime":"2025-03-03T10:00:00Z","data":"hello"}`, output.String())
+
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/subscriptions/test-topic-recording", mockClient.RequestHistory[0].Path)
+	assert.Equal(t, "projects/test-project/subscriptions/test-topic-recording:pull", mockClient.RequestHistory[1].Path)
+	assert.Equal(t, "projects/test-project/subscriptions/test-topic-recording:acknowledge", mockClient.RequestHistory[2].Path)
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[3].Method)
+}
+
+func Test_Capture_Replay(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["2"]}`)}, Error: nil},
+		},
+	}
+
+	entries, err := ReadEntries([]byte(
+		`{"project":"recorded","topic":"orders","publishTime":"2025-03-03T10:00:00Z","data":"first","orderingKey":"k"}` + "\n" +
+			`{"project":"recorded","topic":"payments","publishTime":"2025-03-03T10:00:00.2Z","data":"second"}`,
+	))
+	assert.NoError(t, err)
+
+	start := time.Now()
+	replayed, err := Replay(context.Background(), mockClient, entries, ReplayOptions{
+		TopicMap:       map[string]string{"orders": "orders-copy"},
+		PreserveTiming: true,
+		Speed:          2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	assert.Equal(t, "projects/recorded/topics/orders-copy:publish", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"messages":[{"data":"Zmlyc3Q=","orderingKey":"k"}]}`, string(mockClient.RequestHistory[0].Body))
+	assert.Equal(t, "projects/recorded/topics/payments:publish", mockClient.RequestHistory[1].Path)
+}
+
+func Test_Capture_Replay_InPublishOrder(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["2"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["3"]}`)}, Error: nil},
+		},
+	}
+
+	entries, err := ReadEntries([]byte(
+		`{"project":"recorded","topic":"orders","publishTime":"2025-03-03T10:00:00.1Z","data":"third"}` + "\n" +
+			`{"project":"recorded","topic":"orders","publishTime":"2025-03-03T10:00:00Z","data":"first"}` + "\n" +
+			`{"project":"recorded","topic":"orders","publishTime":"2025-03-03T10:00:00Z","data":"second"}`,
+	))
+	assert.NoError(t, err)
+
+	start := time.Now()
+	replayed, err := Replay(context.Background(), mockClient, entries, ReplayOptions{PreserveTiming: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	assert.JSONEq(t, `{"messages":[{"data":"Zmlyc3Q="}]}`, string(mockClient.RequestHistory[0].Body))
+	assert.JSONEq(t, `{"messages":[{"data":"c2Vjb25k"}]}`, string(mockClient.RequestHistory[1].Body))
+	assert.JSONEq(t, `{"messages":[{"data":"dGhpcmQ="}]}`, string(mockClient.RequestHistory[2].Body))
+	assert.Equal(t, `"third"`, string(entries[0].Data))
+}
@@ -0,0 +1,107 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+type RecordOptions struct {
+	// Maximum number of messages pulled at once from every subscription.
+	MaxMessages int
+	// Time between pulls when no message was received.
+	Interval time.Duration
+	// Suffix of the recording subscriptions, unique per recording.
+	Suffix string
+}
+
+// recordingSubscription is a subscription created to record the messages of
+// a topic without taking them from the subscriptions of the application.
+type recordingSubscription struct {
+	topicResourceName        string
+	subscriptionResourceName string
+}
+
+// Record creates a recording subscription in the project for every topic and
+// writes the messages published to them as entries, until the context is
+// done. The recording subscriptions are deleted before returning the number
+// of messages recorded.
+func Record(ctx context.Context, client utils.ClientInterface, project string, topicResourceNames []string, output io.Writer, options RecordOptions) (int, error) {
+	if options.MaxMessages < 1 {
+		options.MaxMessages = 100
+	}
+	if options.Suffix == "" {
+		options.Suffix = fmt.Sprintf("recording-%d", time.Now().Unix())
+	}
+
+	subscriptions := []recordingSubscription{}
+	defer func() {
+		for _, subscription := range subscriptions {
+			if err := pubsub.DeleteSubscription(client, project, subscription.subscriptionResourceName); err != nil {
+				Llog.Warn(fmt.Sprintf("Can't delete the recording subscription '%s': %s", subscription.subscriptionResourceName, err))
+			}
+		}
+	}()
+
+	for _, topicResourceName := range topicResourceNames {
+		_, topic := SplitTopicResourceName(topicResourceName)
+		subscription := recordingSubscription{
+			topicResourceName:        topicResourceName,
+			subscriptionResourceName: pubsub.GetResourceNameForSubscription(project, fmt.Sprintf("%s-%s", topic, options.Suffix)),
+		}
+		if err := pubsub.CreateSubscription(client, project, subscription.subscriptionResourceName, topicResourceName, &pubsub.Labels{"purpose": "recording"}, nil, nil); err != nil {
+			return 0, fmt.Errorf("topic '%s': %w", topicResourceName, err)
+		}
+		subscriptions = append(subscriptions, subscription)
+		Llog.Info(fmt.Sprintf("Recording topic '%s' with subscription '%s'", topicResourceName, subscription.subscriptionResourceName))
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+
+	recorded := 0
+	for {
+		received := 0
+		for _, subscription := range subscriptions {
+			messages, err := pubsub.Pull(client, project, subscription.subscriptionResourceName, options.MaxMessages)
+			if err != nil {
+				return recorded, err
+			}
+			if len(messages) == 0 {
+				continue
+			}
+
+			ackIds := []string{}
+			for _, message := range messages {
+				if err := encoder.Encode(NewEntry(subscription.topicResourceName, message.Message)); err != nil {
+					return recorded, err
+				}
+				ackIds = append(ackIds, message.AckId)
+			}
+			if err := pubsub.Acknowledge(client, project, subscription.subscriptionResourceName, ackIds); err != nil {
+				return recorded, err
+			}
+			received += len(messages)
+		}
+		recorded += received
+
+		if received > 0 {
+			if ctx.Err() != nil {
+				return recorded, nil
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return recorded, nil
+		case <-time.After(options.Interval):
+		}
+	}
+}
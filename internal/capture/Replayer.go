@@ -0,0 +1,70 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+type ReplayOptions struct {
+	// Project the messages are published to, the recorded one when empty.
+	Project string
+	// Topic each recorded topic is published to, the same one when missing.
+	TopicMap map[string]string
+	// Waits between the messages the time between their original publish
+	// times, divided by Speed (1 when 0).
+	PreserveTiming bool
+	Speed          float64
+}
+
+// Replay publishes the entries in the order of their publish times (keeping
+// the order of the file for the same time), returning the number of messages
+// published. It stops at the first error or when the context is done.
+func Replay(ctx context.Context, client utils.ClientInterface, entries []Entry, options ReplayOptions) (int, error) {
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+
+	// Captures of several subscriptions are not in publish order
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return a.publishTime().Compare(b.publishTime())
+	})
+
+	var previous time.Time
+	for i, entry := range entries {
+		if options.PreserveTiming {
+			publishTime := entry.publishTime()
+			if !previous.IsZero() && publishTime.After(previous) {
+				select {
+				case <-ctx.Done():
+					return i, ctx.Err()
+				case <-time.After(time.Duration(float64(publishTime.Sub(previous)) / options.Speed)):
+				}
+			}
+			if !publishTime.IsZero() {
+				previous = publishTime
+			}
+		}
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+
+		project, topic := entry.Project, entry.Topic
+		if options.Project != "" {
+			project = options.Project
+		}
+		if mapped, exists := options.TopicMap[topic]; exists {
+			topic = mapped
+		}
+
+		if _, err := pubsub.Publish(client, project, pubsub.GetResourceNameForTopic(project, topic), []pubsub.Message{entry.Message}); err != nil {
+			return i, fmt.Errorf("message %d (%s): %w", i, entry.MessageId, err)
+		}
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,109 @@
+package dump
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// DecodeFunc decodes the data of a message pulled from the subscription.
+type DecodeFunc func(message pubsub.PubsubMessage) internal.DecodedMessage
+
+type Options struct {
+	// Acknowledges the messages dumped (drain). Otherwise, they are made
+	// available again once dumped (peek).
+	Ack bool
+	// Maximum number of messages dumped, every message when 0.
+	MaxMessages int
+	// Maximum number of messages pulled at once.
+	BatchSize int
+}
+
+// Peeked messages are held with the longest ack deadline, extended again
+// for every held message when the dump takes longer than the refresh time.
+const (
+	peekAckDeadlineSeconds = 600
+	peekRefreshInterval    = 5 * time.Minute
+)
+
+// Dump writes the messages of a subscription until it has no more messages,
+// returning the number of messages dumped.
+//
+// When peeking, the messages are held until the end, so they are not
+// delivered again in the same dump, and then their ack deadline is set to 0.
+// Messages delivered again anyway are skipped.
+func Dump(client utils.ClientInterface, project, subscriptionResourceName string, options Options, decode DecodeFunc, writer Writer) (int, error) {
+	if options.BatchSize < 1 {
+		options.BatchSize = 100
+	}
+
+	dumped := 0
+	seen := map[string]bool{}
+	peekedAckIds := []string{}
+	heldAt := time.Now()
+
+	release := func() error {
+		if options.Ack || len(peekedAckIds) == 0 {
+			return nil
+		}
+		if err := pubsub.ModifyAckDeadline(client, project, subscriptionResourceName, peekedAckIds, 0); err != nil {
+			return fmt.Errorf("can't make the peeked messages available again: %w", err)
+		}
+		return nil
+	}
+
+	for options.MaxMessages == 0 || dumped < options.MaxMessages {
+		batchSize := options.BatchSize
+		if options.MaxMessages > 0 && options.MaxMessages-dumped < batchSize {
+			batchSize = options.MaxMessages - dumped
+		}
+
+		received, err := pubsub.Pull(client, project, subscriptionResourceName, batchSize)
+		if err != nil {
+			release()
+			return dumped, err
+		}
+
+		if len(received) == 0 {
+			break
+		}
+
+		ackIds := []string{}
+		for _, receivedMessage := range received {
+			ackIds = append(ackIds, receivedMessage.AckId)
+			if seen[receivedMessage.Message.MessageId] {
+				continue
+			}
+			seen[receivedMessage.Message.MessageId] = true
+
+			if err := writer.Write(decode(receivedMessage.Message)); err != nil {
+				release()
+				return dumped, err
+			}
+			dumped++
+		}
+
+		if options.Ack {
+			if err := pubsub.Acknowledge(client, project, subscriptionResourceName, ackIds); err != nil {
+				return dumped, err
+			}
+			continue
+		}
+
+		// Held until the end, otherwise they would be pulled again
+		peekedAckIds = append(peekedAckIds, ackIds...)
+		held := ackIds
+		if time.Since(heldAt) > peekRefreshInterval {
+			held, heldAt = peekedAckIds, time.Now()
+		}
+		if err := pubsub.ModifyAckDeadline(client, project, subscriptionResourceName, held, peekAckDeadlineSeconds); err != nil {
+			release()
+			return dumped, fmt.Errorf("can't hold the peeked messages: %w", err)
+		}
+	}
+
+	return dumped, release()
+}
@@ -0,0 +1,127 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeAsIs(message pubsub.PubsubMessage) internal.DecodedMessage {
+	return internal.Configuration{}.DecodeReceivedMessage("", "", message)
+}
+
+const twoMessagesPull = `{"receivedMessages":[` +
+	`{"ackId":"a1","message":{"data":"eyJhIjoxfQ==","messageId":"1","publishTime":"2025-03-03T10:00:00Z","attributes":{"origin":"test"}}},` +
+	`{"ackId":"a2","message":{"data":"aGVsbG8=","messageId":"2","publishTime":"2025-03-03T10:00:01Z"}}]}`
+
+func Test_Dump_Drain(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(twoMessagesPull)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	var output bytes.Buffer
+	dumped, err := Dump(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", Options{Ack: true}, decodeAsIs, NewJSONLinesWriter(&output))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, dumped)
+	assert.Equal(t, `{"messageId":"1","publishTime":"2025-03-03T10:00:00Z","data":{"a":1},"attributes":{"origin":"test"}}`+"\n"+
+		`{"messageId":"2","publishTime":"2025-03-03T10:00:01Z","data":"hello"}`+"\n", output.String())
+
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:acknowledge", mockClient.RequestHistory[1].Path)
+	assert.JSONEq(t, `{"ackIds":["a1","a2"]}`, string(mockClient.RequestHistory[1].Body))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:pull", mockClient.RequestHistory[2].Path)
+
+	// The output can be loaded as a messagesFile
+	messages, err := internal.ParseMessages(output.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, json.RawMessage(`{"a":1}`), messages[0].Data)
+}
+
+func Test_Dump_Peek(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(twoMessagesPull)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			// Delivered again anyway, the dump goes on
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a3","message":{"data":"aGVsbG8=","messageId":"2"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a4","message":{"data":"d29ybGQ=","messageId":"3"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	var output bytes.Buffer
+	writer := NewCSVWriter(&output)
+	dumped, err := Dump(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", Options{}, decodeAsIs, writer)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, 3, dumped)
+	assert.Equal(t, "messageId,publishTime,orderingKey,attributes,data,dataBase64\n"+
+		`1,2025-03-03T10:00:00Z,,"{""origin"":""test""}","{""a"":1}",`+"\n"+
+		"2,2025-03-03T10:00:01Z,,,hello,\n"+
+		"3,,,,world,\n", output.String())
+
+	assert.Equal(t, 8, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:modifyAckDeadline", mockClient.RequestHistory[1].Path)
+	assert.JSONEq(t, `{"ackIds":["a1","a2"],"ackDeadlineSeconds":600}`, string(mockClient.RequestHistory[1].Body))
+	assert.JSONEq(t, `{"ackIds":["a3"],"ackDeadlineSeconds":600}`, string(mockClient.RequestHistory[3].Body))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:pull", mockClient.RequestHistory[6].Path)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:modifyAckDeadline", mockClient.RequestHistory[7].Path)
+	assert.JSONEq(t, `{"ackIds":["a1","a2","a3","a4"],"ackDeadlineSeconds":0}`, string(mockClient.RequestHistory[7].Body))
+}
+
+func Test_Dump_MaxMessages(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"AAE=","messageId":"1"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	directory := t.TempDir()
+	writer, err := NewWriter(FORMAT_FILES, nil, directory)
+	assert.NoError(t, err)
+
+	dumped, err := Dump(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", Options{Ack: true, MaxMessages: 1}, decodeAsIs, writer)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dumped)
+	assert.JSONEq(t, `{"returnImmediately":true,"maxMessages":1}`, string(mockClient.RequestHistory[0].Body))
+	assert.Equal(t, 2, len(mockClient.RequestHistory))
+
+	content, err := os.ReadFile(filepath.Join(directory, "000001-1.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x01}, content)
+}
+
+func Test_Dump_FilesWriter(t *testing.T) {
+	directory := t.TempDir()
+	writer, err := NewFilesWriter(directory)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Write(internal.DecodedMessage{MessageId: "a/b", Data: json.RawMessage(`{"a":1}`)}))
+	assert.NoError(t, writer.Write(internal.DecodedMessage{Data: json.RawMessage(`"hello"`)}))
+
+	content, err := os.ReadFile(filepath.Join(directory, "000001-a_b.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 1\n}\n", string(content))
+
+	content, err = os.ReadFile(filepath.Join(directory, "000002.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	_, err = NewWriter("xml", nil, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+)
+
+const (
+	FORMAT_JSONL = "jsonl"
+	FORMAT_CSV   = "csv"
+	FORMAT_FILES = "files"
+)
+
+// Writer writes the messages dumped in a format.
+type Writer interface {
+	Write(message internal.DecodedMessage) error
+	Close() error
+}
+
+// NewWriter returns the writer of a format. JSON Lines and CSV are written
+// to output, while files are written to the output directory.
+func NewWriter(format string, output io.Writer, outputDirectory string) (Writer, error) {
+	switch format {
+	case FORMAT_JSONL:
+		return NewJSONLinesWriter(output), nil
+	case FORMAT_CSV:
+		return NewCSVWriter(output), nil
+	case FORMAT_FILES:
+		return NewFilesWriter(outputDirectory)
+	default:
+		return nil, fmt.Errorf("unknown format '%s', use %s, %s or %s", format, FORMAT_JSONL, FORMAT_CSV, FORMAT_FILES)
+	}
+}
+
+// JSONLinesWriter writes a message per line, in the format of the messages
+// of the configuration, so the output can be used as a messagesFile.
+type JSONLinesWriter struct {
+	encoder *json.Encoder
+}
+
+func NewJSONLinesWriter(output io.Writer) *JSONLinesWriter {
+	encoder := json.NewEncoder(output)
+	encoder.SetEscapeHTML(false)
+	return &JSONLinesWriter{encoder: encoder}
+}
+
+func (w *JSONLinesWriter) Write(message internal.DecodedMessage) error {
+	return w.encoder.Encode(message)
+}
+
+func (w *JSONLinesWriter) Close() error {
+	return nil
+}
+
+// CSVWriter writes a message per row, with the attributes as a JSON object
+// and the text data without quotes.
+type CSVWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func NewCSVWriter(output io.Writer) *CSVWriter {
+	return &CSVWriter{writer: csv.NewWriter(output)}
+}
+
+func (w *CSVWriter) Write(message internal.DecodedMessage) error {
+	if !w.headerWritten {
+		if err := w.writer.Write([]string{"messageId", "publishTime", "orderingKey", "attributes", "data", "dataBase64"}); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	attributes := ""
+	if len(message.Attributes) > 0 {
+		raw, err := json.Marshal(message.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = string(raw)
+	}
+
+	data := string(message.Data)
+	var text string
+	if json.Unmarshal(message.Data, &text) == nil {
+		data = text
+	}
+
+	return w.writer.Write([]string{message.MessageId, message.PublishTime, message.OrderingKey, attributes, data, message.DataBase64})
+}
+
+func (w *CSVWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// FilesWriter writes the data of every message to its own file: indented
+// JSON to .json files, text to .txt files and anything else to .bin files.
+// The files can be validated with schema validate-message.
+type FilesWriter struct {
+	directory string
+	written   int
+}
+
+func NewFilesWriter(directory string) (*FilesWriter, error) {
+	if directory == "" {
+		return nil, fmt.Errorf("an output directory is required by the %s format", FORMAT_FILES)
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+	return &FilesWriter{directory: directory}, nil
+}
+
+var unsafeFileNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func (w *FilesWriter) Write(message internal.DecodedMessage) error {
+	var (
+		extension string
+		content   []byte
+		text      string
+	)
+	switch {
+	case message.DataBase64 != "":
+		extension = "bin"
+		decoded, err := base64.StdEncoding.DecodeString(message.DataBase64)
+		if err != nil {
+			return err
+		}
+		content = decoded
+	case json.Unmarshal(message.Data, &text) == nil:
+		extension = "txt"
+		content = []byte(text)
+	default:
+		extension = "json"
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, message.Data, "", "  "); err != nil {
+			return err
+		}
+		content = append(indented.Bytes(), '\n')
+	}
+
+	w.written++
+	name := fmt.Sprintf("%06d", w.written)
+	if message.MessageId != "" {
+		name += "-" + unsafeFileNameCharacters.ReplaceAllString(message.MessageId, "_")
+	}
+	return os.WriteFile(filepath.Join(w.directory, name+"."+extension), content, 0644)
+}
+
+func (w *FilesWriter) Close() error {
+	return nil
+}
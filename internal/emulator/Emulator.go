@@ -0,0 +1,229 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/readiness"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+const (
+	defaultHost        = "localhost"
+	defaultStopTimeout = 10 * time.Second
+)
+
+type Options struct {
+	// Path of the emulator: an executable (like the cloud-pubsub-emulator
+	// script of the gcloud component) or a jar run with java. The emulator of
+	// gcloud is started when empty.
+	Path string
+	// Args are added to the command starting the emulator.
+	Args []string
+	// Host the emulator listens on, localhost when empty.
+	Host string
+	// Port the emulator listens on, a free one when zero.
+	Port int
+	// Readiness waits until the emulator answers once started.
+	Readiness readiness.Options
+	// StopTimeout is the time given to the emulator to exit before killing
+	// it, 10 seconds when zero.
+	StopTimeout time.Duration
+	// Launcher starts the process, ExecLauncher when nil.
+	Launcher Launcher
+}
+
+// Emulator is an emulator process started by the helper.
+type Emulator struct {
+	// Host is the address of the emulator, "host:port".
+	Host string
+
+	process     Process
+	stopTimeout time.Duration
+	exited      chan struct{}
+	exitErr     error
+	stopOnce    sync.Once
+	stopErr     error
+}
+
+// Command returns the command starting the emulator of path listening on
+// host and port.
+func Command(path, host string, port int, args []string) []string {
+	var command []string
+	switch {
+	case path == "":
+		command = []string{"gcloud", "beta", "emulators", "pubsub", "start", "--host-port=" + net.JoinHostPort(host, strconv.Itoa(port))}
+	case strings.HasSuffix(path, ".jar"):
+		command = []string{"java", "-jar", path, "--host=" + host, "--port=" + strconv.Itoa(port)}
+	default:
+		command = []string{path, "--host=" + host, "--port=" + strconv.Itoa(port)}
+	}
+	return append(command, args...)
+}
+
+// FreePort returns a port nothing is listening on in host.
+func FreePort(host string) (int, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Start starts the emulator and waits until it answers. Its output is logged
+// line by line. The emulator is stopped when it isn't ready in time, exits
+// or the context is cancelled.
+func Start(ctx context.Context, options Options) (*Emulator, error) {
+	host := options.Host
+	if host == "" {
+		host = defaultHost
+	}
+
+	port := options.Port
+	if port == 0 {
+		var err error
+		if port, err = FreePort(host); err != nil {
+			return nil, fmt.Errorf("can't find a free port for the emulator: %w", err)
+		}
+	}
+
+	launcher := options.Launcher
+	if launcher == nil {
+		launcher = ExecLauncher{}
+	}
+
+	stopTimeout := options.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = defaultStopTimeout
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	command := Command(options.Path, host, port, options.Args)
+	Llog.Info(fmt.Sprintf("Starting the emulator on '%s': %s", address, strings.Join(command, " ")))
+
+	output := &logWriter{}
+	process, err := launcher.Launch(command, output)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Emulator{
+		Host:        address,
+		process:     process,
+		stopTimeout: stopTimeout,
+		exited:      make(chan struct{}),
+	}
+	go func() {
+		e.exitErr = process.Wait()
+		output.Flush()
+		close(e.exited)
+	}()
+
+	// Waiting is cancelled as soon as the emulator exits
+	readyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-e.exited:
+			cancel()
+		case <-readyCtx.Done():
+		}
+	}()
+
+	if err := readiness.WaitUntilReady(readyCtx, utils.NewClient(address, "v1"), options.Readiness); err != nil {
+		select {
+		case <-e.exited:
+			return nil, fmt.Errorf("the emulator exited before being ready: %v", e.exitErr)
+		default:
+		}
+		if stopErr := e.Stop(); stopErr != nil {
+			return nil, errors.Join(err, stopErr)
+		}
+		return nil, err
+	}
+
+	Llog.Info(fmt.Sprintf("Emulator ready on '%s'", address))
+	return e, nil
+}
+
+// Exited is closed when the emulator process exits.
+func (e *Emulator) Exited() <-chan struct{} {
+	return e.exited
+}
+
+// Stop asks the emulator to exit and waits for it, killing it when it doesn't
+// exit within the stop timeout. Calling it again does nothing.
+func (e *Emulator) Stop() error {
+	e.stopOnce.Do(func() {
+		select {
+		case <-e.exited:
+			return
+		default:
+		}
+
+		Llog.Info(fmt.Sprintf("Stopping the emulator on '%s'", e.Host))
+		if err := e.process.Stop(); err != nil {
+			Llog.Warn(fmt.Sprintf("Can't stop the emulator, killing it: %s", err))
+		} else {
+			select {
+			case <-e.exited:
+				return
+			case <-time.After(e.stopTimeout):
+				Llog.Warn(fmt.Sprintf("The emulator didn't stop after %s, killing it", e.stopTimeout))
+			}
+		}
+
+		if err := e.process.Kill(); err != nil {
+			e.stopErr = fmt.Errorf("can't kill the emulator: %w", err)
+			return
+		}
+		<-e.exited
+	})
+	return e.stopErr
+}
+
+// logWriter logs every line written to it.
+type logWriter struct {
+	mutex   sync.Mutex
+	pending []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		logLine(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs the last line when it didn't end with a new line.
+func (w *logWriter) Flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	logLine(w.pending)
+	w.pending = nil
+}
+
+func logLine(line []byte) {
+	if text := strings.TrimRight(string(line), "\r "); text != "" {
+		Llog.Info("[emulator] " + text)
+	}
+}
@@ -0,0 +1,196 @@
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/readiness"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+	"github.com/stretchr/testify/assert"
+)
+
+// The test binary runs as a fake emulator when this variable is set.
+const fakeEmulatorVariable = "FAKE_PUBSUB_EMULATOR"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeEmulatorVariable) == "1" {
+		runFakeEmulator(os.Args[1:])
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeEmulator answers the readiness probes on the --host and --port of
+// the arguments until SIGTERM.
+func runFakeEmulator(args []string) {
+	host, port := "", ""
+	for _, arg := range args {
+		if value, found := strings.CutPrefix(arg, "--host="); found {
+			host = value
+		}
+		if value, found := strings.CutPrefix(arg, "--port="); found {
+			port = value
+		}
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	go http.Serve(listener, fakeEmulatorHandler())
+	fmt.Println("fake emulator started")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	<-signals
+	fmt.Print("fake emulator stopped")
+}
+
+func fakeEmulatorHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"topics":[]}`))
+	})
+}
+
+// fakeLauncher serves the emulator API in the test process on the port of
+// the command.
+type fakeLauncher struct {
+	ignoreStop bool
+	exitEarly  bool
+	launched   []string
+}
+
+type fakeProcess struct {
+	ignoreStop bool
+	server     *http.Server
+	done       chan struct{}
+	once       sync.Once
+	killed     bool
+}
+
+func (l *fakeLauncher) Launch(command []string, output io.Writer) (Process, error) {
+	l.launched = command
+	process := &fakeProcess{ignoreStop: l.ignoreStop, done: make(chan struct{})}
+	fmt.Fprintln(output, "fake emulator started")
+
+	if l.exitEarly {
+		process.exit()
+		return process, nil
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(defaultHost, strings.TrimPrefix(command[len(command)-1], "--port=")))
+	if err != nil {
+		return nil, err
+	}
+	process.server = &http.Server{Handler: fakeEmulatorHandler()}
+	go process.server.Serve(listener)
+	return process, nil
+}
+
+func (p *fakeProcess) exit() {
+	p.once.Do(func() {
+		if p.server != nil {
+			p.server.Close()
+		}
+		close(p.done)
+	})
+}
+
+func (p *fakeProcess) Stop() error {
+	if !p.ignoreStop {
+		p.exit()
+	}
+	return nil
+}
+
+func (p *fakeProcess) Kill() error {
+	p.killed = true
+	p.exit()
+	return nil
+}
+
+func (p *fakeProcess) Wait() error {
+	<-p.done
+	if p.server == nil {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	logs := &bytes.Buffer{}
+	Llog.SetOutput(logs)
+	t.Cleanup(func() { Llog.SetOutput(os.Stderr) })
+	return logs
+}
+
+var testReadiness = readiness.Options{Interval: 10 * time.Millisecond, Timeout: 5 * time.Second}
+
+func Test_Emulator_Command(t *testing.T) {
+	assert.Equal(t, []string{"gcloud", "beta", "emulators", "pubsub", "start", "--host-port=localhost:8085", "--project=test"}, Command("", "localhost", 8085, []string{"--project=test"}))
+	assert.Equal(t, []string{"java", "-jar", "/opt/emulator.jar", "--host=0.0.0.0", "--port=8085"}, Command("/opt/emulator.jar", "0.0.0.0", 8085, nil))
+	assert.Equal(t, []string{"/opt/bin/cloud-pubsub-emulator", "--host=localhost", "--port=8085"}, Command("/opt/bin/cloud-pubsub-emulator", "localhost", 8085, nil))
+}
+
+func Test_Emulator_StartAndStop(t *testing.T) {
+	logs := captureLogs(t)
+	launcher := &fakeLauncher{}
+
+	emulator, err := Start(context.Background(), Options{Path: "fake-emulator", Launcher: launcher, Readiness: testReadiness})
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-emulator", launcher.launched[0])
+	assert.True(t, strings.HasPrefix(emulator.Host, "localhost:"))
+	assert.Contains(t, logs.String(), "[emulator] fake emulator started")
+
+	assert.NoError(t, emulator.Stop())
+	<-emulator.Exited()
+	assert.NoError(t, emulator.Stop())
+}
+
+func Test_Emulator_ExitsBeforeReady(t *testing.T) {
+	captureLogs(t)
+
+	_, err := Start(context.Background(), Options{Launcher: &fakeLauncher{exitEarly: true}, Readiness: testReadiness})
+	assert.EqualError(t, err, "the emulator exited before being ready: exit status 1")
+}
+
+func Test_Emulator_KilledAfterStopTimeout(t *testing.T) {
+	logs := captureLogs(t)
+
+	emulator, err := Start(context.Background(), Options{Path: "fake-emulator", Launcher: &fakeLauncher{ignoreStop: true}, Readiness: testReadiness, StopTimeout: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	assert.NoError(t, emulator.Stop())
+	assert.True(t, emulator.process.(*fakeProcess).killed)
+	assert.Contains(t, logs.String(), "didn't stop after 10ms, killing it")
+}
+
+func Test_Emulator_ExecLauncher(t *testing.T) {
+	logs := captureLogs(t)
+	t.Setenv(fakeEmulatorVariable, "1")
+
+	emulator, err := Start(context.Background(), Options{Path: os.Args[0], Readiness: testReadiness})
+	assert.NoError(t, err)
+
+	assert.NoError(t, emulator.Stop())
+	select {
+	case <-emulator.Exited():
+	default:
+		t.Fatal("the emulator is still running")
+	}
+	assert.Contains(t, logs.String(), "[emulator] fake emulator started")
+	assert.Contains(t, logs.String(), "[emulator] fake emulator stopped")
+}
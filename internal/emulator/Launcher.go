@@ -0,0 +1,60 @@
+package emulator
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Process is a started emulator.
+type Process interface {
+	// Stop asks the process to exit, letting it clean up.
+	Stop() error
+	// Kill terminates the process right away.
+	Kill() error
+	// Wait blocks until the process exits.
+	Wait() error
+}
+
+// Launcher starts the emulator processes, writing everything they print to
+// output. ExecLauncher runs real commands.
+type Launcher interface {
+	Launch(command []string, output io.Writer) (Process, error)
+}
+
+// ExecLauncher runs the command as a child process in its own process group,
+// so the processes it starts (gcloud runs the emulator through java) are
+// stopped with it.
+type ExecLauncher struct{}
+
+func (ExecLauncher) Launch(command []string, output io.Writer) (Process, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no command to launch")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("can't start '%s': %w", command[0], err)
+	}
+	return &execProcess{cmd: cmd}, nil
+}
+
+type execProcess struct {
+	cmd *exec.Cmd
+}
+
+func (p *execProcess) Stop() error {
+	return stopProcessGroup(p.cmd)
+}
+
+func (p *execProcess) Kill() error {
+	return killProcessGroup(p.cmd)
+}
+
+func (p *execProcess) Wait() error {
+	return p.cmd.Wait()
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package emulator
+
+import (
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// stopProcessGroup kills the process, as Windows can't deliver SIGTERM.
+func stopProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,157 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// hclExpression is a raw HCL expression (a reference, a function call...)
+// that must be written without quoting.
+type hclExpression string
+
+type hclAttribute struct {
+	Name  string
+	Value interface{}
+}
+
+// hclBlock is a minimal representation of an HCL block, enough to render the
+// resources generated by this package in a `terraform fmt` friendly way.
+type hclBlock struct {
+	Type       string
+	Labels     []string
+	Attributes []hclAttribute
+	Blocks     []hclBlock
+}
+
+func (b *hclBlock) attribute(name string, value interface{}) {
+	b.Attributes = append(b.Attributes, hclAttribute{Name: name, Value: value})
+}
+
+func (b *hclBlock) block(child hclBlock) {
+	b.Blocks = append(b.Blocks, child)
+}
+
+func (b hclBlock) render(builder *strings.Builder, indent int) {
+	padding := strings.Repeat("  ", indent)
+
+	builder.WriteString(padding)
+	builder.WriteString(b.Type)
+	for _, label := range b.Labels {
+		builder.WriteString(" ")
+		builder.WriteString(quoteHclString(label))
+	}
+
+	if len(b.Attributes) == 0 && len(b.Blocks) == 0 {
+		builder.WriteString(" {}\n")
+		return
+	}
+	builder.WriteString(" {\n")
+
+	width := 0
+	for _, attribute := range b.Attributes {
+		if len(attribute.Name) > width {
+			width = len(attribute.Name)
+		}
+	}
+
+	for _, attribute := range b.Attributes {
+		builder.WriteString(padding)
+		builder.WriteString("  ")
+		builder.WriteString(attribute.Name)
+		builder.WriteString(strings.Repeat(" ", width-len(attribute.Name)))
+		builder.WriteString(" = ")
+		builder.WriteString(renderHclValue(attribute.Value, indent+1))
+		builder.WriteString("\n")
+	}
+
+	for i, child := range b.Blocks {
+		if i > 0 || len(b.Attributes) > 0 {
+			builder.WriteString("\n")
+		}
+		child.render(builder, indent+1)
+	}
+
+	builder.WriteString(padding)
+	builder.WriteString("}\n")
+}
+
+func renderHclValue(value interface{}, indent int) string {
+	switch v := value.(type) {
+	case hclExpression:
+		return string(v)
+	case string:
+		if strings.Contains(v, "\n") {
+			return renderHclHeredoc(v)
+		}
+		return quoteHclString(v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case int:
+		return fmt.Sprintf("%d", v)
+	case []string:
+		quoted := make([]string, 0, len(v))
+		for _, item := range v {
+			quoted = append(quoted, quoteHclString(item))
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	case map[string]string:
+		if len(v) == 0 {
+			return "{}"
+		}
+
+		keys := make([]string, 0, len(v))
+		width := 0
+		for key := range v {
+			keys = append(keys, key)
+			if len(quoteHclString(key)) > width {
+				width = len(quoteHclString(key))
+			}
+		}
+		sort.Strings(keys)
+
+		padding := strings.Repeat("  ", indent)
+		var builder strings.Builder
+		builder.WriteString("{\n")
+		for _, key := range keys {
+			quotedKey := quoteHclString(key)
+			builder.WriteString(padding)
+			builder.WriteString("  ")
+			builder.WriteString(quotedKey)
+			builder.WriteString(strings.Repeat(" ", width-len(quotedKey)))
+			builder.WriteString(" = ")
+			builder.WriteString(quoteHclString(v[key]))
+			builder.WriteString("\n")
+		}
+		builder.WriteString(padding)
+		builder.WriteString("}")
+		return builder.String()
+	default:
+		panic(fmt.Sprintf("unsupported HCL value type %T", value))
+	}
+}
+
+func escapeHclTemplate(value string) string {
+	value = strings.ReplaceAll(value, "${", "$${")
+	return strings.ReplaceAll(value, "%{", "%%{")
+}
+
+func quoteHclString(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	)
+	return `"` + escapeHclTemplate(replacer.Replace(value)) + `"`
+}
+
+func renderHclHeredoc(value string) string {
+	delimiter := "EOT"
+	for strings.Contains(value, delimiter) {
+		delimiter += "_"
+	}
+
+	return "<<" + delimiter + "\n" + escapeHclTemplate(strings.TrimRight(value, "\n")) + "\n" + delimiter
+}
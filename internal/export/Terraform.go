@@ -0,0 +1,308 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+var invalidTerraformIdentifierCharacters = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// terraformIdentifiers hands out unique Terraform resource identifiers for
+// every resource type, so two resources with the same name in different
+// projects do not collide.
+type terraformIdentifiers struct {
+	used map[string]bool
+}
+
+func (ti *terraformIdentifiers) next(resourceType, project, name string) string {
+	candidates := []string{
+		sanitizeTerraformIdentifier(name),
+		sanitizeTerraformIdentifier(project + "_" + name),
+	}
+
+	for _, candidate := range candidates {
+		key := resourceType + "." + candidate
+		if !ti.used[key] {
+			ti.used[key] = true
+			return candidate
+		}
+	}
+
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", candidates[1], i)
+		key := resourceType + "." + candidate
+		if !ti.used[key] {
+			ti.used[key] = true
+			return candidate
+		}
+	}
+}
+
+func sanitizeTerraformIdentifier(name string) string {
+	identifier := invalidTerraformIdentifierCharacters.ReplaceAllString(name, "_")
+	if identifier == "" || !(identifier[0] == '_' || (identifier[0] >= 'a' && identifier[0] <= 'z') || (identifier[0] >= 'A' && identifier[0] <= 'Z')) {
+		identifier = "_" + identifier
+	}
+	return identifier
+}
+
+// schemaResourceName returns the name the schema will have once created. The
+// helper creates schemas using the id when present (see pubsub.CreateSchema).
+func schemaResourceName(schema pubsub.Schema) string {
+	if schema.Id != "" {
+		return schema.Id
+	}
+	return schema.Name
+}
+
+// findSchemaForTopic resolves which schema of the project a topic is bound to,
+// following the same precedence used when creating the topic.
+func findSchemaForTopic(project pubsub.Project, settings pubsub.SchemaSettings) (int, bool) {
+	references := []string{settings.FirstSchemaId, settings.LastSchemaId, settings.Schema}
+
+	for _, reference := range references {
+		if reference == "" {
+			continue
+		}
+
+		reference = strings.TrimPrefix(reference, pubsub.GetResourceNameForSchema(project.Name, ""))
+
+		for i, schema := range project.Schemas {
+			if schema.Id == reference {
+				return i, true
+			}
+		}
+
+		for i, schema := range project.Schemas {
+			if schema.Name == reference {
+				return i, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// Terraform renders the configuration as google_pubsub_* resources, linking
+// subscriptions to their topics and topics to their schemas through
+// references instead of hardcoded names. Terraform can't express schema
+// revisions, so only the last one is exported and the revision range of the
+// topics is dropped, logging a warning for each.
+func Terraform(configuration internal.Configuration) string {
+	identifiers := terraformIdentifiers{used: map[string]bool{}}
+	blocks := []hclBlock{}
+
+	// Topic identifiers are needed beforehand, as dead letter policies may
+	// reference topics defined later or in other projects.
+	topicIdentifiers := map[string]string{}
+	for _, project := range configuration.Projects {
+		for _, topic := range project.Topics {
+			topicResourceName := pubsub.GetResourceNameForTopic(project.Name, topic.Name)
+			topicIdentifiers[topicResourceName] = identifiers.next("google_pubsub_topic", project.Name, topic.Name)
+		}
+	}
+
+	for _, project := range configuration.Projects {
+		schemaIdentifiers := map[int]string{}
+		// Schemas sharing the resource name are revisions of the same schema,
+		// only the last definition is kept as Terraform creates new revisions
+		// when the definition changes.
+		schemaBlockIndexes := map[string]int{}
+		schemaResourceNames := []string{}
+		schemaRevisionCounts := map[string]int{}
+
+		for i, schema := range project.Schemas {
+			resourceName := schemaResourceName(schema)
+			schemaRevisionCounts[resourceName] += len(schema.SchemaRevisions())
+
+			if blockIndex, exists := schemaBlockIndexes[resourceName]; exists {
+				schemaIdentifiers[i] = blocks[blockIndex].Labels[1]
+				blocks[blockIndex] = terraformSchemaBlock(project.Name, blocks[blockIndex].Labels[1], resourceName, schema)
+				continue
+			}
+
+			identifier := identifiers.next("google_pubsub_schema", project.Name, resourceName)
+			schemaIdentifiers[i] = identifier
+			schemaBlockIndexes[resourceName] = len(blocks)
+			schemaResourceNames = append(schemaResourceNames, resourceName)
+			blocks = append(blocks, terraformSchemaBlock(project.Name, identifier, resourceName, schema))
+		}
+
+		for _, resourceName := range schemaResourceNames {
+			if count := schemaRevisionCounts[resourceName]; count > 1 {
+				Llog.Warn(fmt.Sprintf("Schema '%s' of project '%s' has %d revisions, only the last one is exported to Terraform", resourceName, project.Name, count))
+			}
+		}
+
+		for _, topic := range project.Topics {
+			topicIdentifier := topicIdentifiers[pubsub.GetResourceNameForTopic(project.Name, topic.Name)]
+
+			topicBlock := hclBlock{Type: "resource", Labels: []string{"google_pubsub_topic", topicIdentifier}}
+			topicBlock.attribute("project", project.Name)
+			topicBlock.attribute("name", topic.Name)
+
+			if len(topic.Labels) > 0 {
+				topicBlock.attribute("labels", map[string]string(topic.Labels))
+			}
+
+			if topic.KmsKeyName != "" {
+				topicBlock.attribute("kms_key_name", topic.KmsKeyName)
+			}
+
+			if topic.MessageRetentionDuration != "" {
+				topicBlock.attribute("message_retention_duration", topic.MessageRetentionDuration)
+			}
+
+			if len(topic.MessageStoragePolicy.AllowedPersistenceRegions) > 0 {
+				policyBlock := hclBlock{Type: "message_storage_policy"}
+				policyBlock.attribute("allowed_persistence_regions", topic.MessageStoragePolicy.AllowedPersistenceRegions)
+				if topic.MessageStoragePolicy.EnforceInTransit {
+					policyBlock.attribute("enforce_in_transit", true)
+				}
+				topicBlock.block(policyBlock)
+			}
+
+			if topic.SchemaSettings != nil {
+				settingsBlock := hclBlock{Type: "schema_settings"}
+
+				if schemaIndex, found := findSchemaForTopic(project, *topic.SchemaSettings); found {
+					settingsBlock.attribute("schema", hclExpression(fmt.Sprintf("google_pubsub_schema.%s.id", schemaIdentifiers[schemaIndex])))
+				} else {
+					settingsBlock.attribute("schema", topic.SchemaSettings.Schema)
+				}
+
+				if topic.SchemaSettings.Encoding != "" {
+					settingsBlock.attribute("encoding", string(topic.SchemaSettings.Encoding))
+				}
+
+				if topic.SchemaSettings.FirstRevisionId != "" || topic.SchemaSettings.LastRevisionId != "" {
+					Llog.Warn(fmt.Sprintf("Topic '%s' of project '%s' accepts the schema revisions from '%s' to '%s', Terraform can't set them and every revision will be accepted", topic.Name, project.Name, topic.SchemaSettings.FirstRevisionId, topic.SchemaSettings.LastRevisionId))
+				}
+
+				topicBlock.block(settingsBlock)
+			}
+
+			if topic.IngestionDataSourceSettings != nil {
+				topicBlock.block(terraformIngestionDataSourceSettingsBlock(*topic.IngestionDataSourceSettings))
+			}
+
+			blocks = append(blocks, topicBlock)
+
+			for _, subscription := range topic.Subscriptions {
+				subscriptionIdentifier := identifiers.next("google_pubsub_subscription", project.Name, subscription.Name)
+
+				subscriptionBlock := hclBlock{Type: "resource", Labels: []string{"google_pubsub_subscription", subscriptionIdentifier}}
+				subscriptionBlock.attribute("project", project.Name)
+				subscriptionBlock.attribute("name", subscription.Name)
+				subscriptionBlock.attribute("topic", hclExpression(fmt.Sprintf("google_pubsub_topic.%s.id", topicIdentifier)))
+
+				if len(subscription.Labels) > 0 {
+					subscriptionBlock.attribute("labels", map[string]string(subscription.Labels))
+				}
+
+				if subscription.DeadLetterPolicy != nil {
+					policyBlock := hclBlock{Type: "dead_letter_policy"}
+
+					deadLetterTopic := pubsub.GetResourceNameForDeadLetterTopic(project.Name, subscription.DeadLetterPolicy.DeadLetterTopic)
+					if deadLetterTopicIdentifier, exists := topicIdentifiers[deadLetterTopic]; exists {
+						policyBlock.attribute("dead_letter_topic", hclExpression(fmt.Sprintf("google_pubsub_topic.%s.id", deadLetterTopicIdentifier)))
+					} else {
+						policyBlock.attribute("dead_letter_topic", deadLetterTopic)
+					}
+
+					if subscription.DeadLetterPolicy.MaxDeliveryAttempts > 0 {
+						policyBlock.attribute("max_delivery_attempts", subscription.DeadLetterPolicy.MaxDeliveryAttempts)
+					}
+
+					subscriptionBlock.block(policyBlock)
+				}
+
+				if !subscription.PushConfig.IsPull() {
+					pushBlock := hclBlock{Type: "push_config"}
+					pushBlock.attribute("push_endpoint", subscription.PushConfig.PushEndpoint)
+					if len(subscription.PushConfig.Attributes) > 0 {
+						pushBlock.attribute("attributes", subscription.PushConfig.Attributes)
+					}
+					subscriptionBlock.block(pushBlock)
+				}
+
+				blocks = append(blocks, subscriptionBlock)
+			}
+		}
+	}
+
+	var builder strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		block.render(&builder, 0)
+	}
+
+	return builder.String()
+}
+
+func terraformSchemaBlock(project, identifier, resourceName string, schema pubsub.Schema) hclBlock {
+	schemaBlock := hclBlock{Type: "resource", Labels: []string{"google_pubsub_schema", identifier}}
+	schemaBlock.attribute("project", project)
+	schemaBlock.attribute("name", resourceName)
+	if schema.Type != "" {
+		schemaBlock.attribute("type", schema.Type)
+	}
+	// Terraform keeps a single definition, committing a new revision when
+	// it changes, so the newest revision is the one exported.
+	revisions := schema.SchemaRevisions()
+	schemaBlock.attribute("definition", revisions[len(revisions)-1].Definition)
+	return schemaBlock
+}
+
+func terraformIngestionDataSourceSettingsBlock(settings pubsub.TopicIngestionDataSourceSettings) hclBlock {
+	settingsBlock := hclBlock{Type: "ingestion_data_source_settings"}
+
+	if settings.AwsKinesis != nil {
+		kinesisBlock := hclBlock{Type: "aws_kinesis"}
+		kinesisBlock.attribute("stream_arn", settings.AwsKinesis.StreamArn)
+		kinesisBlock.attribute("consumer_arn", settings.AwsKinesis.ConsumerArn)
+		kinesisBlock.attribute("aws_role_arn", settings.AwsKinesis.AwsRoleArn)
+		kinesisBlock.attribute("gcp_service_account", settings.AwsKinesis.GcpServiceAccount)
+		settingsBlock.block(kinesisBlock)
+	}
+
+	if settings.CloudStorage != nil {
+		storageBlock := hclBlock{Type: "cloud_storage"}
+		storageBlock.attribute("bucket", settings.CloudStorage.Bucket)
+		if settings.CloudStorage.MinimumObjectCreateTime != "" {
+			storageBlock.attribute("minimum_object_create_time", settings.CloudStorage.MinimumObjectCreateTime)
+		}
+		if settings.CloudStorage.MatchGlob != "" {
+			storageBlock.attribute("match_glob", settings.CloudStorage.MatchGlob)
+		}
+
+		if settings.CloudStorage.TextFormat != nil {
+			textFormatBlock := hclBlock{Type: "text_format"}
+			textFormatBlock.attribute("delimiter", settings.CloudStorage.TextFormat.Delimiter)
+			storageBlock.block(textFormatBlock)
+		}
+		if settings.CloudStorage.AvroFormat != nil {
+			storageBlock.block(hclBlock{Type: "avro_format"})
+		}
+		if settings.CloudStorage.PubSubAvroFormat != nil {
+			storageBlock.block(hclBlock{Type: "pubsub_avro_format"})
+		}
+
+		settingsBlock.block(storageBlock)
+	}
+
+	if settings.PlatformLogsSettings.Severity != "" {
+		logsBlock := hclBlock{Type: "platform_logs_settings"}
+		logsBlock.attribute("severity", settings.PlatformLogsSettings.Severity)
+		settingsBlock.block(logsBlock)
+	}
+
+	return settingsBlock
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Terraform_TopicWithSubscription(t *testing.T) {
+	config := internal.Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name:   "test.topic",
+						Labels: pubsub.Labels{"owner": "admin"},
+						Subscriptions: []pubsub.Subscription{
+							{Name: "test.subscription", PushConfig: &pubsub.PushConfig{}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hcl := Terraform(config)
+	assert.Equal(t, `resource "google_pubsub_topic" "test_topic" {
+  project = "test-project"
+  name    = "test.topic"
+  labels  = {
+    "owner" = "admin"
+  }
+}
+
+resource "google_pubsub_subscription" "test_subscription" {
+  project = "test-project"
+  name    = "test.subscription"
+  topic   = google_pubsub_topic.test_topic.id
+}
+`, hcl)
+}
+
+func Test_Terraform_TopicReferencesSchema(t *testing.T) {
+	config := internal.Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Schemas: []pubsub.Schema{
+					{Id: "schemaV1", Name: "test.schema", Type: "AVRO", Definition: `{"type":"string"}`},
+				},
+				Topics: []pubsub.Topic{
+					{
+						Name: "test.topic",
+						SchemaSettings: &pubsub.SchemaSettings{
+							Schema:        "test.schema",
+							Encoding:      pubsub.SCHEMA_ENCODING_JSON,
+							FirstSchemaId: "schemaV1",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hcl := Terraform(config)
+	assert.Contains(t, hcl, `resource "google_pubsub_schema" "schemaV1" {`)
+	assert.Contains(t, hcl, `definition = "{\"type\":\"string\"}"`)
+	assert.Contains(t, hcl, `schema   = google_pubsub_schema.schemaV1.id`)
+	assert.Contains(t, hcl, `encoding = "JSON"`)
+}
+
+func Test_Terraform_SameNameInDifferentProjects(t *testing.T) {
+	config := internal.Configuration{
+		Projects: []pubsub.Project{
+			{Name: "first-project", Topics: []pubsub.Topic{{Name: "topic"}}},
+			{Name: "second-project", Topics: []pubsub.Topic{{Name: "topic"}}},
+		},
+	}
+
+	hcl := Terraform(config)
+	assert.Contains(t, hcl, `resource "google_pubsub_topic" "topic" {`)
+	assert.Contains(t, hcl, `resource "google_pubsub_topic" "second-project_topic" {`)
+}
+
+func Test_Terraform_EscapesTemplateSequences(t *testing.T) {
+	assert.Equal(t, `"$${value} \"quoted\""`, quoteHclString(`${value} "quoted"`))
+}
+
+func Test_Terraform_DeadLetterTopicReference(t *testing.T) {
+	config := internal.Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Topics: []pubsub.Topic{
+					{
+						Name: "orders",
+						Subscriptions: []pubsub.Subscription{
+							{
+								Name:             "orders.billing",
+								DeadLetterPolicy: &pubsub.DeadLetterPolicy{DeadLetterTopic: "orders.dead-letter", MaxDeliveryAttempts: 5},
+							},
+						},
+					},
+					{Name: "orders.dead-letter"},
+				},
+			},
+		},
+	}
+
+	hcl := Terraform(config)
+	assert.Contains(t, hcl, `  dead_letter_policy {
+    dead_letter_topic     = google_pubsub_topic.orders_dead-letter.id
+    max_delivery_attempts = 5
+  }`)
+}
+
+func Test_Terraform_WarnsAboutDroppedRevisions(t *testing.T) {
+	logs := &bytes.Buffer{}
+	Llog.SetOutput(logs)
+	t.Cleanup(func() { Llog.SetOutput(os.Stderr) })
+
+	config := internal.Configuration{
+		Projects: []pubsub.Project{
+			{
+				Name: "test-project",
+				Schemas: []pubsub.Schema{
+					{Name: "test.schema", Type: "AVRO", Revisions: []pubsub.SchemaRevision{
+						{Alias: "v1", Definition: `{"type":"string"}`},
+						{Alias: "v2", Definition: `{"type":"long"}`},
+					}},
+				},
+				Topics: []pubsub.Topic{
+					{
+						Name: "test.topic",
+						SchemaSettings: &pubsub.SchemaSettings{
+							Schema:          "test.schema",
+							Encoding:        pubsub.SCHEMA_ENCODING_JSON,
+							FirstRevisionId: "v1",
+							LastRevisionId:  "v2",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hcl := Terraform(config)
+	assert.Contains(t, hcl, `definition = "{\"type\":\"long\"}"`)
+	assert.NotContains(t, hcl, "string")
+	assert.Contains(t, logs.String(), "Schema 'test.schema' of project 'test-project' has 2 revisions, only the last one is exported to Terraform")
+	assert.Contains(t, logs.String(), "Topic 'test.topic' of project 'test-project' accepts the schema revisions from 'v1' to 'v2', Terraform can't set them and every revision will be accepted")
+}
@@ -0,0 +1,24 @@
+package fixtures
+
+var fakeFirstNames = []string{
+	"Alice", "Bruno", "Carla", "David", "Elena", "Farid", "Grace", "Hugo",
+	"Irene", "Javier", "Kenji", "Laura", "Marco", "Nadia", "Oscar", "Paula",
+	"Quentin", "Rosa", "Samuel", "Teresa", "Umar", "Valeria", "William", "Yara",
+}
+
+var fakeLastNames = []string{
+	"Anderson", "Bianchi", "Castillo", "Dubois", "Evans", "Fischer", "Garcia",
+	"Hansen", "Ito", "Jensen", "Kowalski", "Lopez", "Martin", "Novak",
+	"Okafor", "Petrov", "Quinn", "Rossi", "Schmidt", "Tanaka", "Ueda",
+	"Virtanen", "Walker", "Zhang",
+}
+
+var fakeDomains = []string{
+	"example.com", "example.org", "example.net",
+}
+
+var fakeWords = []string{
+	"amber", "breeze", "canyon", "delta", "ember", "forest", "glacier",
+	"harbor", "island", "jungle", "lagoon", "meadow", "nebula", "orbit",
+	"prairie", "quartz", "river", "summit", "tundra", "valley",
+}
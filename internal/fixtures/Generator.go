@@ -0,0 +1,283 @@
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+)
+
+// Generator expands the message templates of the configuration into
+// messages. The random values depend only on the seed, so the same seed
+// generates the same messages (except for the current time).
+type Generator struct {
+	Now func() time.Time
+
+	random    *rand.Rand
+	sequences map[string]int
+}
+
+// TemplateData is the data the templates are executed with.
+type TemplateData struct {
+	// Index of the message generated by the template, starting at 0.
+	Index int
+	// Count of messages generated by the template.
+	Count int
+}
+
+// NewGenerator returns a generator using the given seed, or a random one when
+// the seed is 0.
+func NewGenerator(seed int64) *Generator {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Generator{
+		Now:       time.Now,
+		random:    rand.New(rand.NewSource(seed)),
+		sequences: map[string]int{},
+	}
+}
+
+// Expand returns the messages generated by every message with a template,
+// Count times (once when not set). The rest of the messages are returned as
+// they are.
+func (g *Generator) Expand(messages []pubsub.Message) ([]pubsub.Message, error) {
+	expanded := []pubsub.Message{}
+	for i, message := range messages {
+		if message.Template == "" {
+			if message.Count != 0 {
+				return nil, fmt.Errorf("message %d: count can only be used with template", i)
+			}
+			expanded = append(expanded, message)
+			continue
+		}
+
+		generated, err := g.expandMessage(message)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		expanded = append(expanded, generated...)
+	}
+	return expanded, nil
+}
+
+func (g *Generator) expandMessage(message pubsub.Message) ([]pubsub.Message, error) {
+	if message.Count < 0 {
+		return nil, fmt.Errorf("invalid count %d", message.Count)
+	}
+
+	count := message.Count
+	if count == 0 {
+		count = 1
+	}
+
+	messageTemplate, err := g.NewMessageTemplate(message)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := make([]pubsub.Message, 0, count)
+	for index := 0; index < count; index++ {
+		generatedMessage, err := messageTemplate.Render(index, count)
+		if err != nil {
+			return nil, err
+		}
+		generated = append(generated, generatedMessage)
+	}
+	return generated, nil
+}
+
+// MessageTemplate is the parsed template of a message, rendering its data,
+// attributes and ordering key.
+type MessageTemplate struct {
+	data               *template.Template
+	attributeKeys      []string
+	attributeTemplates map[string]*template.Template
+	orderingKey        *template.Template
+
+	// Index of the message being rendered, used by seq
+	index int
+}
+
+// NewMessageTemplate parses the template of a message. The messages rendered
+// share the random values and named sequences of the generator, which is not
+// safe for concurrent use.
+func (g *Generator) NewMessageTemplate(message pubsub.Message) (*MessageTemplate, error) {
+	if len(message.Data) > 0 || message.DataBase64 != "" {
+		return nil, fmt.Errorf("template can't be used together with data or dataBase64")
+	}
+
+	messageTemplate := &MessageTemplate{attributeTemplates: map[string]*template.Template{}}
+
+	// Messages rendered by the same template share an unnamed sequence
+	functions := g.functions()
+	functions["seq"] = func(names ...string) int {
+		if len(names) == 0 {
+			return messageTemplate.index + 1
+		}
+		g.sequences[names[0]]++
+		return g.sequences[names[0]]
+	}
+
+	var err error
+	messageTemplate.data, err = template.New("template").Funcs(functions).Option("missingkey=error").Parse(message.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sorted, so the random values only depend on the seed
+	for key := range message.Attributes {
+		messageTemplate.attributeKeys = append(messageTemplate.attributeKeys, key)
+	}
+	sort.Strings(messageTemplate.attributeKeys)
+
+	for _, key := range messageTemplate.attributeKeys {
+		attributeTemplate, err := template.New(key).Funcs(functions).Option("missingkey=error").Parse(message.Attributes[key])
+		if err != nil {
+			return nil, fmt.Errorf("attribute '%s': %w", key, err)
+		}
+		messageTemplate.attributeTemplates[key] = attributeTemplate
+	}
+
+	messageTemplate.orderingKey, err = template.New("orderingKey").Funcs(functions).Option("missingkey=error").Parse(message.OrderingKey)
+	if err != nil {
+		return nil, fmt.Errorf("orderingKey: %w", err)
+	}
+
+	return messageTemplate, nil
+}
+
+// Render returns the message number index (starting at 0) of count.
+func (t *MessageTemplate) Render(index, count int) (pubsub.Message, error) {
+	t.index = index
+	data := TemplateData{Index: index, Count: count}
+
+	text, err := execute(t.data, data)
+	if err != nil {
+		return pubsub.Message{}, err
+	}
+
+	message := pubsub.Message{Data: json.RawMessage(text)}
+	if !json.Valid(message.Data) {
+		// Plain text
+		message.Data, _ = json.Marshal(text)
+	}
+
+	if len(t.attributeKeys) > 0 {
+		message.Attributes = map[string]string{}
+		for _, key := range t.attributeKeys {
+			if message.Attributes[key], err = execute(t.attributeTemplates[key], data); err != nil {
+				return pubsub.Message{}, fmt.Errorf("attribute '%s': %w", key, err)
+			}
+		}
+	}
+
+	if message.OrderingKey, err = execute(t.orderingKey, data); err != nil {
+		return pubsub.Message{}, fmt.Errorf("orderingKey: %w", err)
+	}
+
+	return message, nil
+}
+
+func execute(t *template.Template, data TemplateData) (string, error) {
+	var output bytes.Buffer
+	if err := t.Execute(&output, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output.String()), nil
+}
+
+// functions returns the helpers available in the templates, besides seq.
+func (g *Generator) functions() template.FuncMap {
+	return template.FuncMap{
+		"uuid": g.uuid,
+		"now": func() string {
+			return g.Now().UTC().Format(time.RFC3339Nano)
+		},
+		"unixMillis": func() int64 {
+			return g.Now().UnixMilli()
+		},
+		"timestamp": func(layout string, offset string) (string, error) {
+			duration, err := time.ParseDuration(offset)
+			if err != nil {
+				return "", err
+			}
+			return g.Now().UTC().Add(duration).Format(layout), nil
+		},
+		"randomTime": func(within string) (string, error) {
+			duration, err := time.ParseDuration(within)
+			if err != nil {
+				return "", err
+			}
+			if duration <= 0 {
+				return "", fmt.Errorf("randomTime needs a positive duration, found '%s'", within)
+			}
+			return g.Now().UTC().Add(-time.Duration(g.random.Int63n(int64(duration)))).Format(time.RFC3339Nano), nil
+		},
+		"randInt": func(min, max int) (int, error) {
+			if max < min {
+				return 0, fmt.Errorf("randInt: max %d is lower than min %d", max, min)
+			}
+			return min + g.random.Intn(max-min+1), nil
+		},
+		"randFloat": func(min, max float64) float64 {
+			return min + g.random.Float64()*(max-min)
+		},
+		"randBool": func() bool {
+			return g.random.Intn(2) == 1
+		},
+		"pick": func(values ...interface{}) (interface{}, error) {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("pick needs at least one value")
+			}
+			return values[g.random.Intn(len(values))], nil
+		},
+		"firstName": g.firstName,
+		"lastName":  g.lastName,
+		"name": func() string {
+			return g.firstName() + " " + g.lastName()
+		},
+		"email": func() string {
+			return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(g.firstName()), strings.ToLower(g.lastName()), g.random.Intn(100), fakeDomains[g.random.Intn(len(fakeDomains))])
+		},
+		"word": func() string {
+			return fakeWords[g.random.Intn(len(fakeWords))]
+		},
+		"randString": func(length int) string {
+			text := make([]byte, length)
+			for i := range text {
+				text[i] = randomStringCharacters[g.random.Intn(len(randomStringCharacters))]
+			}
+			return string(text)
+		},
+		"json": func(value interface{}) (string, error) {
+			raw, err := json.Marshal(value)
+			return string(raw), err
+		},
+	}
+}
+
+const randomStringCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// uuid returns a random (version 4) UUID.
+func (g *Generator) uuid() string {
+	var id [16]byte
+	g.random.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+func (g *Generator) firstName() string {
+	return fakeFirstNames[g.random.Intn(len(fakeFirstNames))]
+}
+
+func (g *Generator) lastName() string {
+	return fakeLastNames[g.random.Intn(len(fakeLastNames))]
+}
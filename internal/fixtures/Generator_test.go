@@ -0,0 +1,93 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGenerator(seed int64) *Generator {
+	generator := NewGenerator(seed)
+	generator.Now = func() time.Time { return time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC) }
+	return generator
+}
+
+func Test_Generator_Expand(t *testing.T) {
+	messages, err := newTestGenerator(1).Expand([]pubsub.Message{
+		{Data: json.RawMessage(`"as is"`)},
+		{
+			Template:    `{"id": {{ seq }}, "order": {{ seq "orders" }}, "at": "{{ now }}", "day": "{{ timestamp "2006-01-02" "-24h" }}"}`,
+			Count:       3,
+			Attributes:  map[string]string{"index": "{{ .Index }}"},
+			OrderingKey: "key-{{ .Index }}",
+		},
+		{Template: `plain {{ seq "orders" }}`},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []pubsub.Message{
+		{Data: json.RawMessage(`"as is"`)},
+		{Data: json.RawMessage(`{"id": 1, "order": 1, "at": "2025-03-03T10:00:00Z", "day": "2025-03-02"}`), Attributes: map[string]string{"index": "0"}, OrderingKey: "key-0"},
+		{Data: json.RawMessage(`{"id": 2, "order": 2, "at": "2025-03-03T10:00:00Z", "day": "2025-03-02"}`), Attributes: map[string]string{"index": "1"}, OrderingKey: "key-1"},
+		{Data: json.RawMessage(`{"id": 3, "order": 3, "at": "2025-03-03T10:00:00Z", "day": "2025-03-02"}`), Attributes: map[string]string{"index": "2"}, OrderingKey: "key-2"},
+		{Data: json.RawMessage(`"plain 4"`)},
+	}, messages)
+}
+
+func Test_Generator_Expand_RandomValues(t *testing.T) {
+	template := pubsub.Message{
+		Template: `{"id": "{{ uuid }}", "name": {{ json name }}, "email": "{{ email }}", "size": {{ randInt 36 46 }}, "colour": "{{ pick "red" "blue" }}", "seen": "{{ randomTime "1h" }}"}`,
+		Count:    50,
+	}
+
+	messages, err := newTestGenerator(7).Expand([]pubsub.Message{template})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, len(messages))
+
+	ids := map[string]bool{}
+	for _, message := range messages {
+		var data struct {
+			Id     string
+			Name   string
+			Email  string
+			Size   int
+			Colour string
+			Seen   time.Time
+		}
+		assert.NoError(t, json.Unmarshal(message.Data, &data))
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, data.Id)
+		assert.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, data.Name)
+		assert.Regexp(t, `^[a-z]+\.[a-z]+[0-9]+@example\.(com|org|net)$`, data.Email)
+		assert.True(t, data.Size >= 36 && data.Size <= 46)
+		assert.Contains(t, []string{"red", "blue"}, data.Colour)
+		assert.WithinRange(t, data.Seen, time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC))
+		ids[data.Id] = true
+	}
+	assert.Equal(t, 50, len(ids))
+
+	// Same seed, same messages
+	again, err := newTestGenerator(7).Expand([]pubsub.Message{template})
+	assert.NoError(t, err)
+	assert.Equal(t, messages, again)
+}
+
+func Test_Generator_Expand_Errors(t *testing.T) {
+	generator := newTestGenerator(1)
+
+	_, err := generator.Expand([]pubsub.Message{{Template: `{{ uuid `}})
+	assert.ErrorContains(t, err, "message 0: ")
+
+	_, err = generator.Expand([]pubsub.Message{{Template: `{{ .Missing }}`}})
+	assert.ErrorContains(t, err, "message 0: ")
+
+	_, err = generator.Expand([]pubsub.Message{{Template: `{}`, Data: json.RawMessage(`{}`)}})
+	assert.EqualError(t, err, "message 0: template can't be used together with data or dataBase64")
+
+	_, err = generator.Expand([]pubsub.Message{{Data: json.RawMessage(`{}`)}, {Data: json.RawMessage(`{}`), Count: 2}})
+	assert.EqualError(t, err, "message 1: count can only be used with template")
+
+	_, err = generator.Expand([]pubsub.Message{{Template: `{{ randInt 5 1 }}`}})
+	assert.ErrorContains(t, err, "randInt: max 1 is lower than min 5")
+}
@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	EMBED_START_MARKER = "<!-- pubsub-graph:start -->"
+	EMBED_END_MARKER   = "<!-- pubsub-graph:end -->"
+)
+
+// Embed replaces whatever is between the markers of a Markdown document with
+// the diagram, as a code block of its format. Mermaid code blocks are
+// rendered as diagrams by GitHub and GitLab.
+func Embed(document, diagram, format string) (string, error) {
+	start := strings.Index(document, EMBED_START_MARKER)
+	end := strings.Index(document, EMBED_END_MARKER)
+	if start < 0 || end < 0 || end < start {
+		return "", fmt.Errorf("the document needs the markers '%s' and '%s' where the diagram goes", EMBED_START_MARKER, EMBED_END_MARKER)
+	}
+
+	block := fmt.Sprintf("%s\n```%s\n%s```\n", EMBED_START_MARKER, format, diagram)
+	return document[:start] + block + document[end:], nil
+}
@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// ListProjects returns the topics, with their subscriptions, and the schemas
+// of some projects of the emulator. Their names are resource names.
+func ListProjects(client utils.ClientInterface, projectNames []string) ([]pubsub.Project, error) {
+	projects := []pubsub.Project{}
+	for _, projectName := range projectNames {
+		project := pubsub.Project{Name: projectName}
+
+		topics, err := pubsub.ListTopics(client, projectName)
+		if err != nil {
+			return nil, fmt.Errorf("can't list the topics of project '%s': %w", projectName, err)
+		}
+
+		subscriptions, err := pubsub.ListSubscriptions(client, projectName)
+		if err != nil {
+			return nil, fmt.Errorf("can't list the subscriptions of project '%s': %w", projectName, err)
+		}
+		subscriptionsByName := map[string]pubsub.Subscription{}
+		for _, subscription := range subscriptions {
+			subscriptionsByName[subscription.Name] = subscription
+		}
+
+		for _, topic := range topics {
+			subscriptionNames, err := pubsub.ListTopicSubscriptions(client, projectName, topic.Name)
+			if err != nil {
+				return nil, fmt.Errorf("can't list the subscriptions of topic '%s': %w", topic.Name, err)
+			}
+
+			topic.Subscriptions = []pubsub.Subscription{}
+			for _, subscriptionName := range subscriptionNames {
+				subscription, exists := subscriptionsByName[subscriptionName]
+				if !exists {
+					subscription = pubsub.Subscription{Name: subscriptionName}
+				}
+				topic.Subscriptions = append(topic.Subscriptions, subscription)
+			}
+			project.Topics = append(project.Topics, topic)
+		}
+
+		if project.Schemas, err = pubsub.ListSchemas(client, projectName); err != nil {
+			return nil, fmt.Errorf("can't list the schemas of project '%s': %w", projectName, err)
+		}
+
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
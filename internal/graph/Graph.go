@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+)
+
+const (
+	FORMAT_DOT     = "dot"
+	FORMAT_MERMAID = "mermaid"
+)
+
+type NodeKind string
+
+const (
+	NODE_TOPIC        NodeKind = "topic"
+	NODE_SUBSCRIPTION NodeKind = "subscription"
+	NODE_SCHEMA       NodeKind = "schema"
+	NODE_ENDPOINT     NodeKind = "endpoint"
+)
+
+type EdgeKind string
+
+const (
+	// From a topic to its subscriptions
+	EDGE_DELIVERY EdgeKind = "delivery"
+	// From a subscription to its dead letter topic
+	EDGE_DEAD_LETTER EdgeKind = "deadLetter"
+	// From a subscription to its push endpoint
+	EDGE_PUSH EdgeKind = "push"
+	// From a topic to the schema of its messages
+	EDGE_SCHEMA EdgeKind = "schema"
+)
+
+// Node is a resource of the topology. Its id is the resource name, or the URL
+// of push endpoints, and its project is empty for push endpoints.
+type Node struct {
+	Id      string
+	Label   string
+	Kind    NodeKind
+	Project string
+}
+
+type Edge struct {
+	From  string
+	To    string
+	Kind  EdgeKind
+	Label string
+}
+
+// Graph is the topology of some projects, with the nodes and edges in the
+// order of the projects, so it's rendered the same way every time.
+type Graph struct {
+	Projects []string
+	Nodes    []Node
+	Edges    []Edge
+
+	nodes map[string]bool
+}
+
+// resourceName returns the resource name of a resource given by name, as in
+// the configuration, or already by resource name, as listed by the emulator.
+func resourceName(project, collection, name string) string {
+	if strings.HasPrefix(name, "projects/") {
+		return name
+	}
+	return fmt.Sprintf("projects/%s/%s/%s", project, collection, name)
+}
+
+// splitResourceName returns the project and the name of a resource name.
+func splitResourceName(resourceName string) (string, string) {
+	parts := strings.Split(resourceName, "/")
+	if len(parts) == 4 && parts[0] == "projects" {
+		return parts[1], parts[3]
+	}
+	return "", resourceName
+}
+
+func (g *Graph) addProject(project string) {
+	for _, existing := range g.Projects {
+		if existing == project {
+			return
+		}
+	}
+	g.Projects = append(g.Projects, project)
+}
+
+// addNode adds a resource once. Resources referenced from other projects add
+// their project too.
+func (g *Graph) addNode(id string, kind NodeKind) {
+	if g.nodes[id] {
+		return
+	}
+	g.nodes[id] = true
+
+	node := Node{Id: id, Label: id, Kind: kind}
+	if kind != NODE_ENDPOINT {
+		node.Project, node.Label = splitResourceName(id)
+		if node.Project != "" {
+			g.addProject(node.Project)
+		}
+	}
+	g.Nodes = append(g.Nodes, node)
+}
+
+// Build returns the graph of the topics, subscriptions and schemas of the
+// projects, either from the configuration or listed from the emulator.
+func Build(projects []pubsub.Project) Graph {
+	g := Graph{Projects: []string{}, Nodes: []Node{}, Edges: []Edge{}, nodes: map[string]bool{}}
+
+	for _, project := range projects {
+		g.addProject(project.Name)
+
+		for _, schema := range project.Schemas {
+			name := schema.Name
+			if schema.Id != "" {
+				name = schema.Id
+			}
+			g.addNode(resourceName(project.Name, "schemas", name), NODE_SCHEMA)
+		}
+
+		for _, topic := range project.Topics {
+			topicResourceName := resourceName(project.Name, "topics", topic.Name)
+			g.addNode(topicResourceName, NODE_TOPIC)
+
+			if topic.SchemaSettings != nil {
+				schema := topic.SchemaSettings.Schema
+				if schema == "" {
+					schema = topic.SchemaSettings.LastSchemaId
+				}
+				if schema != "" {
+					schemaResourceName := resourceName(project.Name, "schemas", schema)
+					g.addNode(schemaResourceName, NODE_SCHEMA)
+					g.Edges = append(g.Edges, Edge{From: topicResourceName, To: schemaResourceName, Kind: EDGE_SCHEMA, Label: string(topic.SchemaSettings.Encoding)})
+				}
+			}
+
+			for _, subscription := range topic.Subscriptions {
+				subscriptionResourceName := resourceName(project.Name, "subscriptions", subscription.Name)
+				g.addNode(subscriptionResourceName, NODE_SUBSCRIPTION)
+				g.Edges = append(g.Edges, Edge{From: topicResourceName, To: subscriptionResourceName, Kind: EDGE_DELIVERY})
+
+				if subscription.DeadLetterPolicy != nil && subscription.DeadLetterPolicy.DeadLetterTopic != "" {
+					deadLetterTopic := pubsub.GetResourceNameForDeadLetterTopic(project.Name, subscription.DeadLetterPolicy.DeadLetterTopic)
+					g.addNode(deadLetterTopic, NODE_TOPIC)
+
+					label := "dead letter"
+					if subscription.DeadLetterPolicy.MaxDeliveryAttempts > 0 {
+						label = fmt.Sprintf("dead letter after %d attempts", subscription.DeadLetterPolicy.MaxDeliveryAttempts)
+					}
+					g.Edges = append(g.Edges, Edge{From: subscriptionResourceName, To: deadLetterTopic, Kind: EDGE_DEAD_LETTER, Label: label})
+				}
+
+				if subscription.PushConfig != nil && subscription.PushConfig.PushEndpoint != "" {
+					g.addNode(subscription.PushConfig.PushEndpoint, NODE_ENDPOINT)
+					g.Edges = append(g.Edges, Edge{From: subscriptionResourceName, To: subscription.PushConfig.PushEndpoint, Kind: EDGE_PUSH, Label: "push"})
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+// Render returns the graph in the given format.
+func (g Graph) Render(format string) (string, error) {
+	switch format {
+	case FORMAT_DOT:
+		return g.DOT(), nil
+	case FORMAT_MERMAID:
+		return g.Mermaid(), nil
+	default:
+		return "", fmt.Errorf("unknown format '%s', use %s or %s", format, FORMAT_DOT, FORMAT_MERMAID)
+	}
+}
+
+// projectNodes returns the nodes of a project, the push endpoints when empty.
+func (g Graph) projectNodes(project string) []Node {
+	nodes := []Node{}
+	for _, node := range g.Nodes {
+		if node.Project == project {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
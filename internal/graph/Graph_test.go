@@ -0,0 +1,167 @@
+package graph
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func testProjects() []pubsub.Project {
+	return []pubsub.Project{
+		{
+			Name:    "shop",
+			Schemas: []pubsub.Schema{{Name: "order"}},
+			Topics: []pubsub.Topic{
+				{
+					Name:           "orders",
+					SchemaSettings: &pubsub.SchemaSettings{Schema: "order", Encoding: "JSON"},
+					Subscriptions: []pubsub.Subscription{
+						{
+							Name:             "orders-billing",
+							DeadLetterPolicy: &pubsub.DeadLetterPolicy{DeadLetterTopic: "orders-dead", MaxDeliveryAttempts: 5},
+						},
+						{
+							Name:       "orders-webhook",
+							PushConfig: &pubsub.PushConfig{PushEndpoint: "http://localhost:3000/push"},
+						},
+					},
+				},
+				{Name: "orders-dead"},
+			},
+		},
+	}
+}
+
+func Test_Graph_Build(t *testing.T) {
+	g := Build(testProjects())
+
+	assert.Equal(t, []string{"shop"}, g.Projects)
+	assert.Equal(t, []Node{
+		{Id: "projects/shop/schemas/order", Label: "order", Kind: NODE_SCHEMA, Project: "shop"},
+		{Id: "projects/shop/topics/orders", Label: "orders", Kind: NODE_TOPIC, Project: "shop"},
+		{Id: "projects/shop/subscriptions/orders-billing", Label: "orders-billing", Kind: NODE_SUBSCRIPTION, Project: "shop"},
+		{Id: "projects/shop/topics/orders-dead", Label: "orders-dead", Kind: NODE_TOPIC, Project: "shop"},
+		{Id: "projects/shop/subscriptions/orders-webhook", Label: "orders-webhook", Kind: NODE_SUBSCRIPTION, Project: "shop"},
+		{Id: "http://localhost:3000/push", Label: "http://localhost:3000/push", Kind: NODE_ENDPOINT},
+	}, g.Nodes)
+	assert.Equal(t, []Edge{
+		{From: "projects/shop/topics/orders", To: "projects/shop/schemas/order", Kind: EDGE_SCHEMA, Label: "JSON"},
+		{From: "projects/shop/topics/orders", To: "projects/shop/subscriptions/orders-billing", Kind: EDGE_DELIVERY},
+		{From: "projects/shop/subscriptions/orders-billing", To: "projects/shop/topics/orders-dead", Kind: EDGE_DEAD_LETTER, Label: "dead letter after 5 attempts"},
+		{From: "projects/shop/topics/orders", To: "projects/shop/subscriptions/orders-webhook", Kind: EDGE_DELIVERY},
+		{From: "projects/shop/subscriptions/orders-webhook", To: "http://localhost:3000/push", Kind: EDGE_PUSH, Label: "push"},
+	}, g.Edges)
+}
+
+func Test_Graph_BuildCrossProjectDeadLetter(t *testing.T) {
+	g := Build([]pubsub.Project{{
+		Name: "shop",
+		Topics: []pubsub.Topic{{
+			Name: "orders",
+			Subscriptions: []pubsub.Subscription{{
+				Name:             "orders-billing",
+				DeadLetterPolicy: &pubsub.DeadLetterPolicy{DeadLetterTopic: "projects/ops/topics/dead-letters"},
+			}},
+		}},
+	}})
+
+	assert.Equal(t, []string{"shop", "ops"}, g.Projects)
+	assert.Equal(t, Node{Id: "projects/ops/topics/dead-letters", Label: "dead-letters", Kind: NODE_TOPIC, Project: "ops"}, g.Nodes[2])
+	assert.Equal(t, "dead letter", g.Edges[1].Label)
+}
+
+func Test_Graph_Mermaid(t *testing.T) {
+	diagram, err := Build(testProjects()).Render(FORMAT_MERMAID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `flowchart LR
+  subgraph p1["shop"]
+    n1[("order")]
+    n2["orders"]
+    n3(["orders-billing"])
+    n4["orders-dead"]
+    n5(["orders-webhook"])
+  end
+  n6>"http://localhost:3000/push"]
+  n2 -.-|"JSON"| n1
+  n2 --> n3
+  n3 -.->|"dead letter after 5 attempts"| n4
+  n2 --> n5
+  n5 ==>|"push"| n6
+`, diagram)
+}
+
+func Test_Graph_DOT(t *testing.T) {
+	diagram, err := Build(testProjects()).Render(FORMAT_DOT)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `digraph pubsub {
+  rankdir=LR;
+  node [fontname="Helvetica"];
+  edge [fontname="Helvetica", fontsize=10];
+
+  subgraph "cluster_shop" {
+    label="shop";
+    "projects/shop/schemas/order" [label="order", shape=note];
+    "projects/shop/topics/orders" [label="orders", shape=box];
+    "projects/shop/subscriptions/orders-billing" [label="orders-billing", shape=ellipse];
+    "projects/shop/topics/orders-dead" [label="orders-dead", shape=box];
+    "projects/shop/subscriptions/orders-webhook" [label="orders-webhook", shape=ellipse];
+  }
+
+  "http://localhost:3000/push" [label="http://localhost:3000/push", shape=cds];
+
+  "projects/shop/topics/orders" -> "projects/shop/schemas/order" [label="JSON", style=dotted];
+  "projects/shop/topics/orders" -> "projects/shop/subscriptions/orders-billing";
+  "projects/shop/subscriptions/orders-billing" -> "projects/shop/topics/orders-dead" [label="dead letter after 5 attempts", style=dashed, color="firebrick"];
+  "projects/shop/topics/orders" -> "projects/shop/subscriptions/orders-webhook";
+  "projects/shop/subscriptions/orders-webhook" -> "http://localhost:3000/push" [label="push", color="darkgreen"];
+}
+`, diagram)
+}
+
+func Test_Graph_UnknownFormat(t *testing.T) {
+	_, err := Build(testProjects()).Render("svg")
+	assert.EqualError(t, err, "unknown format 'svg', use dot or mermaid")
+}
+
+func Test_Graph_Embed(t *testing.T) {
+	document := "# Service\n\n<!-- pubsub-graph:start -->\nold\n<!-- pubsub-graph:end -->\n\nMore text\n"
+
+	embedded, err := Embed(document, "flowchart LR\n", FORMAT_MERMAID)
+	assert.NoError(t, err)
+	assert.Equal(t, "# Service\n\n<!-- pubsub-graph:start -->\n```mermaid\nflowchart LR\n```\n<!-- pubsub-graph:end -->\n\nMore text\n", embedded)
+
+	again, err := Embed(embedded, "flowchart LR\n", FORMAT_MERMAID)
+	assert.NoError(t, err)
+	assert.Equal(t, embedded, again)
+
+	_, err = Embed("# Service\n", "flowchart LR\n", FORMAT_MERMAID)
+	assert.Error(t, err)
+}
+
+func Test_Graph_ListProjects(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"projects/shop/topics/orders","schemaSettings":{"schema":"projects/shop/schemas/order","encoding":"JSON"}}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"projects/shop/subscriptions/orders-webhook","pushConfig":{"pushEndpoint":"http://localhost:3000/push"}}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":["projects/shop/subscriptions/orders-webhook"]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"name":"projects/shop/schemas/order","type":"AVRO"}]}`)}},
+		},
+	}
+
+	projects, err := ListProjects(mockClient, []string{"shop"})
+	assert.NoError(t, err)
+
+	g := Build(projects)
+	assert.Equal(t, []string{"shop"}, g.Projects)
+	assert.Equal(t, 4, len(g.Nodes))
+	assert.Equal(t, []Edge{
+		{From: "projects/shop/topics/orders", To: "projects/shop/schemas/order", Kind: EDGE_SCHEMA, Label: "JSON"},
+		{From: "projects/shop/topics/orders", To: "projects/shop/subscriptions/orders-webhook", Kind: EDGE_DELIVERY},
+		{From: "projects/shop/subscriptions/orders-webhook", To: "http://localhost:3000/push", Kind: EDGE_PUSH, Label: "push"},
+	}, g.Edges)
+}
@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+var dotShapes = map[NodeKind]string{
+	NODE_TOPIC:        "box",
+	NODE_SUBSCRIPTION: "ellipse",
+	NODE_SCHEMA:       "note",
+	NODE_ENDPOINT:     "cds",
+}
+
+var dotEdgeStyles = map[EdgeKind]string{
+	EDGE_DELIVERY:    "",
+	EDGE_DEAD_LETTER: `, style=dashed, color="firebrick"`,
+	EDGE_PUSH:        `, color="darkgreen"`,
+	EDGE_SCHEMA:      ", style=dotted",
+}
+
+func dotQuote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// DOT returns the graph in the Graphviz DOT language, with a cluster per
+// project.
+func (g Graph) DOT() string {
+	var builder strings.Builder
+	builder.WriteString("digraph pubsub {\n")
+	builder.WriteString("  rankdir=LR;\n")
+	builder.WriteString("  node [fontname=\"Helvetica\"];\n")
+	builder.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	writeNode := func(indentation string, node Node) {
+		fmt.Fprintf(&builder, "%s%s [label=%s, shape=%s];\n", indentation, dotQuote(node.Id), dotQuote(node.Label), dotShapes[node.Kind])
+	}
+
+	for _, project := range g.Projects {
+		fmt.Fprintf(&builder, "\n  subgraph %s {\n", dotQuote("cluster_"+project))
+		fmt.Fprintf(&builder, "    label=%s;\n", dotQuote(project))
+		for _, node := range g.projectNodes(project) {
+			writeNode("    ", node)
+		}
+		builder.WriteString("  }\n")
+	}
+
+	endpoints := g.projectNodes("")
+	if len(endpoints) > 0 {
+		builder.WriteString("\n")
+		for _, node := range endpoints {
+			writeNode("  ", node)
+		}
+	}
+
+	if len(g.Edges) > 0 {
+		builder.WriteString("\n")
+	}
+	for _, edge := range g.Edges {
+		attributes := ""
+		if edge.Label != "" {
+			attributes = "label=" + dotQuote(edge.Label)
+		}
+		attributes += dotEdgeStyles[edge.Kind]
+		attributes = strings.TrimPrefix(attributes, ", ")
+
+		fmt.Fprintf(&builder, "  %s -> %s", dotQuote(edge.From), dotQuote(edge.To))
+		if attributes != "" {
+			fmt.Fprintf(&builder, " [%s]", attributes)
+		}
+		builder.WriteString(";\n")
+	}
+
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// mermaidShapes are the opening and closing delimiters of every kind of node.
+var mermaidShapes = map[NodeKind][2]string{
+	NODE_TOPIC:        {"[", "]"},
+	NODE_SUBSCRIPTION: {"([", "])"},
+	NODE_SCHEMA:       {"[(", ")]"},
+	NODE_ENDPOINT:     {">", "]"},
+}
+
+var mermaidArrows = map[EdgeKind]string{
+	EDGE_DELIVERY:    "-->",
+	EDGE_DEAD_LETTER: "-.->",
+	EDGE_PUSH:        "==>",
+	EDGE_SCHEMA:      "-.-",
+}
+
+func mermaidQuote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, "#quot;") + `"`
+}
+
+// Mermaid returns the graph as a Mermaid flowchart, with a subgraph per
+// project. Mermaid ids can't be resource names, so nodes are numbered.
+func (g Graph) Mermaid() string {
+	ids := map[string]string{}
+	for i, node := range g.Nodes {
+		ids[node.Id] = fmt.Sprintf("n%d", i+1)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+
+	writeNode := func(indentation string, node Node) {
+		shape := mermaidShapes[node.Kind]
+		fmt.Fprintf(&builder, "%s%s%s%s%s\n", indentation, ids[node.Id], shape[0], mermaidQuote(node.Label), shape[1])
+	}
+
+	for i, project := range g.Projects {
+		fmt.Fprintf(&builder, "  subgraph p%d[%s]\n", i+1, mermaidQuote(project))
+		for _, node := range g.projectNodes(project) {
+			writeNode("    ", node)
+		}
+		builder.WriteString("  end\n")
+	}
+
+	for _, node := range g.projectNodes("") {
+		writeNode("  ", node)
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&builder, "  %s %s", ids[edge.From], mermaidArrows[edge.Kind])
+		if edge.Label != "" {
+			fmt.Fprintf(&builder, "|%s|", mermaidQuote(edge.Label))
+		}
+		fmt.Fprintf(&builder, " %s\n", ids[edge.To])
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// Value used by Pub/Sub when the resource referenced has been deleted.
+const (
+	deletedTopic  = "_deleted-topic_"
+	deletedSchema = "_deleted-schema_"
+)
+
+// GcloudFiles are the paths of the files containing the output of the
+// `gcloud pubsub {topics,subscriptions,schemas} list --format=json` commands.
+// Empty paths are ignored.
+type GcloudFiles struct {
+	Topics        string
+	Subscriptions string
+	Schemas       string
+}
+
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.topics#SchemaSettings
+type gcloudSchemaSettings struct {
+	Schema          string                `json:"schema"`
+	Encoding        pubsub.SchemaEncoding `json:"encoding"`
+	FirstRevisionId string                `json:"firstRevisionId"`
+	LastRevisionId  string                `json:"lastRevisionId"`
+}
+
+type gcloudTopic struct {
+	pubsub.Topic
+	SchemaSettings *gcloudSchemaSettings `json:"schemaSettings"`
+}
+
+type gcloudSubscription struct {
+	pubsub.Subscription
+	Topic string `json:"topic"`
+}
+
+// lastSegment returns the short name of a resource name like
+// projects/{project}/topics/{topic}.
+func lastSegment(resourceName string) string {
+	return resourceName[strings.LastIndex(resourceName, "/")+1:]
+}
+
+// projectOf returns the project of a resource name like
+// projects/{project}/topics/{topic}, or an empty string for other values.
+func projectOf(resourceName string) string {
+	parts := strings.Split(resourceName, "/")
+	if len(parts) != 4 || parts[0] != "projects" {
+		return ""
+	}
+	return parts[1]
+}
+
+func readGcloudList(fileReader utils.FileReaderInterface, filePath string, target interface{}) error {
+	if filePath == "" {
+		return nil
+	}
+
+	content, err := fileReader.Read(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("invalid gcloud output in '%s': %w", filePath, err)
+	}
+
+	return nil
+}
+
+// FromGcloud converts the JSON output of the gcloud CLI into a Configuration
+// with a single project, rewriting every resource name to that project. The
+// only exception are the dead letter topics of other projects, which are
+// added to the configuration in their own project.
+func FromGcloud(fileReader utils.FileReaderInterface, project string, files GcloudFiles) (internal.Configuration, error) {
+	if project == "" {
+		return internal.Configuration{}, fmt.Errorf("a target project is required")
+	}
+
+	var gcloudSchemas []pubsub.Schema
+	if err := readGcloudList(fileReader, files.Schemas, &gcloudSchemas); err != nil {
+		return internal.Configuration{}, err
+	}
+
+	var gcloudTopics []gcloudTopic
+	if err := readGcloudList(fileReader, files.Topics, &gcloudTopics); err != nil {
+		return internal.Configuration{}, err
+	}
+
+	var gcloudSubscriptions []gcloudSubscription
+	if err := readGcloudList(fileReader, files.Subscriptions, &gcloudSubscriptions); err != nil {
+		return internal.Configuration{}, err
+	}
+
+	targetProject := pubsub.Project{
+		Name:    project,
+		Topics:  []pubsub.Topic{},
+		Schemas: []pubsub.Schema{},
+	}
+
+	schemaIds := map[string]bool{}
+	for _, gcloudSchema := range gcloudSchemas {
+		schemaId := lastSegment(gcloudSchema.Name)
+		schemaIds[schemaId] = true
+
+		targetProject.Schemas = append(targetProject.Schemas, pubsub.Schema{
+			Id:         schemaId,
+			Name:       schemaId,
+			Type:       gcloudSchema.Type,
+			Definition: gcloudSchema.Definition,
+		})
+	}
+
+	topicIndexes := map[string]int{}
+	for _, gcloudTopic := range gcloudTopics {
+		topic := gcloudTopic.Topic
+		topic.Name = lastSegment(gcloudTopic.Name)
+		topic.State = ""
+		topic.Subscriptions = []pubsub.Subscription{}
+
+		if gcloudTopic.SchemaSettings != nil {
+			schemaId := lastSegment(gcloudTopic.SchemaSettings.Schema)
+
+			switch {
+			case gcloudTopic.SchemaSettings.Schema == deletedSchema:
+				Llog.Warn(fmt.Sprintf("Topic '%s' references a deleted schema, ignoring its schema settings", topic.Name))
+			case !schemaIds[schemaId]:
+				Llog.Warn(fmt.Sprintf("Topic '%s' references the schema '%s' which was not imported, ignoring its schema settings", topic.Name, schemaId))
+			default:
+				if gcloudTopic.SchemaSettings.FirstRevisionId != "" || gcloudTopic.SchemaSettings.LastRevisionId != "" {
+					Llog.Warn(fmt.Sprintf("Topic '%s' restricts the schema revisions, only the last revision is imported", topic.Name))
+				}
+				topic.SchemaSettings = &pubsub.SchemaSettings{
+					Schema:   schemaId,
+					Encoding: gcloudTopic.SchemaSettings.Encoding,
+				}
+			}
+		}
+
+		topicIndexes[topic.Name] = len(targetProject.Topics)
+		targetProject.Topics = append(targetProject.Topics, topic)
+	}
+
+	foreignProjects := []pubsub.Project{}
+	foreignProjectIndexes := map[string]int{}
+	foreignTopics := map[string]bool{}
+	for _, gcloudSubscription := range gcloudSubscriptions {
+		subscription := gcloudSubscription.Subscription
+		subscription.Name = lastSegment(gcloudSubscription.Name)
+		if subscription.DeadLetterPolicy != nil {
+			deadLetterTopic := subscription.DeadLetterPolicy.DeadLetterTopic
+			deadLetterProject := projectOf(deadLetterTopic)
+
+			switch deadLetterProject {
+			case "", projectOf(gcloudSubscription.Name), project:
+				subscription.DeadLetterPolicy.DeadLetterTopic = lastSegment(deadLetterTopic)
+			default:
+				// Dead letter topics of other projects keep their project,
+				// which is added so the topic exists in the emulator
+				if !foreignTopics[deadLetterTopic] {
+					Llog.Warn(fmt.Sprintf("Subscription '%s' uses the dead letter topic '%s' of another project, adding it to the configuration", subscription.Name, deadLetterTopic))
+					foreignTopics[deadLetterTopic] = true
+
+					projectIndex, exists := foreignProjectIndexes[deadLetterProject]
+					if !exists {
+						projectIndex = len(foreignProjects)
+						foreignProjectIndexes[deadLetterProject] = projectIndex
+						foreignProjects = append(foreignProjects, pubsub.Project{
+							Name:    deadLetterProject,
+							Topics:  []pubsub.Topic{},
+							Schemas: []pubsub.Schema{},
+						})
+					}
+					foreignProjects[projectIndex].Topics = append(foreignProjects[projectIndex].Topics, pubsub.Topic{
+						Name:          lastSegment(deadLetterTopic),
+						Subscriptions: []pubsub.Subscription{},
+					})
+				}
+			}
+		}
+		if subscription.PushConfig.IsPull() {
+			subscription.PushConfig = nil
+		}
+
+		if gcloudSubscription.Topic == deletedTopic {
+			Llog.Warn(fmt.Sprintf("Subscription '%s' belongs to a deleted topic, ignoring it", subscription.Name))
+			continue
+		}
+
+		topicName := lastSegment(gcloudSubscription.Topic)
+		topicIndex, exists := topicIndexes[topicName]
+		if !exists {
+			Llog.Warn(fmt.Sprintf("Subscription '%s' belongs to the topic '%s' which was not imported, adding it", subscription.Name, gcloudSubscription.Topic))
+			topicIndex = len(targetProject.Topics)
+			topicIndexes[topicName] = topicIndex
+			targetProject.Topics = append(targetProject.Topics, pubsub.Topic{
+				Name:          topicName,
+				Subscriptions: []pubsub.Subscription{},
+			})
+		}
+
+		targetProject.Topics[topicIndex].Subscriptions = append(targetProject.Topics[topicIndex].Subscriptions, subscription)
+	}
+
+	return internal.Configuration{
+		Host:                       "localhost:8085",
+		StartTimeoutMs:             30_000,
+		TimeBetweenStartupChecksMs: 200,
+		Projects:                   append([]pubsub.Project{targetProject}, foreignProjects...),
+	}, nil
+}
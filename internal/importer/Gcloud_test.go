@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGcloudFileReaderMock(files map[string]string) *utils.FileReaderMock {
+	return &utils.FileReaderMock{
+		ReadFunc: func(filePath string) ([]byte, error) {
+			content, exists := files[filePath]
+			if !exists {
+				return nil, fmt.Errorf("file '%s' not found", filePath)
+			}
+			return []byte(content), nil
+		},
+	}
+}
+
+func Test_Gcloud_Import(t *testing.T) {
+	mockReader := newGcloudFileReaderMock(map[string]string{
+		"schemas.json": `[
+      {
+        "name": "projects/staging/schemas/product",
+        "type": "AVRO",
+        "definition": "{\"type\":\"string\"}",
+        "revisionId": "a1b2c3"
+      }
+    ]`,
+		"topics.json": `[
+      {
+        "name": "projects/staging/topics/products",
+        "labels": {"owner": "catalog"},
+        "schemaSettings": {"schema": "projects/staging/schemas/product", "encoding": "JSON"}
+      }
+    ]`,
+		"subscriptions.json": `[
+      {
+        "name": "projects/staging/subscriptions/products.indexer",
+        "topic": "projects/staging/topics/products",
+        "labels": {"team": "search"},
+        "pushConfig": {},
+        "deadLetterPolicy": {"deadLetterTopic": "projects/staging/topics/products.dead-letter", "maxDeliveryAttempts": 5}
+      },
+      {
+        "name": "projects/staging/subscriptions/orphan",
+        "topic": "_deleted-topic_"
+      }
+    ]`,
+	})
+
+	config, err := FromGcloud(mockReader, "local", GcloudFiles{
+		Topics:        "topics.json",
+		Subscriptions: "subscriptions.json",
+		Schemas:       "schemas.json",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(config.Projects))
+
+	project := config.Projects[0]
+	assert.Equal(t, "local", project.Name)
+	assert.Equal(t, 1, len(project.Schemas))
+	assert.Equal(t, "product", project.Schemas[0].Id)
+	assert.Equal(t, "AVRO", project.Schemas[0].Type)
+	assert.Equal(t, "", project.Schemas[0].RevisionId)
+
+	assert.Equal(t, 1, len(project.Topics))
+	assert.Equal(t, "products", project.Topics[0].Name)
+	assert.Equal(t, "catalog", project.Topics[0].Labels["owner"])
+	assert.Equal(t, &pubsub.SchemaSettings{
+		Schema:   "product",
+		Encoding: pubsub.SCHEMA_ENCODING_JSON,
+	}, project.Topics[0].SchemaSettings)
+
+	assert.Equal(t, 1, len(project.Topics[0].Subscriptions))
+	assert.Equal(t, "products.indexer", project.Topics[0].Subscriptions[0].Name)
+	assert.Equal(t, "search", project.Topics[0].Subscriptions[0].Labels["team"])
+	assert.Equal(t, "products.dead-letter", project.Topics[0].Subscriptions[0].DeadLetterPolicy.DeadLetterTopic)
+	assert.Nil(t, project.Topics[0].Subscriptions[0].PushConfig)
+}
+
+func Test_Gcloud_Import_SubscriptionOfMissingTopic(t *testing.T) {
+	mockReader := newGcloudFileReaderMock(map[string]string{
+		"subscriptions.json": `[
+      {
+        "name": "projects/staging/subscriptions/audit",
+        "topic": "projects/shared/topics/events",
+        "deadLetterPolicy": {"deadLetterTopic": "projects/shared/topics/events.dead-letter"}
+      },
+      {
+        "name": "projects/staging/subscriptions/audit.replica",
+        "topic": "projects/shared/topics/events",
+        "deadLetterPolicy": {"deadLetterTopic": "projects/shared/topics/events.dead-letter"}
+      }
+    ]`,
+	})
+
+	config, err := FromGcloud(mockReader, "local", GcloudFiles{Subscriptions: "subscriptions.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(config.Projects[0].Topics))
+	assert.Equal(t, "events", config.Projects[0].Topics[0].Name)
+	assert.Equal(t, "audit", config.Projects[0].Topics[0].Subscriptions[0].Name)
+	assert.Equal(t, "projects/shared/topics/events.dead-letter", config.Projects[0].Topics[0].Subscriptions[0].DeadLetterPolicy.DeadLetterTopic)
+
+	// The dead letter topic of another project is provisioned too
+	assert.Equal(t, 2, len(config.Projects))
+	assert.Equal(t, "shared", config.Projects[1].Name)
+	assert.Equal(t, []pubsub.Topic{{Name: "events.dead-letter", Subscriptions: []pubsub.Subscription{}}}, config.Projects[1].Topics)
+}
+
+func Test_Gcloud_Import_InvalidJson(t *testing.T) {
+	mockReader := newGcloudFileReaderMock(map[string]string{"topics.json": `{"name":`})
+
+	_, err := FromGcloud(mockReader, "local", GcloudFiles{Topics: "topics.json"})
+	assert.Error(t, err)
+}
+
+func Test_Gcloud_Import_WithoutProject(t *testing.T) {
+	_, err := FromGcloud(newGcloudFileReaderMock(nil), "", GcloudFiles{})
+	assert.Error(t, err)
+}
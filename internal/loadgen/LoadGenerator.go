@@ -0,0 +1,182 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+)
+
+// PublishFunc publishes a message to a topic.
+type PublishFunc func(topic string, message pubsub.Message) error
+
+// MessageFunc returns the message number index, starting at 0. It's never
+// called concurrently.
+type MessageFunc func(index int) (pubsub.Message, error)
+
+type Options struct {
+	// Topics the messages are published to, in turns.
+	Topics []string
+	// Messages per second, without limit when 0.
+	Rate float64
+	// Messages that can be published at once above the rate, after a pause.
+	Burst int
+	// Time publishing, until the context is done when 0.
+	Duration time.Duration
+	// Messages to publish, without limit when 0.
+	Messages int
+	// Goroutines publishing at the same time.
+	Publishers int
+}
+
+type Report struct {
+	Published int
+	Failed    int
+	// First error found publishing, if any.
+	FirstError error
+	Elapsed    time.Duration
+	// Messages published per second.
+	Throughput float64
+	// Publish latencies of the messages published.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf(
+		"published %d messages (%d failed) in %s: %.1f msg/s, latency p50 %s, p90 %s, p99 %s, max %s",
+		r.Published, r.Failed, r.Elapsed.Round(time.Millisecond), r.Throughput,
+		r.P50.Round(time.Microsecond), r.P90.Round(time.Microsecond), r.P99.Round(time.Microsecond), r.Max.Round(time.Microsecond),
+	)
+}
+
+// Run publishes messages with the given options until the duration passes,
+// every message is published or the context is done. The first error
+// building a message stops it, while publish errors are counted.
+func Run(ctx context.Context, options Options, nextMessage MessageFunc, publish PublishFunc) (Report, error) {
+	if len(options.Topics) == 0 {
+		return Report{}, fmt.Errorf("at least a topic is required")
+	}
+	if options.Rate < 0 {
+		return Report{}, fmt.Errorf("invalid rate %g", options.Rate)
+	}
+	if options.Publishers < 1 {
+		options.Publishers = 1
+	}
+
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limiter := newTokenBucket(options.Rate, options.Burst)
+
+	var (
+		mutex      sync.Mutex
+		next       int
+		latencies  []time.Duration
+		failed     int
+		firstError error
+		buildError error
+	)
+
+	// nextJob returns the topic and message to publish, false when done
+	nextJob := func() (string, pubsub.Message, bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if ctx.Err() != nil || (options.Messages > 0 && next >= options.Messages) {
+			return "", pubsub.Message{}, false
+		}
+
+		message, err := nextMessage(next)
+		if err != nil {
+			buildError = fmt.Errorf("message %d: %w", next, err)
+			cancel()
+			return "", pubsub.Message{}, false
+		}
+		topic := options.Topics[next%len(options.Topics)]
+		next++
+		return topic, message, true
+	}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for publisher := 0; publisher < options.Publishers; publisher++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := limiter.wait(ctx); err != nil {
+					return
+				}
+
+				topic, message, ok := nextJob()
+				if !ok {
+					return
+				}
+
+				publishStart := time.Now()
+				err := publish(topic, message)
+				latency := time.Since(publishStart)
+
+				mutex.Lock()
+				if err != nil {
+					failed++
+					if firstError == nil {
+						firstError = err
+					}
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if buildError != nil {
+		return Report{}, buildError
+	}
+
+	report := Report{
+		Published:  len(latencies),
+		Failed:     failed,
+		FirstError: firstError,
+		Elapsed:    time.Since(start),
+	}
+	if report.Elapsed > 0 {
+		report.Throughput = float64(report.Published) / report.Elapsed.Seconds()
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50 = percentile(latencies, 50)
+	report.P90 = percentile(latencies, 90)
+	report.P99 = percentile(latencies, 99)
+	if len(latencies) > 0 {
+		report.Max = latencies[len(latencies)-1]
+	}
+
+	return report, nil
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,87 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+)
+
+func indexMessage(index int) (pubsub.Message, error) {
+	return pubsub.Message{Data: json.RawMessage(fmt.Sprint(index))}, nil
+}
+
+func Test_LoadGenerator_Run(t *testing.T) {
+	var mutex sync.Mutex
+	published := map[string]int{}
+
+	report, err := Run(context.Background(), Options{Topics: []string{"a", "b"}, Messages: 10, Publishers: 3}, indexMessage,
+		func(topic string, message pubsub.Message) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			published[topic]++
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, report.Published)
+	assert.Equal(t, 0, report.Failed)
+	assert.Equal(t, map[string]int{"a": 5, "b": 5}, published)
+	assert.True(t, report.P50 <= report.P90 && report.P90 <= report.P99 && report.P99 <= report.Max)
+}
+
+func Test_LoadGenerator_Run_Rate(t *testing.T) {
+	report, err := Run(context.Background(), Options{Topics: []string{"a"}, Rate: 50, Messages: 11, Publishers: 4}, indexMessage,
+		func(topic string, message pubsub.Message) error { return nil },
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 11, report.Published)
+	// The first message is published right away, the other 10 at 50 msg/s
+	assert.GreaterOrEqual(t, report.Elapsed, 190*time.Millisecond)
+
+	report, err = Run(context.Background(), Options{Topics: []string{"a"}, Rate: 10, Duration: 150 * time.Millisecond}, indexMessage,
+		func(topic string, message pubsub.Message) error { return nil },
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Published)
+}
+
+func Test_LoadGenerator_Run_Errors(t *testing.T) {
+	report, err := Run(context.Background(), Options{Topics: []string{"a"}, Messages: 4}, indexMessage,
+		func(topic string, message pubsub.Message) error {
+			if string(message.Data) == "1" {
+				return fmt.Errorf("rejected")
+			}
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Published)
+	assert.Equal(t, 1, report.Failed)
+	assert.EqualError(t, report.FirstError, "rejected")
+
+	_, err = Run(context.Background(), Options{Topics: []string{"a"}, Messages: 4},
+		func(index int) (pubsub.Message, error) { return pubsub.Message{}, fmt.Errorf("invalid template") },
+		func(topic string, message pubsub.Message) error { return nil },
+	)
+	assert.EqualError(t, err, "message 0: invalid template")
+
+	_, err = Run(context.Background(), Options{}, indexMessage, func(topic string, message pubsub.Message) error { return nil })
+	assert.Error(t, err)
+}
+
+func Test_LoadGenerator_Percentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 1*time.Millisecond, percentile(latencies[:1], 99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+}
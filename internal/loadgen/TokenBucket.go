@@ -0,0 +1,58 @@
+package loadgen
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits the messages published to rate per second, allowing
+// bursts of up to burst messages after a pause.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	// Starts with a single token, so the first second doesn't exceed the rate
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: 1, last: time.Now()}
+}
+
+// wait blocks until a message can be published or the context is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b.rate == 0 {
+		return ctx.Err()
+	}
+
+	for {
+		b.mutex.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mutex.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mutex.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
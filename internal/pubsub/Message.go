@@ -0,0 +1,275 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// Message is a message to publish to a topic, as declared in the
+// configuration. The payload is either Data, used as is when it's a JSON
+// string and as compact JSON otherwise, or DataBase64 for binary payloads.
+type Message struct {
+	Data        json.RawMessage   `json:"data,omitempty"`
+	DataBase64  string            `json:"dataBase64,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+
+	// Template generating the data of Count messages, expanded when loading
+	// the configuration.
+	Template string `json:"template,omitempty"`
+	Count    int    `json:"count,omitempty"`
+}
+
+// Payload returns the bytes published as the data of the message.
+func (m Message) Payload() ([]byte, error) {
+	if m.Template != "" {
+		return nil, fmt.Errorf("the template of the message is not expanded")
+	}
+
+	if m.DataBase64 != "" {
+		if len(m.Data) > 0 {
+			return nil, fmt.Errorf("data and dataBase64 can't be used together")
+		}
+		payload, err := base64.StdEncoding.DecodeString(m.DataBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dataBase64: %w", err)
+		}
+		return payload, nil
+	}
+
+	if len(m.Data) == 0 {
+		return []byte{}, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(m.Data, &text); err == nil {
+		return []byte(text), nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, m.Data); err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+	return compact.Bytes(), nil
+}
+
+// NewMessage returns a message whose payload is exactly the given one: JSON
+// (other than strings) already compact as data, text as a JSON string and
+// anything else in base64.
+func NewMessage(payload []byte) Message {
+	var compact bytes.Buffer
+	if len(payload) > 0 && payload[0] != '"' && json.Compact(&compact, payload) == nil && bytes.Equal(compact.Bytes(), payload) {
+		return Message{Data: json.RawMessage(payload)}
+	}
+	if IsText(payload) {
+		data, _ := json.Marshal(string(payload))
+		return Message{Data: data}
+	}
+	return Message{DataBase64: base64.StdEncoding.EncodeToString(payload)}
+}
+
+// IsText reports whether the payload is UTF-8 text without control
+// characters other than whitespace, which are only expected in binary
+// payloads.
+func IsText(payload []byte) bool {
+	if !utf8.Valid(payload) {
+		return false
+	}
+	for _, r := range string(payload) {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/PubsubMessage
+type publishMessageBody struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// Limits of a publish request of Pub/Sub.
+const (
+	maxPublishBatchMessages = 1000
+	maxPublishBatchBytes    = 10 * 1000 * 1000
+)
+
+// Publish publishes the messages to a topic, returning the ids the emulator
+// assigned to them. They are sent in batches within the limits of a publish
+// request, in order; a failed batch stops the rest. Nothing is sent when
+// there are no messages.
+func Publish(client utils.ClientInterface, project, topicResourceName string, messages []Message) ([]string, error) {
+	batch := []json.RawMessage{}
+	batchBytes := 0
+	messageIds := []string{}
+
+	for i, message := range messages {
+		payload, err := message.Payload()
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		rawMessage, err := json.Marshal(publishMessageBody{
+			Data:        base64.StdEncoding.EncodeToString(payload),
+			Attributes:  message.Attributes,
+			OrderingKey: message.OrderingKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// A comma separates every message
+		if len(batch) == maxPublishBatchMessages || (len(batch) > 0 && batchBytes+len(rawMessage)+1 > maxPublishBatchBytes) {
+			ids, err := publishBatch(client, topicResourceName, batch)
+			if err != nil {
+				return messageIds, err
+			}
+			messageIds = append(messageIds, ids...)
+			batch, batchBytes = []json.RawMessage{}, 0
+		}
+		batch = append(batch, rawMessage)
+		batchBytes += len(rawMessage) + 1
+	}
+
+	// Pub/Sub rejects a publish request without messages
+	if len(batch) == 0 {
+		return messageIds, nil
+	}
+
+	ids, err := publishBatch(client, topicResourceName, batch)
+	if err != nil {
+		return messageIds, err
+	}
+	return append(messageIds, ids...), nil
+}
+
+func publishBatch(client utils.ClientInterface, topicResourceName string, messages []json.RawMessage) ([]string, error) {
+	rawBody, err := json.Marshal(map[string][]json.RawMessage{"messages": messages})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Post(topicResourceName+":publish", rawBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error publishing to topic: status code %d: %s", response.StatusCode, string(response.Body))
+	}
+
+	var publishResponse struct {
+		MessageIds []string `json:"messageIds"`
+	}
+	if err := json.Unmarshal(response.Body, &publishResponse); err != nil {
+		return nil, err
+	}
+
+	return publishResponse.MessageIds, nil
+}
+
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/PubsubMessage
+type PubsubMessage struct {
+	Data        []byte            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	MessageId   string            `json:"messageId"`
+	PublishTime string            `json:"publishTime"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.subscriptions/pull#ReceivedMessage
+type ReceivedMessage struct {
+	AckId           string        `json:"ackId"`
+	Message         PubsubMessage `json:"message"`
+	DeliveryAttempt int           `json:"deliveryAttempt,omitempty"`
+}
+
+// Pull returns up to maxMessages messages of a subscription, without waiting
+// when there are none.
+func Pull(client utils.ClientInterface, project, subscriptionResourceName string, maxMessages int) ([]ReceivedMessage, error) {
+	type PullBody struct {
+		ReturnImmediately bool `json:"returnImmediately"`
+		MaxMessages       int  `json:"maxMessages"`
+	}
+
+	rawBody, err := json.Marshal(PullBody{ReturnImmediately: true, MaxMessages: maxMessages})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Post(subscriptionResourceName+":pull", rawBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error pulling from subscription: status code %d", response.StatusCode)
+	}
+
+	var pullResponse struct {
+		ReceivedMessages []ReceivedMessage `json:"receivedMessages"`
+	}
+	if err := json.Unmarshal(response.Body, &pullResponse); err != nil {
+		return nil, err
+	}
+
+	if pullResponse.ReceivedMessages == nil {
+		return []ReceivedMessage{}, nil
+	}
+	return pullResponse.ReceivedMessages, nil
+}
+
+// Acknowledge acknowledges the messages pulled from a subscription, so they
+// are not delivered again.
+func Acknowledge(client utils.ClientInterface, project, subscriptionResourceName string, ackIds []string) error {
+	type AcknowledgeBody struct {
+		AckIds []string `json:"ackIds"`
+	}
+
+	rawBody, err := json.Marshal(AcknowledgeBody{AckIds: ackIds})
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(subscriptionResourceName+":acknowledge", rawBody)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error acknowledging messages: status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// ModifyAckDeadline changes the deadline of the messages pulled from a
+// subscription. A deadline of 0 makes them available again right away.
+func ModifyAckDeadline(client utils.ClientInterface, project, subscriptionResourceName string, ackIds []string, ackDeadlineSeconds int) error {
+	type ModifyAckDeadlineBody struct {
+		AckIds             []string `json:"ackIds"`
+		AckDeadlineSeconds int      `json:"ackDeadlineSeconds"`
+	}
+
+	rawBody, err := json.Marshal(ModifyAckDeadlineBody{AckIds: ackIds, AckDeadlineSeconds: ackDeadlineSeconds})
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(subscriptionResourceName+":modifyAckDeadline", rawBody)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error modifying the ack deadline: status code %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package pubsub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Message_Payload(t *testing.T) {
+	payload, err := Message{Data: json.RawMessage(`{ "sku": 42 }`)}.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"sku":42}`, string(payload))
+
+	payload, err = Message{Data: json.RawMessage(`"plain text"`)}.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, "plain text", string(payload))
+
+	payload, err = Message{DataBase64: "AAE="}.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x01}, payload)
+
+	_, err = Message{Data: json.RawMessage(`"a"`), DataBase64: "AAE="}.Payload()
+	assert.Error(t, err)
+}
+
+func Test_Message_Publish(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1","2"]}`)}, Error: nil},
+		},
+	}
+
+	messageIds, err := Publish(mockClient, "test-project", "projects/test-project/topics/test-topic", []Message{
+		{Data: json.RawMessage(`"hello"`), Attributes: map[string]string{"origin": "seed"}},
+		{DataBase64: "AAE=", OrderingKey: "key"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, messageIds)
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPost, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/topics/test-topic:publish", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"messages":[{"data":"aGVsbG8=","attributes":{"origin":"seed"}},{"data":"AAE=","orderingKey":"key"}]}`, string(mockClient.RequestHistory[0].Body))
+}
+
+func Test_Message_Publish_InBatches(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["2"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["3"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["4"]}`)}, Error: nil},
+		},
+	}
+
+	messages := []Message{}
+	for i := 0; i < 2500; i++ {
+		messages = append(messages, Message{Data: json.RawMessage(`"hello"`)})
+	}
+	// 4 MB each once encoded, only two of them fit in a request
+	large := Message{DataBase64: base64.StdEncoding.EncodeToString(make([]byte, 3*1000*1000))}
+	messages = append(messages, large, large, large)
+
+	messageIds, err := Publish(mockClient, "test-project", "projects/test-project/topics/test-topic", messages)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, messageIds)
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+
+	counts := []int{}
+	for _, request := range mockClient.RequestHistory {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		assert.NoError(t, json.Unmarshal(request.Body, &body))
+		assert.LessOrEqual(t, len(request.Body), maxPublishBatchBytes)
+		counts = append(counts, len(body.Messages))
+	}
+	assert.Equal(t, []int{1000, 1000, 502, 1}, counts)
+}
+
+func Test_Message_Publish_WithoutMessages(t *testing.T) {
+	mockClient := &utils.MockClient{}
+
+	messageIds, err := Publish(mockClient, "test-project", "projects/test-project/topics/test-topic", []Message{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, messageIds)
+	assert.Equal(t, 0, len(mockClient.RequestHistory))
+}
+
+func Test_Message_Publish_Rejected(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusBadRequest}, Error: nil},
+		},
+	}
+
+	_, err := Publish(mockClient, "test-project", "projects/test-project/topics/test-topic", []Message{{Data: json.RawMessage(`"hello"`)}})
+	assert.Error(t, err)
+}
+
+func Test_Message_Pull(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"aGVsbG8=","messageId":"1","publishTime":"2024-01-01T00:00:00Z"}}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	received, err := Pull(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, "a1", received[0].AckId)
+	assert.Equal(t, []byte("hello"), received[0].Message.Data)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:pull", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"returnImmediately":true,"maxMessages":5}`, string(mockClient.RequestHistory[0].Body))
+
+	received, err = Pull(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []ReceivedMessage{}, received)
+}
+
+func Test_Message_AcknowledgeAndModifyAckDeadline(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusNotFound}, Error: nil},
+		},
+	}
+
+	subscriptionResourceName := "projects/test-project/subscriptions/test-subscription"
+	assert.NoError(t, Acknowledge(mockClient, "test-project", subscriptionResourceName, []string{"a1"}))
+	assert.Equal(t, subscriptionResourceName+":acknowledge", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"ackIds":["a1"]}`, string(mockClient.RequestHistory[0].Body))
+
+	assert.NoError(t, ModifyAckDeadline(mockClient, "test-project", subscriptionResourceName, []string{"a1"}, 0))
+	assert.Equal(t, subscriptionResourceName+":modifyAckDeadline", mockClient.RequestHistory[1].Path)
+	assert.JSONEq(t, `{"ackIds":["a1"],"ackDeadlineSeconds":0}`, string(mockClient.RequestHistory[1].Body))
+
+	assert.Error(t, Acknowledge(mockClient, "test-project", subscriptionResourceName, []string{"a1"}))
+}
+
+func Test_Message_NewMessage(t *testing.T) {
+	assert.Equal(t, Message{Data: json.RawMessage(`{"a":1}`)}, NewMessage([]byte(`{"a":1}`)))
+	assert.Equal(t, Message{Data: json.RawMessage(`"{ \"a\": 1 }"`)}, NewMessage([]byte(`{ "a": 1 }`)))
+	assert.Equal(t, Message{Data: json.RawMessage(`"\"quoted\""`)}, NewMessage([]byte(`"quoted"`)))
+	assert.Equal(t, Message{DataBase64: "CFM="}, NewMessage([]byte{0x08, 'S'}))
+}
@@ -0,0 +1,19 @@
+package pubsub
+
+import (
+	"net/url"
+	"strings"
+)
+
+// withPageToken adds the token of the page to request to a list URL. The
+// first page has no token.
+func withPageToken(listUrl, pageToken string) string {
+	if pageToken == "" {
+		return listUrl
+	}
+	separator := "?"
+	if strings.Contains(listUrl, "?") {
+		separator = "&"
+	}
+	return listUrl + separator + "pageToken=" + url.QueryEscape(pageToken)
+}
@@ -2,19 +2,42 @@ package pubsub
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
 )
 
 type Schema struct {
 	Id                 string `json:"id,omitempty"`
 	Name               string `json:"name"`
 	Type               string `json:"type"`
-	Definition         string `json:"definition"`
+	Definition         string `json:"definition,omitempty"`
 	RevisionId         string `json:"revisionId,omitempty"`
 	RevisionCreateTime string `json:"revisionCreateTime,omitempty"`
+
+	// DefinitionFile is an .avsc or .proto file to read the definition from,
+	// relative to the configuration file.
+	DefinitionFile string `json:"definitionFile,omitempty"`
+
+	// Revisions are the definitions committed to the schema, oldest first.
+	// When present, Definition and DefinitionFile are not used.
+	Revisions []SchemaRevision `json:"revisions,omitempty"`
+
+	// Compatibility required between consecutive revisions (BACKWARD,
+	// FORWARD, FULL or NONE), overriding the one of the configuration.
+	Compatibility string `json:"compatibility,omitempty"`
+}
+
+// SchemaRevision is a definition committed to a schema. The emulator assigns
+// the revision id when committed, so revisions are referenced from the
+// configuration by their alias.
+type SchemaRevision struct {
+	Alias          string `json:"alias"`
+	Definition     string `json:"definition,omitempty"`
+	DefinitionFile string `json:"definitionFile,omitempty"`
 }
 
 // String returns a JSON string representation of the Schema.
@@ -26,41 +49,163 @@ func (s *Schema) String() string {
 	return string(jsonBytes)
 }
 
-func CreateSchema(client utils.ClientInterface, project, schemaId, name, schemaType, definition string) error {
-	type CreateSchemaRequest struct {
-		Name       string `json:"name"`
-		Type       string `json:"type"`
-		Definition string `json:"definition"`
+// SchemaRevisions returns the revisions of the schema, a schema without
+// revisions has a single one with its definition and no alias.
+func (s Schema) SchemaRevisions() []SchemaRevision {
+	if len(s.Revisions) > 0 {
+		return s.Revisions
 	}
+	return []SchemaRevision{{Definition: s.Definition, DefinitionFile: s.DefinitionFile}}
+}
 
-	body, err := json.Marshal(
-		CreateSchemaRequest{
-			Name:       GetResourceNameForSchema(project, name),
-			Type:       schemaType,
-			Definition: definition,
-		},
-	)
-	if err != nil {
-		return err
+// ErrSchemaAlreadyExists is returned by CreateSchema when the schema exists.
+var ErrSchemaAlreadyExists = errors.New("schema already exists")
+
+// schemaBody is the schema as sent to the REST API.
+type schemaBody struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Definition string `json:"definition"`
+}
+
+func decodeSchema(response utils.Response) (Schema, error) {
+	var schema Schema
+	if len(response.Body) == 0 {
+		return schema, nil
 	}
+	if err := json.Unmarshal(response.Body, &schema); err != nil {
+		return Schema{}, err
+	}
+	return schema, nil
+}
 
-	prettyBody, err := json.MarshalIndent(
-		CreateSchemaRequest{
+// CreateSchema creates the schema with its first revision, returning the
+// created revision.
+func CreateSchema(client utils.ClientInterface, project, schemaId, schemaType, definition string) (Schema, error) {
+	body, err := json.Marshal(
+		schemaBody{
 			Name:       GetResourceNameForSchema(project, schemaId),
 			Type:       schemaType,
 			Definition: definition,
 		},
-		"", "  ",
 	)
 	if err != nil {
-		return err
+		return Schema{}, err
 	}
-	fmt.Printf("Creating schema with body:\n%s\n", string(prettyBody))
+
+	Llog.Debug(fmt.Sprintf("Creating schema '%s' with body: %s", schemaId, string(body)))
 
 	response, err := client.Post(
 		fmt.Sprintf("projects/%s/schemas?schemaId=%s", project, schemaId),
 		body,
 	)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return decodeSchema(response)
+	case http.StatusConflict:
+		return Schema{}, ErrSchemaAlreadyExists
+	default:
+		return Schema{}, fmt.Errorf("unexpected status code %d in CreateSchema", response.StatusCode)
+	}
+}
+
+// CommitSchema commits a new revision to an existing schema, returning the
+// committed revision.
+func CommitSchema(client utils.ClientInterface, project, schemaId, schemaType, definition string) (Schema, error) {
+	body, err := json.Marshal(
+		struct {
+			Schema schemaBody `json:"schema"`
+		}{
+			Schema: schemaBody{
+				Name:       GetResourceNameForSchema(project, schemaId),
+				Type:       schemaType,
+				Definition: definition,
+			},
+		},
+	)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	response, err := client.Post(GetResourceNameForSchema(project, schemaId)+":commit", body)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return decodeSchema(response)
+	default:
+		return Schema{}, fmt.Errorf("unexpected status code %d in CommitSchema", response.StatusCode)
+	}
+}
+
+// ListSchemaRevisions returns every revision of the schema, as ordered by
+// the emulator (newest first), following every page.
+func ListSchemaRevisions(client utils.ClientInterface, project, schemaId string) ([]Schema, error) {
+	type listSchemaRevisionsResponse struct {
+		Schemas       []Schema `json:"schemas"`
+		NextPageToken string   `json:"nextPageToken"`
+	}
+
+	var revisions []Schema
+	pageToken := ""
+	for {
+		response, err := client.Get(withPageToken(GetResourceNameForSchema(project, schemaId)+":listRevisions?view=FULL", pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.StatusCode {
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("schema '%s' not found", schemaId)
+		case http.StatusOK:
+		default:
+			return nil, fmt.Errorf("unexpected status code %d in ListSchemaRevisions", response.StatusCode)
+		}
+
+		var res listSchemaRevisionsResponse
+		if err := json.Unmarshal(response.Body, &res); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, res.Schemas...)
+
+		if res.NextPageToken == "" {
+			return revisions, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// RollbackSchema commits again the definition of the given revision, which
+// becomes the newest one.
+func RollbackSchema(client utils.ClientInterface, project, schemaId, revisionId string) (Schema, error) {
+	body, err := json.Marshal(map[string]string{"revisionId": revisionId})
+	if err != nil {
+		return Schema{}, err
+	}
+
+	response, err := client.Post(GetResourceNameForSchema(project, schemaId)+":rollback", body)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return decodeSchema(response)
+	default:
+		return Schema{}, fmt.Errorf("unexpected status code %d in RollbackSchema", response.StatusCode)
+	}
+}
+
+// DeleteSchemaRevision deletes a revision of the schema. The last remaining
+// revision can't be deleted, the whole schema has to be deleted instead.
+func DeleteSchemaRevision(client utils.ClientInterface, project, schemaId, revisionId string) error {
+	response, err := client.Delete(fmt.Sprintf("%s@%s:deleteRevision", GetResourceNameForSchema(project, schemaId), revisionId))
 	if err != nil {
 		return err
 	}
@@ -69,7 +214,7 @@ func CreateSchema(client utils.ClientInterface, project, schemaId, name, schemaT
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf("unexpected status code %d in CreateSchema", response.StatusCode)
+		return fmt.Errorf("unexpected status code %d in DeleteSchemaRevision", response.StatusCode)
 	}
 }
 
@@ -89,29 +234,41 @@ func IsSchemaPresent(client utils.ClientInterface, schemaResourceName string) (b
 	}
 }
 
+// ListSchemas returns every schema of a project, following every page.
 func ListSchemas(client utils.ClientInterface, project string) ([]Schema, error) {
 	url := fmt.Sprintf("projects/%s/schemas?view=FULL", project)
-	response, err := client.Get(url)
-	if err != nil {
-		return nil, err
-	}
 
 	type listSchemasResponse struct {
 		Schemas       []Schema `json:"schemas"`
 		NextPageToken string   `json:"nextPageToken"`
 	}
 
-	switch response.StatusCode {
-	case http.StatusNotFound:
-		return nil, fmt.Errorf("project not found")
-	case http.StatusOK:
+	var schemas []Schema
+	pageToken := ""
+	for {
+		response, err := client.Get(withPageToken(url, pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.StatusCode {
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("project not found")
+		case http.StatusOK:
+		default:
+			return nil, fmt.Errorf("unexpected status code %d in ListSchemas", response.StatusCode)
+		}
+
 		var res listSchemasResponse
 		if err := json.Unmarshal(response.Body, &res); err != nil {
 			return nil, err
 		}
-		return res.Schemas, nil
-	default:
-		return nil, fmt.Errorf("unexpected status code %d in ListSchemas", response.StatusCode)
+		schemas = append(schemas, res.Schemas...)
+
+		if res.NextPageToken == "" {
+			return schemas, nil
+		}
+		pageToken = res.NextPageToken
 	}
 }
 
@@ -133,6 +290,20 @@ func GetSchemaRevisionIdBySchemaId(client utils.ClientInterface, project, schema
 	}
 }
 
+func DeleteSchema(client utils.ClientInterface, project, schemaId string) error {
+	response, err := client.Delete(GetResourceNameForSchema(project, schemaId))
+	if err != nil {
+		return err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return nil
+	default:
+		return fmt.Errorf("unexpected status code %d in DeleteSchema", response.StatusCode)
+	}
+}
+
 func GetResourceNameForSchema(project, schema string) string {
 	return fmt.Sprintf("projects/%s/schemas/%s", project, schema)
 }
@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Schemas_Create(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/schemas/test-schema","revisionId":"a1"}`)}, Error: nil},
+		},
+	}
+
+	schema, err := CreateSchema(mockClient, "test-project", "test-schema", "AVRO", `{"type":"string"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a1", schema.RevisionId)
+	assert.Equal(t, http.MethodPost, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/schemas?schemaId=test-schema", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"name":"projects/test-project/schemas/test-schema","type":"AVRO","definition":"{\"type\":\"string\"}"}`, string(mockClient.RequestHistory[0].Body))
+}
+
+func Test_Schemas_Create_AlreadyExists(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusConflict}, Error: nil},
+		},
+	}
+
+	_, err := CreateSchema(mockClient, "test-project", "test-schema", "AVRO", `{"type":"string"}`)
+	assert.ErrorIs(t, err, ErrSchemaAlreadyExists)
+}
+
+func Test_Schemas_Commit(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"revisionId":"b2"}`)}, Error: nil},
+		},
+	}
+
+	schema, err := CommitSchema(mockClient, "test-project", "test-schema", "AVRO", `{"type":"int"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "b2", schema.RevisionId)
+	assert.Equal(t, "projects/test-project/schemas/test-schema:commit", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"schema":{"name":"projects/test-project/schemas/test-schema","type":"AVRO","definition":"{\"type\":\"int\"}"}}`, string(mockClient.RequestHistory[0].Body))
+}
+
+func Test_Schemas_ListRevisions(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"b2"},{"revisionId":"a1"}]}`)}, Error: nil},
+		},
+	}
+
+	revisions, err := ListSchemaRevisions(mockClient, "test-project", "test-schema")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, "b2", revisions[0].RevisionId)
+	assert.Equal(t, "projects/test-project/schemas/test-schema:listRevisions?view=FULL", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Schemas_ListRevisions_FollowsPages(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"b2"}],"nextPageToken":"next"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[{"revisionId":"a1"}]}`)}, Error: nil},
+		},
+	}
+
+	revisions, err := ListSchemaRevisions(mockClient, "test-project", "test-schema")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, "a1", revisions[1].RevisionId)
+	assert.Equal(t, "projects/test-project/schemas/test-schema:listRevisions?view=FULL&pageToken=next", mockClient.RequestHistory[1].Path)
+}
+
+func Test_Schemas_Rollback(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"revisionId":"c3"}`)}, Error: nil},
+		},
+	}
+
+	schema, err := RollbackSchema(mockClient, "test-project", "test-schema", "a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "c3", schema.RevisionId)
+	assert.Equal(t, "projects/test-project/schemas/test-schema:rollback", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"revisionId":"a1"}`, string(mockClient.RequestHistory[0].Body))
+}
+
+func Test_Schemas_DeleteRevision(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	err := DeleteSchemaRevision(mockClient, "test-project", "test-schema", "a1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/schemas/test-schema@a1:deleteRevision", mockClient.RequestHistory[0].Path)
+}
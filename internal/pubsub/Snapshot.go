@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.snapshots
+type Snapshot struct {
+	Name       string `json:"name"`
+	Topic      string `json:"topic"`
+	ExpireTime string `json:"expireTime,omitempty"`
+	Labels     Labels `json:"labels,omitempty"`
+}
+
+// ErrSnapshotAlreadyExists is returned by CreateSnapshot when the snapshot
+// exists.
+var ErrSnapshotAlreadyExists = errors.New("snapshot already exists")
+
+// CreateSnapshot creates a snapshot of the acknowledgment state of a
+// subscription, so it can be sought back to it with SeekToSnapshot.
+func CreateSnapshot(
+	client utils.ClientInterface,
+	project, snapshotResourceName, subscriptionResourceName string,
+	labels *Labels,
+) (Snapshot, error) {
+	type CreateSnapshotBody struct {
+		Subscription string `json:"subscription"`
+		Labels       Labels `json:"labels,omitempty"`
+	}
+
+	body := CreateSnapshotBody{Subscription: subscriptionResourceName}
+	if labels != nil {
+		body.Labels = *labels
+	}
+
+	rawBody, err := json.Marshal(body)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	response, err := client.Put(snapshotResourceName, rawBody)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		var snapshot Snapshot
+		if err := json.Unmarshal(response.Body, &snapshot); err != nil {
+			return Snapshot{}, err
+		}
+		return snapshot, nil
+	case http.StatusConflict:
+		return Snapshot{}, ErrSnapshotAlreadyExists
+	case http.StatusNotFound:
+		return Snapshot{}, errors.New("subscription not found")
+	default:
+		return Snapshot{}, fmt.Errorf("error creating snapshot: status code %d", response.StatusCode)
+	}
+}
+
+// ListSnapshots lists the snapshots of a project, following every page.
+func ListSnapshots(
+	client utils.ClientInterface,
+	project string,
+) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+	pageToken := ""
+	for {
+		response, err := client.Get(withPageToken(fmt.Sprintf("projects/%s/snapshots", project), pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.StatusCode {
+		case http.StatusNotFound:
+			return nil, errors.New("project not found")
+		case http.StatusOK:
+		default:
+			return nil, fmt.Errorf("unexpected status code %d in ListSnapshots", response.StatusCode)
+		}
+
+		var res struct {
+			Snapshots     []Snapshot `json:"snapshots"`
+			NextPageToken string     `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(response.Body, &res); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, res.Snapshots...)
+
+		if res.NextPageToken == "" {
+			return snapshots, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// DeleteSnapshot deletes a snapshot.
+func DeleteSnapshot(
+	client utils.ClientInterface,
+	project, snapshotResourceName string,
+) error {
+	response, err := client.Delete(snapshotResourceName)
+	if err != nil {
+		return err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return errors.New("snapshot not found")
+	default:
+		return fmt.Errorf("error deleting snapshot: status code %d", response.StatusCode)
+	}
+}
+
+// SeekToSnapshot restores the acknowledgment state of a subscription to the
+// one captured by a snapshot of its topic.
+func SeekToSnapshot(
+	client utils.ClientInterface,
+	project, subscriptionResourceName, snapshotResourceName string,
+) error {
+	type SeekBody struct {
+		Snapshot string `json:"snapshot"`
+	}
+
+	rawBody, err := json.Marshal(SeekBody{Snapshot: snapshotResourceName})
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(subscriptionResourceName+":seek", rawBody)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error seeking subscription to snapshot: status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// GetResourceNameForSnapshot generates the full resource name for a snapshot.
+func GetResourceNameForSnapshot(project, snapshot string) string {
+	return fmt.Sprintf("projects/%s/snapshots/%s", project, snapshot)
+}
@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshots_Create(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/snapshots/before-test","topic":"projects/test-project/topics/test-topic"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusConflict}, Error: nil},
+		},
+	}
+
+	snapshot, err := CreateSnapshot(mockClient, "test-project", "projects/test-project/snapshots/before-test", "projects/test-project/subscriptions/test-subscription", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "projects/test-project/topics/test-topic", snapshot.Topic)
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/snapshots/before-test", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"subscription":"projects/test-project/subscriptions/test-subscription"}`, string(mockClient.RequestHistory[0].Body))
+
+	_, err = CreateSnapshot(mockClient, "test-project", "projects/test-project/snapshots/before-test", "projects/test-project/subscriptions/test-subscription", nil)
+	assert.ErrorIs(t, err, ErrSnapshotAlreadyExists)
+}
+
+func Test_Snapshots_ListAndDelete(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"snapshots":[{"name":"projects/test-project/snapshots/before-test"}]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	snapshots, err := ListSnapshots(mockClient, "test-project")
+	assert.NoError(t, err)
+	assert.Equal(t, []Snapshot{{Name: "projects/test-project/snapshots/before-test"}}, snapshots)
+	assert.Equal(t, "projects/test-project/snapshots", mockClient.RequestHistory[0].Path)
+
+	err = DeleteSnapshot(mockClient, "test-project", "projects/test-project/snapshots/before-test")
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[1].Method)
+}
+
+func Test_Snapshots_Seek(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	err := SeekToSnapshot(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", "projects/test-project/snapshots/before-test")
+	assert.NoError(t, err)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:seek", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"snapshot":"projects/test-project/snapshots/before-test"}`, string(mockClient.RequestHistory[0].Body))
+}
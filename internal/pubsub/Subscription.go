@@ -5,14 +5,48 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
 )
 
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.subscriptions#DeadLetterPolicy
+type DeadLetterPolicy struct {
+	/**
+	  The topic receiving the undeliverable messages. It can be the name of a
+	    topic of the same project or a full resource name, like
+	    projects/{project}/topics/{topic}.
+	*/
+	DeadLetterTopic     string `json:"deadLetterTopic"`
+	MaxDeliveryAttempts int    `json:"maxDeliveryAttempts,omitempty"`
+}
+
+// https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.subscriptions#PushConfig
+type PushConfig struct {
+	/**
+	  URL the messages are pushed to. The emulator pushes them when the
+	    endpoint is reachable from it.
+	*/
+	PushEndpoint string            `json:"pushEndpoint,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// IsPull is true when there is no endpoint to push to, like the empty
+// pushConfig of the pull subscriptions listed by gcloud.
+func (p *PushConfig) IsPull() bool {
+	return p == nil || p.PushEndpoint == ""
+}
+
 // Subscription represents a Pub/Sub subscription.
 type Subscription struct {
-	Name   string `json:"name"`
-	Labels Labels `json:"labels"`
+	Name string `json:"name"`
+	// Topic is the resource name of the topic of the subscription, only set
+	// for the subscriptions listed from the emulator.
+	Topic            string            `json:"topic,omitempty"`
+	Labels           Labels            `json:"labels"`
+	DeadLetterPolicy *DeadLetterPolicy `json:"deadLetterPolicy,omitempty"`
+	PushConfig       *PushConfig       `json:"pushConfig,omitempty"`
 }
 
 // String returns a JSON string representation of the Subscription.
@@ -48,32 +82,106 @@ func GetSubscription(
 	}
 }
 
+// GetSubscriptionTopic returns the resource name of the topic of a
+// subscription.
+func GetSubscriptionTopic(
+	client utils.ClientInterface,
+	project, subscriptionResourceName string,
+) (string, error) {
+	response, err := client.Get(subscriptionResourceName)
+	if err != nil {
+		return "", err
+	}
+
+	switch response.StatusCode {
+	case http.StatusNotFound:
+		return "", errors.New("subscription not found")
+	case http.StatusOK:
+		var body subscriptionBody
+		if err := json.Unmarshal(response.Body, &body); err != nil {
+			return "", err
+		}
+		return body.Topic, nil
+	default:
+		return "", fmt.Errorf("unexpected status code %d in GetSubscriptionTopic", response.StatusCode)
+	}
+}
+
 // listSubscriptionsResponse is the internal structure for unmarshalling the ListSubscriptions response.
 type listSubscriptionsResponse struct {
 	Subscriptions []Subscription `json:"subscriptions"`
+	NextPageToken string         `json:"nextPageToken"`
 }
 
-// ListSubscriptions retrieves all subscriptions for a given project.
+// ListSubscriptions retrieves all subscriptions for a given project,
+// following every page.
 func ListSubscriptions(
 	client utils.ClientInterface,
 	project string,
 ) ([]Subscription, error) {
 	// Build the URL for listing subscriptions.
 	url := fmt.Sprintf("projects/%s/subscriptions", project)
-	response, err := client.Get(url)
-	if err != nil {
-		return nil, err
-	}
 
-	switch response.StatusCode {
-	case http.StatusOK:
+	var subscriptions []Subscription
+	pageToken := ""
+	for {
+		response, err := client.Get(withPageToken(url, pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d in ListSubscriptions", response.StatusCode)
+		}
+
 		var res listSubscriptionsResponse
 		if err := json.Unmarshal(response.Body, &res); err != nil {
 			return nil, err
 		}
-		return res.Subscriptions, nil
-	default:
-		return nil, fmt.Errorf("unexpected status code %d in ListSubscriptions", response.StatusCode)
+		subscriptions = append(subscriptions, res.Subscriptions...)
+
+		if res.NextPageToken == "" {
+			return subscriptions, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// ListTopicSubscriptions returns the resource names of the subscriptions of a
+// topic.
+func ListTopicSubscriptions(
+	client utils.ClientInterface,
+	project, topicResourceName string,
+) ([]string, error) {
+	subscriptions := []string{}
+	pageToken := ""
+	for {
+		response, err := client.Get(withPageToken(topicResourceName+"/subscriptions", pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.StatusCode {
+		case http.StatusNotFound:
+			return nil, errors.New("topic not found")
+		case http.StatusOK:
+		default:
+			return nil, fmt.Errorf("unexpected status code %d in ListTopicSubscriptions", response.StatusCode)
+		}
+
+		var res struct {
+			Subscriptions []string `json:"subscriptions"`
+			NextPageToken string   `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(response.Body, &res); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, res.Subscriptions...)
+
+		if res.NextPageToken == "" {
+			return subscriptions, nil
+		}
+		pageToken = res.NextPageToken
 	}
 }
 
@@ -98,46 +206,178 @@ func IsSubscriptionPresent(
 	}
 }
 
-// CreateSubscription creates a subscription for a topic if it does not exist.
+// subscriptionBody is the subscription as sent to (and received from) the
+// REST API.
+type subscriptionBody struct {
+	Name             string            `json:"name,omitempty"`
+	Topic            string            `json:"topic"`
+	Labels           Labels            `json:"labels"`
+	DeadLetterPolicy *DeadLetterPolicy `json:"deadLetterPolicy,omitempty"`
+	PushConfig       *PushConfig       `json:"pushConfig,omitempty"`
+}
+
+// subscriptionUpdatableFields maps every field of subscriptionBody that can
+// be patched to its name in the update mask.
+var subscriptionUpdatableFields = map[string]func(subscriptionBody) interface{}{
+	"labels":           func(s subscriptionBody) interface{} { return s.Labels },
+	"deadLetterPolicy": func(s subscriptionBody) interface{} { return s.DeadLetterPolicy },
+	"pushConfig":       func(s subscriptionBody) interface{} { return s.PushConfig },
+}
+
+func buildSubscriptionBody(
+	project, topicResourceName string,
+	labels *Labels,
+	deadLetterPolicy *DeadLetterPolicy,
+	pushConfig *PushConfig,
+) subscriptionBody {
+	body := subscriptionBody{Topic: topicResourceName}
+
+	if !pushConfig.IsPull() {
+		body.PushConfig = pushConfig
+	}
+
+	if labels != nil {
+		body.Labels = *labels
+	}
+
+	if deadLetterPolicy != nil {
+		body.DeadLetterPolicy = &DeadLetterPolicy{
+			DeadLetterTopic:     GetResourceNameForDeadLetterTopic(project, deadLetterPolicy.DeadLetterTopic),
+			MaxDeliveryAttempts: deadLetterPolicy.MaxDeliveryAttempts,
+		}
+	}
+
+	return body
+}
+
+// CreateSubscription creates a subscription for a topic if it does not
+// exist. Like CreateTopic, an already existing subscription is detected by
+// the conflict returned by the emulator.
 func CreateSubscription(
 	client utils.ClientInterface,
 	project, subscriptionResourceName, topicResourceName string,
 	labels *Labels,
+	deadLetterPolicy *DeadLetterPolicy,
+	pushConfig *PushConfig,
 ) error {
-	exists, err := IsSubscriptionPresent(client, project, subscriptionResourceName)
+	rawBody, err := json.Marshal(buildSubscriptionBody(project, topicResourceName, labels, deadLetterPolicy, pushConfig))
+	if err != nil {
+		return err
+	}
+	response, err := client.Put(subscriptionResourceName, rawBody)
 	if err != nil {
 		return err
 	}
-	if exists {
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusConflict:
 		return nil
+	default:
+		return fmt.Errorf("error creating subscription: status code %d", response.StatusCode)
+	}
+}
+
+// SubscriptionUpdateMask returns the fields of an existing subscription that
+// differ from the given ones, without updating it. The topic of a
+// subscription can't be changed, so it must be recreated in that case.
+func SubscriptionUpdateMask(
+	client utils.ClientInterface,
+	project, subscriptionResourceName, topicResourceName string,
+	labels *Labels,
+	deadLetterPolicy *DeadLetterPolicy,
+	pushConfig *PushConfig,
+) ([]string, error) {
+	updateMask, _, err := subscriptionUpdate(client, project, subscriptionResourceName, topicResourceName, labels, deadLetterPolicy, pushConfig)
+	return updateMask, err
+}
+
+// subscriptionUpdate returns the update mask of the subscription and its
+// desired body.
+func subscriptionUpdate(
+	client utils.ClientInterface,
+	project, subscriptionResourceName, topicResourceName string,
+	labels *Labels,
+	deadLetterPolicy *DeadLetterPolicy,
+	pushConfig *PushConfig,
+) ([]string, subscriptionBody, error) {
+	response, err := client.Get(subscriptionResourceName)
+	if err != nil {
+		return nil, subscriptionBody{}, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, subscriptionBody{}, errors.New("subscription not found")
+	default:
+		return nil, subscriptionBody{}, fmt.Errorf("unexpected status code %d in UpdateSubscription", response.StatusCode)
 	}
 
-	// Prepare the request body.
-	type CreateSubscriptionBody struct {
-		Topic  string `json:"topic"`
-		Labels Labels `json:"labels"`
+	var currentSubscriptionBody subscriptionBody
+	if err := json.Unmarshal(response.Body, &currentSubscriptionBody); err != nil {
+		return nil, subscriptionBody{}, err
 	}
 
-	createSubscriptionBody := CreateSubscriptionBody{Topic: topicResourceName}
+	if currentSubscriptionBody.Topic != topicResourceName {
+		return nil, subscriptionBody{}, fmt.Errorf("subscription belongs to '%s' and it can't be moved to '%s'", currentSubscriptionBody.Topic, topicResourceName)
+	}
 
-	if labels != nil {
-		createSubscriptionBody.Labels = *labels
+	desiredSubscriptionBody := buildSubscriptionBody(project, topicResourceName, labels, deadLetterPolicy, pushConfig)
+
+	updateMask := []string{}
+	for _, field := range sortedKeys(subscriptionUpdatableFields) {
+		getter := subscriptionUpdatableFields[field]
+		if !equivalentValues(getter(currentSubscriptionBody), getter(desiredSubscriptionBody)) {
+			updateMask = append(updateMask, field)
+		}
 	}
+	return updateMask, desiredSubscriptionBody, nil
+}
 
-	rawBody, err := json.Marshal(createSubscriptionBody)
+// UpdateSubscription patches the fields of an existing subscription that
+// differ from the given ones, returning the update mask used. The topic of a
+// subscription can't be changed, so it must be recreated in that case.
+func UpdateSubscription(
+	client utils.ClientInterface,
+	project, subscriptionResourceName, topicResourceName string,
+	labels *Labels,
+	deadLetterPolicy *DeadLetterPolicy,
+	pushConfig *PushConfig,
+) ([]string, error) {
+	updateMask, desiredSubscriptionBody, err := subscriptionUpdate(client, project, subscriptionResourceName, topicResourceName, labels, deadLetterPolicy, pushConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	response, err := client.Put(subscriptionResourceName, rawBody)
+
+	if len(updateMask) == 0 {
+		return updateMask, nil
+	}
+
+	desiredSubscriptionBody.Name = subscriptionResourceName
+
+	type UpdateSubscriptionBody struct {
+		Subscription subscriptionBody `json:"subscription"`
+		UpdateMask   string           `json:"updateMask"`
+	}
+
+	rawBody, err := json.Marshal(UpdateSubscriptionBody{
+		Subscription: desiredSubscriptionBody,
+		UpdateMask:   strings.Join(updateMask, ","),
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	response, err := client.Patch(subscriptionResourceName, rawBody)
+	if err != nil {
+		return nil, err
 	}
 
 	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("error creating subscription: status code %d", response.StatusCode)
+		return nil, fmt.Errorf("error updating subscription: status code %d", response.StatusCode)
 	}
 
-	return nil
+	return updateMask, nil
 }
 
 // DeleteSubscription deletes a subscription.
@@ -158,7 +398,43 @@ func DeleteSubscription(
 	return nil
 }
 
+// Seek acknowledges the messages of a subscription published before the given
+// time and marks the ones published after it as unacknowledged.
+func Seek(
+	client utils.ClientInterface,
+	project, subscriptionResourceName string,
+	seekTime time.Time,
+) error {
+	type SeekBody struct {
+		Time string `json:"time"`
+	}
+
+	rawBody, err := json.Marshal(SeekBody{Time: seekTime.UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(subscriptionResourceName+":seek", rawBody)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error seeking subscription: status code %d", response.StatusCode)
+	}
+	return nil
+}
+
 // GetResourceNameForSubscription generates the full resource name for a subscription.
 func GetResourceNameForSubscription(project, subscription string) string {
 	return fmt.Sprintf("projects/%s/subscriptions/%s", project, subscription)
 }
+
+// GetResourceNameForDeadLetterTopic returns the full resource name of a dead
+// letter topic, which can be configured with just the topic name.
+func GetResourceNameForDeadLetterTopic(project, deadLetterTopic string) string {
+	if strings.HasPrefix(deadLetterTopic, "projects/") {
+		return deadLetterTopic
+	}
+	return GetResourceNameForTopic(project, deadLetterTopic)
+}
@@ -3,6 +3,7 @@ package pubsub
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
 	"github.com/stretchr/testify/assert"
@@ -11,18 +12,15 @@ import (
 func Test_Subscriptions_Create(t *testing.T) {
 	mockClient := &utils.MockClient{
 		ResponseHistory: []utils.MockClientHistoryResponse{
-			{Response: utils.Response{StatusCode: http.StatusNotFound}, Error: nil},
 			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
 		},
 	}
 
-	err := CreateSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", "projects/test-project/topics/test-topic", nil)
+	err := CreateSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", "projects/test-project/topics/test-topic", nil, nil, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, 2, len(mockClient.RequestHistory))
-	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[0].Method)
 	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[0].Path)
-	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[1].Method)
-	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[1].Path)
 }
 
 func Test_Subscriptions_IsPresent(t *testing.T) {
@@ -56,6 +54,40 @@ func Test_Subscriptions_List(t *testing.T) {
 	assert.Equal(t, "projects/test-project/subscriptions", mockClient.RequestHistory[0].Path)
 }
 
+func Test_Subscriptions_List_FollowsPages(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"first"}],"nextPageToken":"page/2"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[{"name":"second"}]}`)}, Error: nil},
+		},
+	}
+
+	subscriptions, err := ListSubscriptions(mockClient, "test-project")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(subscriptions))
+	assert.Equal(t, "second", subscriptions[1].Name)
+	assert.Equal(t, "projects/test-project/subscriptions?pageToken=page%2F2", mockClient.RequestHistory[1].Path)
+}
+
+func Test_Subscriptions_ListTopicSubscriptions(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":["projects/test-project/subscriptions/test-subscription"]}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+		},
+	}
+
+	subscriptions, err := ListTopicSubscriptions(mockClient, "test-project", "projects/test-project/topics/test-topic")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"projects/test-project/subscriptions/test-subscription"}, subscriptions)
+	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/topics/test-topic/subscriptions", mockClient.RequestHistory[0].Path)
+
+	subscriptions, err = ListTopicSubscriptions(mockClient, "test-project", "projects/test-project/topics/test-topic")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, subscriptions)
+}
+
 func Test_Subscriptions_Delete(t *testing.T) {
 	mockClient := &utils.MockClient{
 		ResponseHistory: []utils.MockClientHistoryResponse{
@@ -69,3 +101,118 @@ func Test_Subscriptions_Delete(t *testing.T) {
 	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[0].Method)
 	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[0].Path)
 }
+
+func Test_Subscriptions_Create_WithDeadLetterPolicy(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	err := CreateSubscription(
+		mockClient,
+		"test-project",
+		"projects/test-project/subscriptions/test-subscription",
+		"projects/test-project/topics/test-topic",
+		nil,
+		&DeadLetterPolicy{DeadLetterTopic: "test-topic.dead-letter", MaxDeliveryAttempts: 5},
+		nil,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+	assert.JSONEq(
+		t,
+		`{"topic":"projects/test-project/topics/test-topic","labels":null,"deadLetterPolicy":{"deadLetterTopic":"projects/test-project/topics/test-topic.dead-letter","maxDeliveryAttempts":5}}`,
+		string(mockClient.RequestHistory[0].Body),
+	)
+}
+
+func Test_Subscriptions_Create_WithPushConfig(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	err := CreateSubscription(
+		mockClient,
+		"test-project",
+		"projects/test-project/subscriptions/test-subscription",
+		"projects/test-project/topics/test-topic",
+		nil,
+		nil,
+		&PushConfig{PushEndpoint: "http://localhost:3000/push"},
+	)
+	assert.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{"topic":"projects/test-project/topics/test-topic","labels":null,"pushConfig":{"pushEndpoint":"http://localhost:3000/push"}}`,
+		string(mockClient.RequestHistory[0].Body),
+	)
+}
+
+func Test_Subscriptions_Create_WithEmptyPushConfig(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	err := CreateSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", "projects/test-project/topics/test-topic", nil, nil, &PushConfig{})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(mockClient.RequestHistory[0].Body), "pushConfig")
+}
+
+func Test_Subscriptions_Update(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/subscriptions/test-subscription","topic":"projects/test-project/topics/test-topic"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	updateMask, err := UpdateSubscription(
+		mockClient,
+		"test-project",
+		"projects/test-project/subscriptions/test-subscription",
+		"projects/test-project/topics/test-topic",
+		nil,
+		&DeadLetterPolicy{DeadLetterTopic: "test-topic.dead-letter"},
+		nil,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deadLetterPolicy"}, updateMask)
+	assert.Equal(t, http.MethodPatch, mockClient.RequestHistory[1].Method)
+	assert.JSONEq(
+		t,
+		`{"subscription":{"name":"projects/test-project/subscriptions/test-subscription","topic":"projects/test-project/topics/test-topic","labels":null,"deadLetterPolicy":{"deadLetterTopic":"projects/test-project/topics/test-topic.dead-letter"}},"updateMask":"deadLetterPolicy"}`,
+		string(mockClient.RequestHistory[1].Body),
+	)
+}
+
+func Test_Subscriptions_Update_DifferentTopic(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/subscriptions/test-subscription","topic":"projects/test-project/topics/other-topic"}`)}, Error: nil},
+		},
+	}
+
+	_, err := UpdateSubscription(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", "projects/test-project/topics/test-topic", nil, nil, nil)
+	assert.Error(t, err)
+}
+
+func Test_Subscriptions_Seek(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusNotImplemented}, Error: nil},
+		},
+	}
+
+	seekTime := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, Seek(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", seekTime))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:seek", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"time":"2025-03-03T10:00:00Z"}`, string(mockClient.RequestHistory[0].Body))
+
+	assert.Error(t, Seek(mockClient, "test-project", "projects/test-project/subscriptions/test-subscription", seekTime))
+}
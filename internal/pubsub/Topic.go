@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
 )
 
 // https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.topics#MessageStoragePolicy
@@ -93,7 +95,17 @@ type SchemaSettings struct {
 	Encoding SchemaEncoding `json:"encoding"`
 
 	/**
-	  These will convert the SchemaId to RevisionId
+	  Revisions of the schema the messages can be validated against. In the
+	   configuration these are aliases of the schema revisions, resolved to
+	   the revision ids before the topic is sent to the emulator.
+	*/
+	FirstRevisionId string `json:"firstRevisionId,omitempty"`
+	LastRevisionId  string `json:"lastRevisionId,omitempty"`
+
+	/**
+	  Deprecated: schema revisions used to be declared as separate schemas.
+	   These will convert the SchemaId to the RevisionId of its last revision
+	   and use that schema instead of Schema.
 	*/
 	FirstSchemaId string `json:"firstSchemaId,omitempty"`
 	LastSchemaId  string `json:"lastSchemaId,omitempty"`
@@ -124,6 +136,14 @@ type Topic struct {
 	IngestionDataSourceSettings *TopicIngestionDataSourceSettings `json:"ingestionDataSourceSettings,omitempty"`
 
 	SchemaSettings *SchemaSettings `json:"schemaSettings,omitempty"`
+
+	/**
+	  Messages published to the topic once it and its subscriptions exist.
+	   MessagesFile loads them from a JSON array or a JSON Lines file, relative
+	   to the configuration file.
+	*/
+	Messages     []Message `json:"messages,omitempty"`
+	MessagesFile string    `json:"messagesFile,omitempty"`
 }
 
 // String returns a JSON string representation of the Topic.
@@ -135,95 +155,142 @@ func (t *Topic) String() string {
 	return string(b)
 }
 
-// CreateTopic creates a topic if it does not exist.
-func CreateTopic(
+// topicSchemaSettingsBody is the SchemaSettings as the REST API expects it,
+// with the revisions resolved.
+type topicSchemaSettingsBody struct {
+	Schema          string         `json:"schema"`
+	Encoding        SchemaEncoding `json:"encoding,omitempty"`
+	FirstRevisionId string         `json:"firstRevisionId,omitempty"`
+	LastRevisionId  string         `json:"lastRevisionId,omitempty"`
+}
+
+// topicBody is the topic as sent to (and received from) the REST API.
+type topicBody struct {
+	Name                        string                            `json:"name,omitempty"`
+	Labels                      Labels                            `json:"labels"`
+	MessageStoragePolicy        TopicMessageStoragePolicy         `json:"messageStoragePolicy"`
+	KmsKeyName                  string                            `json:"kmsKeyName"`
+	MessageRetentionDuration    *string                           `json:"messageRetentionDuration"`
+	IngestionDataSourceSettings *TopicIngestionDataSourceSettings `json:"ingestionDataSourceSettings"`
+	SchemaSettings              *topicSchemaSettingsBody          `json:"schemaSettings"`
+}
+
+// topicUpdatableFields maps every field of topicBody that can be patched to
+// its name in the update mask.
+var topicUpdatableFields = map[string]func(topicBody) interface{}{
+	"labels":                      func(t topicBody) interface{} { return t.Labels },
+	"messageStoragePolicy":        func(t topicBody) interface{} { return t.MessageStoragePolicy },
+	"kmsKeyName":                  func(t topicBody) interface{} { return t.KmsKeyName },
+	"messageRetentionDuration":    func(t topicBody) interface{} { return t.MessageRetentionDuration },
+	"ingestionDataSourceSettings": func(t topicBody) interface{} { return t.IngestionDataSourceSettings },
+	"schemaSettings":              func(t topicBody) interface{} { return t.SchemaSettings },
+}
+
+func buildTopicBody(
 	client utils.ClientInterface,
-	project, topicResourceName string,
+	project string,
 	labels *Labels,
 	messageStoragePolicy *TopicMessageStoragePolicy,
 	kmsKeyName string,
 	messageRetentionDuration string,
 	ingestionDataSourceSettings *TopicIngestionDataSourceSettings,
 	schemaSettings *SchemaSettings,
-) error {
-	// Check if the topic already exists.
-	exists, err := IsTopicPresent(client, topicResourceName)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return nil
-	}
-
-	type CreateTopicSchemaSettingsBody struct {
-		Schema          string         `json:"schema"`
-		Encoding        SchemaEncoding `json:"encoding,omitempty"`
-		FirstRevisionId string         `json:"firstRevisionId,omitempty"`
-		LastRevisionId  string         `json:"lastRevisionId,omitempty"`
-	}
-
-	var createTopicSchemaSettingsBody *CreateTopicSchemaSettingsBody
+) (topicBody, error) {
+	var schemaSettingsBody *topicSchemaSettingsBody
 
 	if schemaSettings != nil {
-		schemaFirstRevisionId, err := GetSchemaRevisionIdBySchemaId(client, project, schemaSettings.FirstSchemaId)
-		if err != nil {
-			return err
-		}
-
-		schemaLastRevisionId, err := GetSchemaRevisionIdBySchemaId(client, project, schemaSettings.LastSchemaId)
-		if err != nil {
-			return err
-		}
-
-		// TODO: RIGHT NOW ITS SEEMS THEY ARE CONFUSING ID WITH NAME
-		createTopicSchemaSettingsBody = &CreateTopicSchemaSettingsBody{
-			Schema:          GetResourceNameForSchema(project, schemaSettings.FirstSchemaId),
+		schemaSettingsBody = &topicSchemaSettingsBody{
+			Schema:          GetResourceNameForSchema(project, strings.TrimPrefix(schemaSettings.Schema, GetResourceNameForSchema(project, ""))),
 			Encoding:        schemaSettings.Encoding,
-			FirstRevisionId: schemaFirstRevisionId,
-			LastRevisionId:  schemaLastRevisionId,
+			FirstRevisionId: schemaSettings.FirstRevisionId,
+			LastRevisionId:  schemaSettings.LastRevisionId,
 		}
 
-		// Pretty print the schema settings for logging
-		if b, err := json.MarshalIndent(createTopicSchemaSettingsBody, "", "  "); err == nil {
-			fmt.Printf("Schema settings: %s\n", string(b))
+		if schemaSettings.FirstSchemaId != "" || schemaSettings.LastSchemaId != "" {
+			schemaId := schemaSettings.FirstSchemaId
+			if schemaId == "" {
+				schemaId = schemaSettings.LastSchemaId
+			}
+			schemaSettingsBody.Schema = GetResourceNameForSchema(project, schemaId)
+
+			if schemaSettings.FirstSchemaId != "" {
+				revisionId, err := GetSchemaRevisionIdBySchemaId(client, project, schemaSettings.FirstSchemaId)
+				if err != nil {
+					return topicBody{}, err
+				}
+				schemaSettingsBody.FirstRevisionId = revisionId
+			}
+
+			if schemaSettings.LastSchemaId != "" {
+				revisionId, err := GetSchemaRevisionIdBySchemaId(client, project, schemaSettings.LastSchemaId)
+				if err != nil {
+					return topicBody{}, err
+				}
+				schemaSettingsBody.LastRevisionId = revisionId
+			}
 		}
-	}
 
-	type CreateTopicBody struct {
-		Labels                      Labels                            `json:"labels"`
-		MessageStoragePolicy        TopicMessageStoragePolicy         `json:"messageStoragePolicy"`
-		KmsKeyName                  string                            `json:"kmsKeyName"`
-		MessageRetentionDuration    *string                           `json:"messageRetentionDuration"`
-		IngestionDataSourceSettings *TopicIngestionDataSourceSettings `json:"ingestionDataSourceSettings"`
-		SchemaSettings              *CreateTopicSchemaSettingsBody    `json:"schemaSettings"`
+		if b, err := json.Marshal(schemaSettingsBody); err == nil {
+			Llog.Debug(fmt.Sprintf("Schema settings: %s", string(b)))
+		}
 	}
 
-	createTopicBody := CreateTopicBody{}
+	body := topicBody{}
 
 	if labels != nil {
-		createTopicBody.Labels = *labels
+		body.Labels = *labels
 	}
 
 	if messageStoragePolicy != nil {
-		createTopicBody.MessageStoragePolicy = *messageStoragePolicy
+		body.MessageStoragePolicy = *messageStoragePolicy
 	}
 
 	if kmsKeyName != "" {
-		createTopicBody.KmsKeyName = kmsKeyName
+		body.KmsKeyName = kmsKeyName
 	}
 
 	if messageRetentionDuration != "" {
-		createTopicBody.MessageRetentionDuration = &messageRetentionDuration
+		body.MessageRetentionDuration = &messageRetentionDuration
 	} else {
-		createTopicBody.MessageRetentionDuration = nil
+		body.MessageRetentionDuration = nil
 	}
 
 	if ingestionDataSourceSettings != nil {
-		createTopicBody.IngestionDataSourceSettings = ingestionDataSourceSettings
+		body.IngestionDataSourceSettings = ingestionDataSourceSettings
 	}
 
-	if createTopicSchemaSettingsBody != nil {
-		createTopicBody.SchemaSettings = createTopicSchemaSettingsBody
+	if schemaSettingsBody != nil {
+		body.SchemaSettings = schemaSettingsBody
+	}
+
+	return body, nil
+}
+
+// CreateTopic creates a topic if it does not exist. The existence is not
+// checked beforehand, an already existing topic is reported by the emulator
+// with a conflict.
+func CreateTopic(
+	client utils.ClientInterface,
+	project, topicResourceName string,
+	labels *Labels,
+	messageStoragePolicy *TopicMessageStoragePolicy,
+	kmsKeyName string,
+	messageRetentionDuration string,
+	ingestionDataSourceSettings *TopicIngestionDataSourceSettings,
+	schemaSettings *SchemaSettings,
+) error {
+	createTopicBody, err := buildTopicBody(
+		client,
+		project,
+		labels,
+		messageStoragePolicy,
+		kmsKeyName,
+		messageRetentionDuration,
+		ingestionDataSourceSettings,
+		schemaSettings,
+	)
+	if err != nil {
+		return err
 	}
 
 	jsonCreateTopicBody, err := json.Marshal(createTopicBody)
@@ -237,11 +304,130 @@ func CreateTopic(
 		return err
 	}
 
-	if response.StatusCode != http.StatusOK {
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusConflict:
+		return nil
+	default:
 		return fmt.Errorf("error creating topic: status code %d", response.StatusCode)
 	}
+}
 
-	return nil
+// TopicUpdateMask returns the fields of an existing topic that differ from
+// the given ones, without updating it.
+func TopicUpdateMask(
+	client utils.ClientInterface,
+	project, topicResourceName string,
+	labels *Labels,
+	messageStoragePolicy *TopicMessageStoragePolicy,
+	kmsKeyName string,
+	messageRetentionDuration string,
+	ingestionDataSourceSettings *TopicIngestionDataSourceSettings,
+	schemaSettings *SchemaSettings,
+) ([]string, error) {
+	updateMask, _, err := topicUpdate(client, project, topicResourceName, labels, messageStoragePolicy, kmsKeyName, messageRetentionDuration, ingestionDataSourceSettings, schemaSettings)
+	return updateMask, err
+}
+
+// topicUpdate returns the update mask of the topic and its desired body.
+func topicUpdate(
+	client utils.ClientInterface,
+	project, topicResourceName string,
+	labels *Labels,
+	messageStoragePolicy *TopicMessageStoragePolicy,
+	kmsKeyName string,
+	messageRetentionDuration string,
+	ingestionDataSourceSettings *TopicIngestionDataSourceSettings,
+	schemaSettings *SchemaSettings,
+) ([]string, topicBody, error) {
+	response, err := client.Get(topicResourceName)
+	if err != nil {
+		return nil, topicBody{}, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, topicBody{}, errors.New("topic not found")
+	default:
+		return nil, topicBody{}, fmt.Errorf("unexpected status code %d in UpdateTopic", response.StatusCode)
+	}
+
+	var currentTopicBody topicBody
+	if err := json.Unmarshal(response.Body, &currentTopicBody); err != nil {
+		return nil, topicBody{}, err
+	}
+
+	desiredTopicBody, err := buildTopicBody(
+		client,
+		project,
+		labels,
+		messageStoragePolicy,
+		kmsKeyName,
+		messageRetentionDuration,
+		ingestionDataSourceSettings,
+		schemaSettings,
+	)
+	if err != nil {
+		return nil, topicBody{}, err
+	}
+
+	updateMask := []string{}
+	for _, field := range sortedKeys(topicUpdatableFields) {
+		getter := topicUpdatableFields[field]
+		if !equivalentValues(getter(currentTopicBody), getter(desiredTopicBody)) {
+			updateMask = append(updateMask, field)
+		}
+	}
+	return updateMask, desiredTopicBody, nil
+}
+
+// UpdateTopic patches the fields of an existing topic that differ from the
+// given ones, returning the update mask used. Nothing is sent when the topic
+// is already up to date.
+func UpdateTopic(
+	client utils.ClientInterface,
+	project, topicResourceName string,
+	labels *Labels,
+	messageStoragePolicy *TopicMessageStoragePolicy,
+	kmsKeyName string,
+	messageRetentionDuration string,
+	ingestionDataSourceSettings *TopicIngestionDataSourceSettings,
+	schemaSettings *SchemaSettings,
+) ([]string, error) {
+	updateMask, desiredTopicBody, err := topicUpdate(client, project, topicResourceName, labels, messageStoragePolicy, kmsKeyName, messageRetentionDuration, ingestionDataSourceSettings, schemaSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(updateMask) == 0 {
+		return updateMask, nil
+	}
+
+	desiredTopicBody.Name = topicResourceName
+
+	type UpdateTopicBody struct {
+		Topic      topicBody `json:"topic"`
+		UpdateMask string    `json:"updateMask"`
+	}
+
+	rawBody, err := json.Marshal(UpdateTopicBody{
+		Topic:      desiredTopicBody,
+		UpdateMask: strings.Join(updateMask, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Patch(topicResourceName, rawBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error updating topic: status code %d", response.StatusCode)
+	}
+
+	return updateMask, nil
 }
 
 // IsTopicPresent checks if a topic exists.
@@ -264,35 +450,47 @@ func IsTopicPresent(
 	}
 }
 
-// ListTopics lists all topics of a project.
+// ListTopics lists all topics of a project, following every page.
 func ListTopics(
 	client utils.ClientInterface,
 	project string,
 ) ([]Topic, error) {
 	// Build the URL for listing topics.
 	url := fmt.Sprintf("projects/%s/topics", project)
-	response, err := client.Get(url)
-	if err != nil {
-		return nil, err
-	}
 
-	switch response.StatusCode {
-	case http.StatusNotFound:
-		return nil, errors.New("project not found")
-	case http.StatusOK:
+	var topics []Topic
+	pageToken := ""
+	for {
+		response, err := client.Get(withPageToken(url, pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.StatusCode {
+		case http.StatusNotFound:
+			return nil, errors.New("project not found")
+		case http.StatusOK:
+		default:
+			return nil, fmt.Errorf("unexpected status code %d in ListTopics", response.StatusCode)
+		}
+
 		var res listTopicsResponse
 		if err := json.Unmarshal(response.Body, &res); err != nil {
 			return nil, err
 		}
-		return res.Topics, nil
-	default:
-		return nil, fmt.Errorf("unexpected status code %d in ListTopics", response.StatusCode)
+		topics = append(topics, res.Topics...)
+
+		if res.NextPageToken == "" {
+			return topics, nil
+		}
+		pageToken = res.NextPageToken
 	}
 }
 
 // listTopicsResponse is the internal structure for unmarshalling the ListTopics response.
 type listTopicsResponse struct {
-	Topics []Topic `json:"topics"`
+	Topics        []Topic `json:"topics"`
+	NextPageToken string  `json:"nextPageToken"`
 }
 
 // DeleteTopic deletes a topic.
@@ -11,18 +11,15 @@ import (
 func Test_Topics_Create(t *testing.T) {
 	mockClient := &utils.MockClient{
 		ResponseHistory: []utils.MockClientHistoryResponse{
-			{Response: utils.Response{StatusCode: http.StatusNotFound}, Error: nil},
 			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
 		},
 	}
 
 	err := CreateTopic(mockClient, "test-project", "projects/test-project/topics/test-topic", nil, nil, "", "", nil, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, 2, len(mockClient.RequestHistory))
-	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[0].Method)
 	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[0].Path)
-	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[1].Method)
-	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[1].Path)
 }
 
 func Test_Topics_IsPresent(t *testing.T) {
@@ -56,6 +53,23 @@ func Test_Topics_List(t *testing.T) {
 	assert.Equal(t, "projects/test-project/topics", mockClient.RequestHistory[0].Path)
 }
 
+func Test_Topics_List_FollowsPages(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"first"}],"nextPageToken":"2"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"second"}],"nextPageToken":"3"}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[{"name":"third"}]}`)}, Error: nil},
+		},
+	}
+
+	topics, err := ListTopics(mockClient, "test-project")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(topics))
+	assert.Equal(t, "third", topics[2].Name)
+	assert.Equal(t, "projects/test-project/topics?pageToken=2", mockClient.RequestHistory[1].Path)
+	assert.Equal(t, "projects/test-project/topics?pageToken=3", mockClient.RequestHistory[2].Path)
+}
+
 func Test_Topics_Delete(t *testing.T) {
 	mockClient := &utils.MockClient{
 		ResponseHistory: []utils.MockClientHistoryResponse{
@@ -69,3 +83,47 @@ func Test_Topics_Delete(t *testing.T) {
 	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[0].Method)
 	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[0].Path)
 }
+
+func Test_Topics_Create_AlreadyExists(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusConflict}, Error: nil},
+		},
+	}
+
+	err := CreateTopic(mockClient, "test-project", "projects/test-project/topics/test-topic", nil, nil, "", "", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+}
+
+func Test_Topics_Update(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic","labels":{"owner":"admin"}}`)}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK}, Error: nil},
+		},
+	}
+
+	labels := Labels{"owner": "consumers"}
+	updateMask, err := UpdateTopic(mockClient, "test-project", "projects/test-project/topics/test-topic", &labels, nil, "kms-key", "", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kmsKeyName", "labels"}, updateMask)
+	assert.Equal(t, 2, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPatch, mockClient.RequestHistory[1].Method)
+	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[1].Path)
+	assert.Contains(t, string(mockClient.RequestHistory[1].Body), `"updateMask":"kmsKeyName,labels"`)
+}
+
+func Test_Topics_Update_NothingChanged(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/topics/test-topic","labels":{"owner":"admin"}}`)}, Error: nil},
+		},
+	}
+
+	labels := Labels{"owner": "admin"}
+	updateMask, err := UpdateTopic(mockClient, "test-project", "projects/test-project/topics/test-topic", &labels, &TopicMessageStoragePolicy{}, "", "", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(updateMask))
+	assert.Equal(t, 1, len(mockClient.RequestHistory))
+}
@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// equivalentValues compares two values as they would be sent to the REST
+// API, considering equal the empty values (null, "", false, 0, {} and [])
+// as the emulator omits them in its responses.
+func equivalentValues(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizedJSONValue(a), normalizedJSONValue(b))
+}
+
+func normalizedJSONValue(value interface{}) interface{} {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return value
+	}
+
+	return pruneEmptyJSONValues(decoded)
+}
+
+func pruneEmptyJSONValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		pruned := map[string]interface{}{}
+		for key, item := range v {
+			if item = pruneEmptyJSONValues(item); item != nil {
+				pruned[key] = item
+			}
+		}
+		if len(pruned) == 0 {
+			return nil
+		}
+		return pruned
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		pruned := make([]interface{}, len(v))
+		for i, item := range v {
+			pruned[i] = pruneEmptyJSONValues(item)
+		}
+		return pruned
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	}
+
+	return value
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
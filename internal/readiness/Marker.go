@@ -0,0 +1,37 @@
+package readiness
+
+import (
+	"net/http"
+	"os"
+	"sync/atomic"
+)
+
+// Marker exposes the readiness to container orchestrators, as an HTTP
+// handler answering 200 once ready and 503 before.
+type Marker struct {
+	ready atomic.Bool
+}
+
+func (m *Marker) SetReady() {
+	m.ready.Store(true)
+}
+
+func (m *Marker) IsReady() bool {
+	return m.ready.Load()
+}
+
+func (m *Marker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.IsReady() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not ready\n"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ready\n"))
+}
+
+// WriteReadyFile creates the file used as readiness marker.
+func WriteReadyFile(path string) error {
+	return os.WriteFile(path, []byte("ready\n"), 0644)
+}
@@ -0,0 +1,23 @@
+package readiness
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Marker_ServeHTTP(t *testing.T) {
+	marker := &Marker{}
+
+	recorder := httptest.NewRecorder()
+	marker.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	marker.SetReady()
+
+	recorder = httptest.NewRecorder()
+	marker.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
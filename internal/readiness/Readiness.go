@@ -0,0 +1,112 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// Project used to probe the emulator when the configuration has none. The
+// emulator answers for any project, even if nothing was created on it.
+const DefaultProbeProject = "readiness-probe"
+
+const defaultInterval = 200 * time.Millisecond
+
+type Options struct {
+	// InitialDelay is waited before the first probe.
+	InitialDelay time.Duration
+	// Interval is the time between the first probes.
+	Interval time.Duration
+	// BackoffMultiplier grows the interval after every failed probe. Values
+	// lower or equal than 1 keep the interval constant.
+	BackoffMultiplier float64
+	// MaxInterval caps the interval when using backoff, ignored when zero.
+	MaxInterval time.Duration
+	// Timeout is the maximum time waiting, counting the initial delay.
+	Timeout time.Duration
+	// Project is the project whose topics are listed to probe the emulator.
+	Project string
+	// OnAttempt, when set, is called after every failed probe. By default
+	// the progress is logged.
+	OnAttempt func(attempt int, elapsed time.Duration, err error)
+}
+
+// Probe checks the emulator is answering the API by listing the topics of
+// the given project.
+func Probe(client utils.ClientInterface, project string) error {
+	if project == "" {
+		project = DefaultProbeProject
+	}
+
+	_, err := pubsub.ListTopics(client, project)
+	return err
+}
+
+func logAttempt(attempt int, elapsed time.Duration, err error) {
+	Llog.Info(fmt.Sprintf("Emulator not ready yet (attempt %d, %s elapsed): %v", attempt, elapsed.Round(time.Millisecond), err))
+}
+
+// nextInterval applies the backoff to the current interval.
+func nextInterval(current time.Duration, options Options) time.Duration {
+	if options.BackoffMultiplier <= 1 {
+		return current
+	}
+
+	next := time.Duration(float64(current) * options.BackoffMultiplier)
+	if options.MaxInterval > 0 && next > options.MaxInterval {
+		return options.MaxInterval
+	}
+	return next
+}
+
+// WaitUntilReady blocks until the emulator answers, the timeout is exceeded
+// or the context is cancelled.
+func WaitUntilReady(ctx context.Context, client utils.ClientInterface, options Options) error {
+	onAttempt := options.OnAttempt
+	if onAttempt == nil {
+		onAttempt = logAttempt
+	}
+
+	interval := options.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	startTime := time.Now()
+
+	if options.InitialDelay > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("emulator wasn't ready after %s: %w", time.Since(startTime).Round(time.Millisecond), ctx.Err())
+		case <-time.After(options.InitialDelay):
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := Probe(client, options.Project)
+		if err == nil {
+			Llog.Debug(fmt.Sprintf("Emulator ready after %d attempt(s)", attempt))
+			return nil
+		}
+
+		onAttempt(attempt, time.Since(startTime), err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("emulator wasn't ready after %d attempt(s) and %s: %w", attempt, time.Since(startTime).Round(time.Millisecond), err)
+		case <-time.After(interval):
+		}
+
+		interval = nextInterval(interval, options)
+	}
+}
@@ -0,0 +1,61 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Readiness_ReadyAfterRetries(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{}, Error: errors.New("connection refused")},
+			{Response: utils.Response{StatusCode: http.StatusServiceUnavailable}, Error: nil},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[]}`)}, Error: nil},
+		},
+	}
+
+	attempts := []int{}
+	err := WaitUntilReady(context.Background(), mockClient, Options{
+		Interval: time.Millisecond,
+		Timeout:  time.Second,
+		Project:  "test-project",
+		OnAttempt: func(attempt int, elapsed time.Duration, err error) {
+			attempts = append(attempts, attempt)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, 3, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodGet, mockClient.RequestHistory[0].Method)
+	assert.Equal(t, "projects/test-project/topics", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Readiness_Timeout(t *testing.T) {
+	responses := []utils.MockClientHistoryResponse{}
+	for i := 0; i < 100; i++ {
+		responses = append(responses, utils.MockClientHistoryResponse{Error: errors.New("connection refused")})
+	}
+	mockClient := &utils.MockClient{ResponseHistory: responses}
+
+	err := WaitUntilReady(context.Background(), mockClient, Options{
+		Interval:  5 * time.Millisecond,
+		Timeout:   20 * time.Millisecond,
+		OnAttempt: func(attempt int, elapsed time.Duration, err error) {},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, "projects/readiness-probe/topics", mockClient.RequestHistory[0].Path)
+}
+
+func Test_Readiness_Backoff(t *testing.T) {
+	options := Options{BackoffMultiplier: 2, MaxInterval: 300 * time.Millisecond}
+	assert.Equal(t, 200*time.Millisecond, nextInterval(100*time.Millisecond, options))
+	assert.Equal(t, 300*time.Millisecond, nextInterval(200*time.Millisecond, options))
+	assert.Equal(t, 100*time.Millisecond, nextInterval(100*time.Millisecond, Options{}))
+}
@@ -0,0 +1,355 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type AvroType string
+
+const (
+	AVRO_TYPE_NULL    AvroType = "null"
+	AVRO_TYPE_BOOLEAN AvroType = "boolean"
+	AVRO_TYPE_INT     AvroType = "int"
+	AVRO_TYPE_LONG    AvroType = "long"
+	AVRO_TYPE_FLOAT   AvroType = "float"
+	AVRO_TYPE_DOUBLE  AvroType = "double"
+	AVRO_TYPE_BYTES   AvroType = "bytes"
+	AVRO_TYPE_STRING  AvroType = "string"
+	AVRO_TYPE_RECORD  AvroType = "record"
+	AVRO_TYPE_ENUM    AvroType = "enum"
+	AVRO_TYPE_ARRAY   AvroType = "array"
+	AVRO_TYPE_MAP     AvroType = "map"
+	AVRO_TYPE_FIXED   AvroType = "fixed"
+	AVRO_TYPE_UNION   AvroType = "union"
+)
+
+var avroPrimitiveTypes = map[AvroType]bool{
+	AVRO_TYPE_NULL:    true,
+	AVRO_TYPE_BOOLEAN: true,
+	AVRO_TYPE_INT:     true,
+	AVRO_TYPE_LONG:    true,
+	AVRO_TYPE_FLOAT:   true,
+	AVRO_TYPE_DOUBLE:  true,
+	AVRO_TYPE_BYTES:   true,
+	AVRO_TYPE_STRING:  true,
+}
+
+var avroNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AvroSchema is a parsed Avro schema. Named types referenced more than once
+// (including recursive records) share the same *AvroSchema.
+type AvroSchema struct {
+	Type AvroType
+	// Name is the full name (namespace included) of records, enums and fixed.
+	Name        string
+	LogicalType string
+
+	Fields   []AvroField   // record
+	Symbols  []string      // enum
+	Items    *AvroSchema   // array
+	Values   *AvroSchema   // map
+	Size     int           // fixed
+	Branches []*AvroSchema // union
+}
+
+type AvroField struct {
+	Name       string
+	Type       *AvroSchema
+	HasDefault bool
+	Default    interface{}
+	// Aliases are previous names of the field, used when reading data
+	// written with them.
+	Aliases []string
+}
+
+// key identifies the schema inside a union, where only one branch of every
+// unnamed type is allowed.
+func (s *AvroSchema) key() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return string(s.Type)
+}
+
+type avroParser struct {
+	named map[string]*AvroSchema
+}
+
+// ParseAvro parses an Avro schema definition (the content of an .avsc file)
+// returning an error describing the first problem found.
+func ParseAvro(definition string) (*AvroSchema, error) {
+	decoder := json.NewDecoder(strings.NewReader(definition))
+	decoder.UseNumber()
+
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid Avro schema: unexpected content after the schema")
+	}
+
+	parser := avroParser{named: map[string]*AvroSchema{}}
+	schema, err := parser.parse(raw, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	return schema, nil
+}
+
+func (p *avroParser) parse(raw interface{}, namespace string) (*AvroSchema, error) {
+	switch value := raw.(type) {
+	case string:
+		return p.parseReference(value, namespace)
+	case []interface{}:
+		return p.parseUnion(value, namespace)
+	case map[string]interface{}:
+		return p.parseObject(value, namespace)
+	default:
+		return nil, fmt.Errorf("unexpected schema %v", raw)
+	}
+}
+
+func (p *avroParser) parseReference(name, namespace string) (*AvroSchema, error) {
+	if avroPrimitiveTypes[AvroType(name)] {
+		return &AvroSchema{Type: AvroType(name)}, nil
+	}
+
+	if schema, exists := p.named[fullAvroName(name, namespace)]; exists {
+		return schema, nil
+	}
+	if schema, exists := p.named[name]; exists {
+		return schema, nil
+	}
+	return nil, fmt.Errorf("unknown type '%s'", name)
+}
+
+func (p *avroParser) parseUnion(branches []interface{}, namespace string) (*AvroSchema, error) {
+	union := &AvroSchema{Type: AVRO_TYPE_UNION}
+	seen := map[string]bool{}
+
+	for _, rawBranch := range branches {
+		branch, err := p.parse(rawBranch, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if branch.Type == AVRO_TYPE_UNION {
+			return nil, fmt.Errorf("unions can't contain other unions")
+		}
+		if seen[branch.key()] {
+			return nil, fmt.Errorf("union contains '%s' more than once", branch.key())
+		}
+		seen[branch.key()] = true
+		union.Branches = append(union.Branches, branch)
+	}
+
+	return union, nil
+}
+
+func (p *avroParser) parseObject(object map[string]interface{}, namespace string) (*AvroSchema, error) {
+	rawType, exists := object["type"]
+	if !exists {
+		return nil, fmt.Errorf("missing 'type' in %v", object)
+	}
+
+	typeName, isString := rawType.(string)
+	if !isString {
+		return p.parse(rawType, namespace)
+	}
+
+	logicalType, _ := object["logicalType"].(string)
+
+	switch AvroType(typeName) {
+	case AVRO_TYPE_RECORD, "error":
+		return p.parseRecord(object, namespace)
+	case AVRO_TYPE_ENUM:
+		return p.parseEnum(object, namespace)
+	case AVRO_TYPE_FIXED:
+		return p.parseFixed(object, namespace)
+	case AVRO_TYPE_ARRAY:
+		items, exists := object["items"]
+		if !exists {
+			return nil, fmt.Errorf("array without 'items'")
+		}
+		itemsSchema, err := p.parse(items, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &AvroSchema{Type: AVRO_TYPE_ARRAY, Items: itemsSchema, LogicalType: logicalType}, nil
+	case AVRO_TYPE_MAP:
+		values, exists := object["values"]
+		if !exists {
+			return nil, fmt.Errorf("map without 'values'")
+		}
+		valuesSchema, err := p.parse(values, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &AvroSchema{Type: AVRO_TYPE_MAP, Values: valuesSchema, LogicalType: logicalType}, nil
+	}
+
+	schema, err := p.parseReference(typeName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if logicalType != "" && avroPrimitiveTypes[schema.Type] {
+		return &AvroSchema{Type: schema.Type, LogicalType: logicalType}, nil
+	}
+	return schema, nil
+}
+
+// register declares a named type, before parsing its content so it can be
+// referenced recursively.
+func (p *avroParser) register(object map[string]interface{}, namespace string, schema *AvroSchema) (string, error) {
+	name, _ := object["name"].(string)
+	if name == "" {
+		return "", fmt.Errorf("%s without 'name'", schema.Type)
+	}
+
+	if explicitNamespace, exists := object["namespace"].(string); exists && !strings.Contains(name, ".") {
+		namespace = explicitNamespace
+	}
+
+	fullName := fullAvroName(name, namespace)
+	for _, part := range strings.Split(fullName, ".") {
+		if !avroNamePattern.MatchString(part) {
+			return "", fmt.Errorf("invalid name '%s'", fullName)
+		}
+	}
+	if avroPrimitiveTypes[AvroType(fullName)] {
+		return "", fmt.Errorf("'%s' can't be used as a name", fullName)
+	}
+	if _, exists := p.named[fullName]; exists {
+		return "", fmt.Errorf("type '%s' is defined more than once", fullName)
+	}
+
+	schema.Name = fullName
+	p.named[fullName] = schema
+
+	// Types defined inside use the namespace of the enclosing type.
+	if index := strings.LastIndex(fullName, "."); index >= 0 {
+		return fullName[:index], nil
+	}
+	return "", nil
+}
+
+func (p *avroParser) parseRecord(object map[string]interface{}, namespace string) (*AvroSchema, error) {
+	record := &AvroSchema{Type: AVRO_TYPE_RECORD}
+	namespace, err := p.register(object, namespace, record)
+	if err != nil {
+		return nil, err
+	}
+
+	rawFields, isArray := object["fields"].([]interface{})
+	if !isArray {
+		return nil, fmt.Errorf("record '%s' without 'fields'", record.Name)
+	}
+
+	seen := map[string]bool{}
+	for _, rawField := range rawFields {
+		fieldObject, isObject := rawField.(map[string]interface{})
+		if !isObject {
+			return nil, fmt.Errorf("invalid field %v in record '%s'", rawField, record.Name)
+		}
+
+		name, _ := fieldObject["name"].(string)
+		if !avroNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid field name '%s' in record '%s'", name, record.Name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("field '%s' is defined more than once in record '%s'", name, record.Name)
+		}
+		seen[name] = true
+
+		rawType, exists := fieldObject["type"]
+		if !exists {
+			return nil, fmt.Errorf("field '%s' of record '%s' without 'type'", name, record.Name)
+		}
+		fieldType, err := p.parse(rawType, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s' of record '%s': %w", name, record.Name, err)
+		}
+
+		field := AvroField{Name: name, Type: fieldType}
+		if defaultValue, exists := fieldObject["default"]; exists {
+			field.HasDefault = true
+			field.Default = defaultValue
+		}
+		if rawAliases, exists := fieldObject["aliases"]; exists {
+			aliases, isArray := rawAliases.([]interface{})
+			if !isArray {
+				return nil, fmt.Errorf("invalid aliases of field '%s' in record '%s'", name, record.Name)
+			}
+			for _, rawAlias := range aliases {
+				alias, _ := rawAlias.(string)
+				if !avroNamePattern.MatchString(alias) {
+					return nil, fmt.Errorf("invalid alias %v of field '%s' in record '%s'", rawAlias, name, record.Name)
+				}
+				field.Aliases = append(field.Aliases, alias)
+			}
+		}
+		record.Fields = append(record.Fields, field)
+	}
+
+	return record, nil
+}
+
+func (p *avroParser) parseEnum(object map[string]interface{}, namespace string) (*AvroSchema, error) {
+	enum := &AvroSchema{Type: AVRO_TYPE_ENUM}
+	if _, err := p.register(object, namespace, enum); err != nil {
+		return nil, err
+	}
+
+	rawSymbols, isArray := object["symbols"].([]interface{})
+	if !isArray {
+		return nil, fmt.Errorf("enum '%s' without 'symbols'", enum.Name)
+	}
+
+	seen := map[string]bool{}
+	for _, rawSymbol := range rawSymbols {
+		symbol, _ := rawSymbol.(string)
+		if !avroNamePattern.MatchString(symbol) {
+			return nil, fmt.Errorf("invalid symbol %v in enum '%s'", rawSymbol, enum.Name)
+		}
+		if seen[symbol] {
+			return nil, fmt.Errorf("symbol '%s' is defined more than once in enum '%s'", symbol, enum.Name)
+		}
+		seen[symbol] = true
+		enum.Symbols = append(enum.Symbols, symbol)
+	}
+
+	return enum, nil
+}
+
+func (p *avroParser) parseFixed(object map[string]interface{}, namespace string) (*AvroSchema, error) {
+	fixed := &AvroSchema{Type: AVRO_TYPE_FIXED}
+	if _, err := p.register(object, namespace, fixed); err != nil {
+		return nil, err
+	}
+
+	size, isNumber := object["size"].(json.Number)
+	if !isNumber {
+		return nil, fmt.Errorf("fixed '%s' without 'size'", fixed.Name)
+	}
+	value, err := size.Int64()
+	if err != nil || value < 0 {
+		return nil, fmt.Errorf("invalid size %s in fixed '%s'", size, fixed.Name)
+	}
+	fixed.Size = int(value)
+
+	if logicalType, exists := object["logicalType"].(string); exists {
+		fixed.LogicalType = logicalType
+	}
+
+	return fixed, nil
+}
+
+func fullAvroName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
@@ -0,0 +1,402 @@
+package schema
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// avroUnionBranchName is the name used for a branch of a union in the JSON
+// encoding, the full name for named types and the type otherwise.
+func avroUnionBranchName(s *AvroSchema) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return string(s.Type)
+}
+
+// validateAvroJSON checks a value decoded (using json.Number) from the JSON
+// encoding of Avro, adding a problem for every value not matching. Like the
+// decoder of Pub/Sub, every field of a record must be present, even when it
+// has a default.
+func validateAvroJSON(s *AvroSchema, value interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s", displayPath(path), fmt.Sprintf(format, args...)))
+	}
+
+	switch s.Type {
+	case AVRO_TYPE_NULL:
+		if value != nil {
+			report("expected null, found %s", describeJSONValue(value))
+		}
+	case AVRO_TYPE_BOOLEAN:
+		if _, ok := value.(bool); !ok {
+			report("expected boolean, found %s", describeJSONValue(value))
+		}
+	case AVRO_TYPE_INT, AVRO_TYPE_LONG:
+		number, ok := value.(json.Number)
+		if !ok {
+			report("expected %s, found %s", s.Type, describeJSONValue(value))
+			return
+		}
+		integer, err := number.Int64()
+		if err != nil {
+			report("expected %s, found %s", s.Type, number)
+			return
+		}
+		if s.Type == AVRO_TYPE_INT && (integer < math.MinInt32 || integer > math.MaxInt32) {
+			report("%d is out of the range of int", integer)
+		}
+	case AVRO_TYPE_FLOAT, AVRO_TYPE_DOUBLE:
+		if _, ok := value.(json.Number); !ok {
+			report("expected %s, found %s", s.Type, describeJSONValue(value))
+		}
+	case AVRO_TYPE_STRING:
+		if _, ok := value.(string); !ok {
+			report("expected string, found %s", describeJSONValue(value))
+		}
+	case AVRO_TYPE_BYTES, AVRO_TYPE_FIXED:
+		text, ok := value.(string)
+		if !ok {
+			report("expected %s (as a string), found %s", s.Type, describeJSONValue(value))
+			return
+		}
+		length := 0
+		for _, r := range text {
+			if r > 0xFF {
+				report("%s can only contain code points up to \\u00ff", s.Type)
+				return
+			}
+			length++
+		}
+		if s.Type == AVRO_TYPE_FIXED && length != s.Size {
+			report("expected %d bytes for fixed '%s', found %d", s.Size, s.Name, length)
+		}
+	case AVRO_TYPE_ENUM:
+		symbol, ok := value.(string)
+		if !ok {
+			report("expected a symbol of enum '%s', found %s", s.Name, describeJSONValue(value))
+			return
+		}
+		for _, candidate := range s.Symbols {
+			if candidate == symbol {
+				return
+			}
+		}
+		report("'%s' is not a symbol of enum '%s'", symbol, s.Name)
+	case AVRO_TYPE_ARRAY:
+		items, ok := value.([]interface{})
+		if !ok {
+			report("expected array, found %s", describeJSONValue(value))
+			return
+		}
+		for i, item := range items {
+			validateAvroJSON(s.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case AVRO_TYPE_MAP:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report("expected map, found %s", describeJSONValue(value))
+			return
+		}
+		for _, key := range sortedJSONKeys(object) {
+			validateAvroJSON(s.Values, object[key], fmt.Sprintf("%s[%q]", path, key), problems)
+		}
+	case AVRO_TYPE_RECORD:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report("expected record '%s', found %s", s.Name, describeJSONValue(value))
+			return
+		}
+		known := map[string]bool{}
+		for _, field := range s.Fields {
+			known[field.Name] = true
+			fieldValue, exists := object[field.Name]
+			if !exists {
+				// The JSON decoder of Pub/Sub doesn't fill in the defaults
+				if field.HasDefault {
+					report("missing field '%s', fields with a default are required in the JSON encoding too", field.Name)
+				} else {
+					report("missing field '%s'", field.Name)
+				}
+				continue
+			}
+			validateAvroJSON(field.Type, fieldValue, joinPath(path, field.Name), problems)
+		}
+		for _, key := range sortedJSONKeys(object) {
+			if !known[key] {
+				report("unknown field '%s'", key)
+			}
+		}
+	case AVRO_TYPE_UNION:
+		if value == nil {
+			for _, branch := range s.Branches {
+				if branch.Type == AVRO_TYPE_NULL {
+					return
+				}
+			}
+			report("null is not allowed")
+			return
+		}
+
+		object, ok := value.(map[string]interface{})
+		if !ok || len(object) != 1 {
+			report("expected a union value like {\"<type>\": value}, found %s", describeJSONValue(value))
+			return
+		}
+		for name, branchValue := range object {
+			for _, branch := range s.Branches {
+				if avroUnionBranchName(branch) == name {
+					validateAvroJSON(branch, branchValue, path, problems)
+					return
+				}
+			}
+			report("'%s' is not a type of the union", name)
+		}
+	}
+}
+
+// avroBinaryDecoder reads the Avro binary encoding returning the values as
+// they are represented in the JSON encoding.
+type avroBinaryDecoder struct {
+	data     []byte
+	position int
+}
+
+func (d *avroBinaryDecoder) errorf(path, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s (at byte %d)", displayPath(path), fmt.Sprintf(format, args...), d.position)
+}
+
+func (d *avroBinaryDecoder) read(path string, length int) ([]byte, error) {
+	if length < 0 || length > len(d.data)-d.position {
+		return nil, d.errorf(path, "unexpected end of data")
+	}
+	bytes := d.data[d.position : d.position+length]
+	d.position += length
+	return bytes, nil
+}
+
+func (d *avroBinaryDecoder) readLong(path string) (int64, error) {
+	value, length := binary.Uvarint(d.data[d.position:])
+	if length <= 0 {
+		return 0, d.errorf(path, "invalid variable length integer")
+	}
+	d.position += length
+	return int64(value>>1) ^ -int64(value&1), nil
+}
+
+func (d *avroBinaryDecoder) decode(s *AvroSchema, path string) (interface{}, error) {
+	switch s.Type {
+	case AVRO_TYPE_NULL:
+		return nil, nil
+	case AVRO_TYPE_BOOLEAN:
+		bytes, err := d.read(path, 1)
+		if err != nil {
+			return nil, err
+		}
+		if bytes[0] > 1 {
+			return nil, d.errorf(path, "invalid boolean %d", bytes[0])
+		}
+		return bytes[0] == 1, nil
+	case AVRO_TYPE_INT, AVRO_TYPE_LONG:
+		value, err := d.readLong(path)
+		if err != nil {
+			return nil, err
+		}
+		if s.Type == AVRO_TYPE_INT && (value < math.MinInt32 || value > math.MaxInt32) {
+			return nil, d.errorf(path, "%d is out of the range of int", value)
+		}
+		return json.Number(fmt.Sprint(value)), nil
+	case AVRO_TYPE_FLOAT:
+		bytes, err := d.read(path, 4)
+		if err != nil {
+			return nil, err
+		}
+		return jsonFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(bytes)))), nil
+	case AVRO_TYPE_DOUBLE:
+		bytes, err := d.read(path, 8)
+		if err != nil {
+			return nil, err
+		}
+		return jsonFloat(math.Float64frombits(binary.LittleEndian.Uint64(bytes))), nil
+	case AVRO_TYPE_BYTES, AVRO_TYPE_STRING:
+		length, err := d.readLong(path)
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := d.read(path, int(length))
+		if err != nil {
+			return nil, err
+		}
+		if s.Type == AVRO_TYPE_STRING {
+			if !utf8.Valid(bytes) {
+				return nil, d.errorf(path, "invalid UTF-8 string")
+			}
+			return string(bytes), nil
+		}
+		return avroBytesToJSON(bytes), nil
+	case AVRO_TYPE_FIXED:
+		bytes, err := d.read(path, s.Size)
+		if err != nil {
+			return nil, err
+		}
+		return avroBytesToJSON(bytes), nil
+	case AVRO_TYPE_ENUM:
+		index, err := d.readLong(path)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s.Symbols)) {
+			return nil, d.errorf(path, "invalid index %d for enum '%s'", index, s.Name)
+		}
+		return s.Symbols[index], nil
+	case AVRO_TYPE_ARRAY:
+		items := []interface{}{}
+		err := d.readBlocks(path, func() error {
+			item, err := d.decode(s.Items, fmt.Sprintf("%s[%d]", path, len(items)))
+			items = append(items, item)
+			return err
+		})
+		return items, err
+	case AVRO_TYPE_MAP:
+		values := map[string]interface{}{}
+		err := d.readBlocks(path, func() error {
+			key, err := d.decode(&AvroSchema{Type: AVRO_TYPE_STRING}, path)
+			if err != nil {
+				return err
+			}
+			value, err := d.decode(s.Values, fmt.Sprintf("%s[%q]", path, key))
+			values[key.(string)] = value
+			return err
+		})
+		return values, err
+	case AVRO_TYPE_RECORD:
+		record := map[string]interface{}{}
+		for _, field := range s.Fields {
+			value, err := d.decode(field.Type, joinPath(path, field.Name))
+			if err != nil {
+				return nil, err
+			}
+			record[field.Name] = value
+		}
+		return record, nil
+	case AVRO_TYPE_UNION:
+		index, err := d.readLong(path)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s.Branches)) {
+			return nil, d.errorf(path, "invalid union branch %d", index)
+		}
+		branch := s.Branches[index]
+		value, err := d.decode(branch, path)
+		if err != nil || branch.Type == AVRO_TYPE_NULL {
+			return nil, err
+		}
+		return map[string]interface{}{avroUnionBranchName(branch): value}, nil
+	default:
+		return nil, d.errorf(path, "unsupported type '%s'", s.Type)
+	}
+}
+
+// readBlocks reads the blocks of arrays and maps, calling readItem for every
+// item until the empty block.
+func (d *avroBinaryDecoder) readBlocks(path string, readItem func() error) error {
+	for {
+		count, err := d.readLong(path)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			// The size in bytes of the block follows negative counts
+			if _, err := d.readLong(path); err != nil {
+				return err
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// DecodeAvroBinary decodes data in the Avro binary encoding, returning the
+// value as represented in the Avro JSON encoding.
+func DecodeAvroBinary(s *AvroSchema, data []byte) (interface{}, error) {
+	decoder := avroBinaryDecoder{data: data}
+	value, err := decoder.decode(s, "")
+	if err != nil {
+		return nil, err
+	}
+	if decoder.position != len(data) {
+		return nil, fmt.Errorf("%d unexpected bytes after the message", len(data)-decoder.position)
+	}
+	return value, nil
+}
+
+// avroBytesToJSON represents bytes as the JSON encoding does, a string with
+// a code point per byte.
+func avroBytesToJSON(bytes []byte) string {
+	runes := make([]rune, len(bytes))
+	for i, b := range bytes {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func jsonFloat(value float64) interface{} {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Sprint(value)
+	}
+	return json.Number(fmt.Sprint(value))
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "message"
+	}
+	return fmt.Sprintf("field '%s'", path)
+}
+
+func describeJSONValue(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprintf("boolean %v", typed)
+	case json.Number:
+		return fmt.Sprintf("number %s", typed)
+	case string:
+		return fmt.Sprintf("string %q", typed)
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%v", typed)
+	}
+}
+
+func sortedJSONKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,220 @@
+package schema
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// normalizeAvroJSON returns the value in the Avro JSON encoding, accepting
+// plain JSON as well: union values without the {"type": value} wrapper and
+// records without the fields having a default. Every value not matching the
+// schema adds a problem.
+func normalizeAvroJSON(s *AvroSchema, value interface{}, path string, problems *[]string) interface{} {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s", displayPath(path), fmt.Sprintf(format, args...)))
+	}
+
+	switch s.Type {
+	case AVRO_TYPE_RECORD:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report("expected record '%s', found %s", s.Name, describeJSONValue(value))
+			return value
+		}
+		record := map[string]interface{}{}
+		for _, field := range s.Fields {
+			fieldValue, exists := object[field.Name]
+			if !exists {
+				if !field.HasDefault {
+					report("missing field '%s'", field.Name)
+					continue
+				}
+				fieldValue = field.Default
+			}
+			record[field.Name] = normalizeAvroJSON(field.Type, fieldValue, joinPath(path, field.Name), problems)
+		}
+		for _, key := range sortedJSONKeys(object) {
+			if !avroRecordHasField(s, key) {
+				report("unknown field '%s'", key)
+			}
+		}
+		return record
+	case AVRO_TYPE_ARRAY:
+		items, ok := value.([]interface{})
+		if !ok {
+			report("expected array, found %s", describeJSONValue(value))
+			return value
+		}
+		normalized := make([]interface{}, len(items))
+		for i, item := range items {
+			normalized[i] = normalizeAvroJSON(s.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+		return normalized
+	case AVRO_TYPE_MAP:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report("expected map, found %s", describeJSONValue(value))
+			return value
+		}
+		normalized := map[string]interface{}{}
+		for _, key := range sortedJSONKeys(object) {
+			normalized[key] = normalizeAvroJSON(s.Values, object[key], fmt.Sprintf("%s[%q]", path, key), problems)
+		}
+		return normalized
+	case AVRO_TYPE_UNION:
+		if value == nil {
+			for _, branch := range s.Branches {
+				if branch.Type == AVRO_TYPE_NULL {
+					return nil
+				}
+			}
+			report("null is not allowed")
+			return value
+		}
+
+		if object, ok := value.(map[string]interface{}); ok && len(object) == 1 {
+			for name, branchValue := range object {
+				for _, branch := range s.Branches {
+					if avroUnionBranchName(branch) == name {
+						return map[string]interface{}{name: normalizeAvroJSON(branch, branchValue, path, problems)}
+					}
+				}
+			}
+		}
+
+		for _, branch := range s.Branches {
+			if branch.Type == AVRO_TYPE_NULL {
+				continue
+			}
+			branchProblems := []string{}
+			normalized := normalizeAvroJSON(branch, value, path, &branchProblems)
+			if len(branchProblems) == 0 {
+				return map[string]interface{}{avroUnionBranchName(branch): normalized}
+			}
+		}
+		report("%s doesn't match any type of the union", describeJSONValue(value))
+		return value
+	default:
+		validateAvroJSON(s, value, path, problems)
+		return value
+	}
+}
+
+// appendAvroLong appends the zig-zag variable length encoding of a long.
+func appendAvroLong(data []byte, value int64) []byte {
+	return binary.AppendUvarint(data, uint64((value<<1)^(value>>63)))
+}
+
+// appendAvroBinary appends the binary encoding of a value already validated
+// in the Avro JSON encoding.
+func appendAvroBinary(data []byte, s *AvroSchema, value interface{}) []byte {
+	switch s.Type {
+	case AVRO_TYPE_NULL:
+		return data
+	case AVRO_TYPE_BOOLEAN:
+		if value.(bool) {
+			return append(data, 1)
+		}
+		return append(data, 0)
+	case AVRO_TYPE_INT, AVRO_TYPE_LONG:
+		integer, _ := value.(json.Number).Int64()
+		return appendAvroLong(data, integer)
+	case AVRO_TYPE_FLOAT:
+		return binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(avroFloat(value))))
+	case AVRO_TYPE_DOUBLE:
+		return binary.LittleEndian.AppendUint64(data, math.Float64bits(avroFloat(value)))
+	case AVRO_TYPE_STRING:
+		text := value.(string)
+		return append(appendAvroLong(data, int64(len(text))), text...)
+	case AVRO_TYPE_BYTES:
+		bytes := avroJSONToBytes(value.(string))
+		return append(appendAvroLong(data, int64(len(bytes))), bytes...)
+	case AVRO_TYPE_FIXED:
+		return append(data, avroJSONToBytes(value.(string))...)
+	case AVRO_TYPE_ENUM:
+		for i, symbol := range s.Symbols {
+			if symbol == value.(string) {
+				return appendAvroLong(data, int64(i))
+			}
+		}
+		return data
+	case AVRO_TYPE_ARRAY:
+		items := value.([]interface{})
+		if len(items) > 0 {
+			data = appendAvroLong(data, int64(len(items)))
+			for _, item := range items {
+				data = appendAvroBinary(data, s.Items, item)
+			}
+		}
+		return appendAvroLong(data, 0)
+	case AVRO_TYPE_MAP:
+		object := value.(map[string]interface{})
+		if len(object) > 0 {
+			data = appendAvroLong(data, int64(len(object)))
+			for _, key := range sortedJSONKeys(object) {
+				data = appendAvroLong(data, int64(len(key)))
+				data = append(data, key...)
+				data = appendAvroBinary(data, s.Values, object[key])
+			}
+		}
+		return appendAvroLong(data, 0)
+	case AVRO_TYPE_RECORD:
+		object := value.(map[string]interface{})
+		for _, field := range s.Fields {
+			data = appendAvroBinary(data, field.Type, object[field.Name])
+		}
+		return data
+	case AVRO_TYPE_UNION:
+		for i, branch := range s.Branches {
+			if value == nil && branch.Type == AVRO_TYPE_NULL {
+				return appendAvroLong(data, int64(i))
+			}
+			if object, ok := value.(map[string]interface{}); ok {
+				if branchValue, exists := object[avroUnionBranchName(branch)]; exists {
+					return appendAvroBinary(appendAvroLong(data, int64(i)), branch, branchValue)
+				}
+			}
+		}
+		return data
+	default:
+		return data
+	}
+}
+
+// avroFloat reads a float or double of the JSON encoding, where NaN and the
+// infinities are strings.
+func avroFloat(value interface{}) float64 {
+	var text string
+	switch typed := value.(type) {
+	case json.Number:
+		text = typed.String()
+	case string:
+		text = typed
+	}
+	number, _ := strconv.ParseFloat(text, 64)
+	return number
+}
+
+// avroJSONToBytes reverses avroBytesToJSON.
+func avroJSONToBytes(text string) []byte {
+	bytes := []byte{}
+	for _, r := range text {
+		bytes = append(bytes, byte(r))
+	}
+	return bytes
+}
+
+// EncodeAvroBinary encodes a JSON-shaped value (decoded using json.Number)
+// in the Avro binary encoding. The problems found are returned as a
+// *ValidationError.
+func EncodeAvroBinary(s *AvroSchema, value interface{}) ([]byte, error) {
+	problems := []string{}
+	normalized := normalizeAvroJSON(s, value, "", &problems)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return appendAvroBinary([]byte{}, s, normalized), nil
+}
@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Avro_Parse_Record(t *testing.T) {
+	avroSchema, err := ParseAvro(`{
+    "type": "record",
+    "name": "Product",
+    "namespace": "com.example",
+    "fields": [
+      {"name": "name", "type": "string", "default": ""},
+      {"name": "tags", "type": {"type": "array", "items": "string"}},
+      {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "RETIRED"]}},
+      {"name": "previous", "type": ["null", "Status"]},
+      {"name": "parent", "type": ["null", "com.example.Product"]}
+    ]
+  }`)
+	assert.NoError(t, err)
+	assert.Equal(t, AVRO_TYPE_RECORD, avroSchema.Type)
+	assert.Equal(t, "com.example.Product", avroSchema.Name)
+	assert.Equal(t, 5, len(avroSchema.Fields))
+	assert.True(t, avroSchema.Fields[0].HasDefault)
+	assert.Equal(t, AVRO_TYPE_ARRAY, avroSchema.Fields[1].Type.Type)
+	assert.Equal(t, "com.example.Status", avroSchema.Fields[2].Type.Name)
+	assert.Same(t, avroSchema.Fields[2].Type, avroSchema.Fields[3].Type.Branches[1])
+	assert.Same(t, avroSchema, avroSchema.Fields[4].Type.Branches[1])
+}
+
+func Test_Avro_Parse_Invalid(t *testing.T) {
+	definitions := map[string]string{
+		"not json":            `{"type":`,
+		"unknown type":        `{"type": "record", "name": "A", "fields": [{"name": "b", "type": "Missing"}]}`,
+		"duplicated field":    `{"type": "record", "name": "A", "fields": [{"name": "b", "type": "int"}, {"name": "b", "type": "int"}]}`,
+		"duplicated branch":   `["null", "int", "null"]`,
+		"nested union":        `["null", ["int"]]`,
+		"enum without values": `{"type": "enum", "name": "A"}`,
+		"invalid name":        `{"type": "fixed", "name": "1A", "size": 4}`,
+		"array without items": `{"type": "array"}`,
+	}
+
+	for description, definition := range definitions {
+		_, err := ParseAvro(definition)
+		assert.Error(t, err, description)
+	}
+}
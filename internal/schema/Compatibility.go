@@ -0,0 +1,404 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Compatibility modes between two revisions of a schema, named as in schema
+// registries.
+const (
+	// COMPATIBILITY_BACKWARD requires the new revision to read the messages
+	// written with the previous one.
+	COMPATIBILITY_BACKWARD = "BACKWARD"
+	// COMPATIBILITY_FORWARD requires the previous revision to read the
+	// messages written with the new one.
+	COMPATIBILITY_FORWARD = "FORWARD"
+	// COMPATIBILITY_FULL requires both.
+	COMPATIBILITY_FULL = "FULL"
+	// COMPATIBILITY_NONE doesn't check anything.
+	COMPATIBILITY_NONE = "NONE"
+)
+
+// ParseCompatibility returns the compatibility mode with the given name,
+// BACKWARD when empty.
+func ParseCompatibility(mode string) (string, error) {
+	switch strings.ToUpper(mode) {
+	case "":
+		return COMPATIBILITY_BACKWARD, nil
+	case COMPATIBILITY_BACKWARD, COMPATIBILITY_FORWARD, COMPATIBILITY_FULL, COMPATIBILITY_NONE:
+		return strings.ToUpper(mode), nil
+	default:
+		return "", fmt.Errorf("unknown compatibility mode '%s'", mode)
+	}
+}
+
+// CheckCompatibility returns the problems found moving from the previous
+// definition to the current one with the given compatibility mode. Renamed
+// fields are reported in every mode but NONE, as the data of the old field
+// is silently lost even when both fields have defaults.
+func CheckCompatibility(mode string, previous, current *Definition) []string {
+	problems := []string{}
+	if mode == COMPATIBILITY_NONE {
+		return problems
+	}
+
+	if previous.Type != current.Type {
+		return append(problems, fmt.Sprintf("message: type changed from %s to %s", previous.Type, current.Type))
+	}
+
+	checker := compatibilityChecker{problems: &problems, reported: map[string]bool{}}
+	if previous.Avro != nil {
+		checker.checkAvroRenames(previous.Avro, current.Avro, "", map[*AvroSchema]bool{})
+		if mode == COMPATIBILITY_BACKWARD || mode == COMPATIBILITY_FULL {
+			checker.checkAvroReadable(current.Avro, previous.Avro, "", true, map[[2]*AvroSchema]bool{})
+		}
+		if mode == COMPATIBILITY_FORWARD || mode == COMPATIBILITY_FULL {
+			checker.checkAvroReadable(previous.Avro, current.Avro, "", false, map[[2]*AvroSchema]bool{})
+		}
+	} else {
+		checker.checkProtoMessage(previous.ProtobufMessage(), current.ProtobufMessage(), "", mode, map[[2]*ProtoMessage]bool{})
+	}
+	return problems
+}
+
+type compatibilityChecker struct {
+	problems *[]string
+	// reported avoids repeating the same problem found in both directions.
+	reported map[string]bool
+}
+
+func (c compatibilityChecker) report(path, format string, args ...interface{}) {
+	problem := fmt.Sprintf("%s: %s", displayPath(path), fmt.Sprintf(format, args...))
+	if c.reported[problem] {
+		return
+	}
+	c.reported[problem] = true
+	*c.problems = append(*c.problems, problem)
+}
+
+// avroPromotions are the types data written with the key type can be read
+// as, besides the same type.
+var avroPromotions = map[AvroType][]AvroType{
+	AVRO_TYPE_INT:    {AVRO_TYPE_LONG, AVRO_TYPE_FLOAT, AVRO_TYPE_DOUBLE},
+	AVRO_TYPE_LONG:   {AVRO_TYPE_FLOAT, AVRO_TYPE_DOUBLE},
+	AVRO_TYPE_FLOAT:  {AVRO_TYPE_DOUBLE},
+	AVRO_TYPE_STRING: {AVRO_TYPE_BYTES},
+	AVRO_TYPE_BYTES:  {AVRO_TYPE_STRING},
+}
+
+func avroTypeName(s *AvroSchema) string {
+	if s.Name != "" {
+		return fmt.Sprintf("%s '%s'", s.Type, s.Name)
+	}
+	return string(s.Type)
+}
+
+// avroMatches tells whether the reader and writer schemas are of the same
+// kind, following the schema resolution rules of the Avro specification.
+func avroMatches(reader, writer *AvroSchema) bool {
+	if reader.Type == writer.Type {
+		switch reader.Type {
+		case AVRO_TYPE_RECORD, AVRO_TYPE_ENUM, AVRO_TYPE_FIXED:
+			return avroUnqualifiedName(reader.Name) == avroUnqualifiedName(writer.Name)
+		}
+		return true
+	}
+	for _, promoted := range avroPromotions[writer.Type] {
+		if promoted == reader.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func avroUnqualifiedName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// checkAvroReadable reports what prevents the reader from reading the data
+// written with the writer. newReader tells whether the reader is the current
+// revision, to describe the problems as the change done.
+func (c compatibilityChecker) checkAvroReadable(reader, writer *AvroSchema, path string, newReader bool, visited map[[2]*AvroSchema]bool) {
+	if visited[[2]*AvroSchema{reader, writer}] {
+		return
+	}
+	visited[[2]*AvroSchema{reader, writer}] = true
+
+	reportNotAllowed := func(writer *AvroSchema) {
+		if newReader {
+			c.report(path, "%s is no longer allowed", avroTypeName(writer))
+		} else {
+			c.report(path, "%s is not allowed by the previous revision", avroTypeName(writer))
+		}
+	}
+
+	if writer.Type == AVRO_TYPE_UNION {
+		for _, branch := range writer.Branches {
+			if reader.Type != AVRO_TYPE_UNION && !avroMatches(reader, branch) {
+				reportNotAllowed(branch)
+				continue
+			}
+			c.checkAvroReadable(reader, branch, path, newReader, visited)
+		}
+		return
+	}
+
+	if reader.Type == AVRO_TYPE_UNION {
+		for _, branch := range reader.Branches {
+			if avroMatches(branch, writer) {
+				c.checkAvroReadable(branch, writer, path, newReader, visited)
+				return
+			}
+		}
+		reportNotAllowed(writer)
+		return
+	}
+
+	if !avroMatches(reader, writer) {
+		if newReader {
+			c.report(path, "type changed from %s to %s", avroTypeName(writer), avroTypeName(reader))
+		} else {
+			c.report(path, "type changed from %s to %s", avroTypeName(reader), avroTypeName(writer))
+		}
+		return
+	}
+
+	switch reader.Type {
+	case AVRO_TYPE_RECORD:
+		for _, readerField := range reader.Fields {
+			writerField, exists := findAvroWriterField(writer, readerField)
+			if exists {
+				c.checkAvroReadable(readerField.Type, writerField.Type, joinPath(path, readerField.Name), newReader, visited)
+				continue
+			}
+			if readerField.HasDefault {
+				continue
+			}
+			if newReader {
+				c.report(path, "field '%s' was added without a default", readerField.Name)
+			} else {
+				c.report(path, "field '%s' was removed and has no default", readerField.Name)
+			}
+		}
+	case AVRO_TYPE_ENUM:
+		for _, symbol := range writer.Symbols {
+			if !containsString(reader.Symbols, symbol) {
+				if newReader {
+					c.report(path, "symbol '%s' was removed from enum '%s'", symbol, reader.Name)
+				} else {
+					c.report(path, "symbol '%s' was added to enum '%s'", symbol, reader.Name)
+				}
+			}
+		}
+	case AVRO_TYPE_FIXED:
+		if reader.Size != writer.Size {
+			c.report(path, "size of fixed '%s' changed", reader.Name)
+		}
+	case AVRO_TYPE_ARRAY:
+		c.checkAvroReadable(reader.Items, writer.Items, path+"[]", newReader, visited)
+	case AVRO_TYPE_MAP:
+		c.checkAvroReadable(reader.Values, writer.Values, path+"[]", newReader, visited)
+	}
+}
+
+// findAvroWriterField returns the field of the writer record read by the
+// field of the reader, by name or by one of its aliases.
+func findAvroWriterField(writer *AvroSchema, readerField AvroField) (AvroField, bool) {
+	for _, writerField := range writer.Fields {
+		if writerField.Name == readerField.Name || containsString(readerField.Aliases, writerField.Name) {
+			return writerField, true
+		}
+	}
+	return AvroField{}, false
+}
+
+// checkAvroRenames reports the fields of the records that were replaced, in
+// the same position, by a new field of the same type not declaring the old
+// name as alias.
+func (c compatibilityChecker) checkAvroRenames(previous, current *AvroSchema, path string, visited map[*AvroSchema]bool) {
+	if previous.Type != current.Type || visited[previous] {
+		return
+	}
+	visited[previous] = true
+
+	switch previous.Type {
+	case AVRO_TYPE_RECORD:
+		for i, previousField := range previous.Fields {
+			if i >= len(current.Fields) {
+				break
+			}
+			currentField := current.Fields[i]
+			if _, exists := findAvroWriterField(current, previousField); exists {
+				continue
+			}
+
+			if previousField.Name != currentField.Name &&
+				!avroRecordHasField(previous, currentField.Name) &&
+				!containsString(currentField.Aliases, previousField.Name) &&
+				avroTypeName(previousField.Type) == avroTypeName(currentField.Type) {
+				c.report(path, "field '%s' was renamed to '%s', add \"aliases\": [\"%s\"] to keep reading it", previousField.Name, currentField.Name, previousField.Name)
+			}
+		}
+		for _, currentField := range current.Fields {
+			if previousField, exists := findAvroWriterField(previous, currentField); exists {
+				c.checkAvroRenames(previousField.Type, currentField.Type, joinPath(path, currentField.Name), visited)
+			}
+		}
+	case AVRO_TYPE_ARRAY:
+		c.checkAvroRenames(previous.Items, current.Items, path+"[]", visited)
+	case AVRO_TYPE_MAP:
+		c.checkAvroRenames(previous.Values, current.Values, path+"[]", visited)
+	}
+}
+
+func avroRecordHasField(record *AvroSchema, name string) bool {
+	for _, field := range record.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// protoWireCompatibleTypes groups the scalar types sharing the same encoding,
+// which can be changed between them.
+var protoWireCompatibleTypes = map[string]string{
+	"int32": "varint", "int64": "varint", "uint32": "varint", "uint64": "varint", "bool": "varint",
+	"sint32": "zigzag", "sint64": "zigzag",
+	"fixed32": "fixed32", "sfixed32": "fixed32",
+	"fixed64": "fixed64", "sfixed64": "fixed64",
+	"string": "length", "bytes": "length",
+	"float": "float", "double": "double",
+}
+
+func protoTypeKind(field *ProtoField) string {
+	switch {
+	case field.MapValue != nil:
+		return "map"
+	case field.Message != nil:
+		return "message"
+	case field.Enum != nil:
+		// Enums are encoded as int32
+		return "varint"
+	default:
+		return protoWireCompatibleTypes[field.Type]
+	}
+}
+
+// checkProtoMessage compares the fields of two revisions of a message by
+// their numbers, which identify them in the binary encoding, while their
+// names identify them in the JSON encoding.
+func (c compatibilityChecker) checkProtoMessage(previous, current *ProtoMessage, path, mode string, visited map[[2]*ProtoMessage]bool) {
+	if visited[[2]*ProtoMessage{previous, current}] {
+		return
+	}
+	visited[[2]*ProtoMessage{previous, current}] = true
+
+	backward := mode == COMPATIBILITY_BACKWARD || mode == COMPATIBILITY_FULL
+	forward := mode == COMPATIBILITY_FORWARD || mode == COMPATIBILITY_FULL
+
+	for _, previousField := range previous.Fields {
+		currentField := current.fieldByNumber(previousField.Number)
+		if currentField == nil {
+			if forward && previousField.Label == "required" {
+				c.report(path, "required field '%s' was removed", previousField.Name)
+			}
+			if renamed := current.fieldByName(previousField.Name); renamed != nil {
+				c.report(path, "number of field '%s' changed from %d to %d", previousField.Name, previousField.Number, renamed.Number)
+			}
+			continue
+		}
+
+		fieldPath := joinPath(path, currentField.Name)
+		if previousField.Name != currentField.Name {
+			c.report(path, "field %d was renamed from '%s' to '%s'", previousField.Number, previousField.Name, currentField.Name)
+		}
+
+		previousKind, currentKind := protoTypeKind(previousField), protoTypeKind(currentField)
+		if previousKind != currentKind || (previousKind == "map" && previousField.MapKey != currentField.MapKey) {
+			c.report(fieldPath, "type changed from %s to %s", describeProtoType(previousField), describeProtoType(currentField))
+			continue
+		}
+		if (previousField.Label == "repeated") != (currentField.Label == "repeated") {
+			c.report(fieldPath, "changed between repeated and singular")
+		}
+		if backward && previousField.Label != "required" && currentField.Label == "required" {
+			c.report(fieldPath, "became required")
+		}
+
+		switch {
+		case previousField.MapValue != nil:
+			if protoTypeKind(previousField.MapValue) != protoTypeKind(currentField.MapValue) {
+				c.report(fieldPath, "type changed from %s to %s", describeProtoType(previousField), describeProtoType(currentField))
+			} else if previousField.MapValue.Message != nil {
+				c.checkProtoMessage(previousField.MapValue.Message, currentField.MapValue.Message, fieldPath+"[]", mode, visited)
+			}
+		case previousField.Message != nil:
+			c.checkProtoMessage(previousField.Message, currentField.Message, fieldPath, mode, visited)
+		case previousField.Enum != nil && currentField.Enum != nil:
+			c.checkProtoEnum(previousField.Enum, currentField.Enum, fieldPath, backward, forward)
+		}
+	}
+
+	if backward {
+		for _, currentField := range current.Fields {
+			if previous.fieldByNumber(currentField.Number) == nil && currentField.Label == "required" {
+				c.report(path, "required field '%s' was added", currentField.Name)
+			}
+		}
+	}
+}
+
+func (c compatibilityChecker) checkProtoEnum(previous, current *ProtoEnum, path string, backward, forward bool) {
+	previousNames := map[int]string{}
+	for _, value := range previous.Values {
+		previousNames[value.Number] = value.Name
+	}
+	currentNames := map[int]string{}
+	for _, value := range current.Values {
+		currentNames[value.Number] = value.Name
+	}
+
+	for _, value := range previous.Values {
+		name, exists := currentNames[value.Number]
+		switch {
+		case !exists && backward:
+			c.report(path, "value '%s' was removed from enum '%s'", value.Name, current.Name)
+		case exists && name != value.Name:
+			c.report(path, "value %d of enum '%s' was renamed from '%s' to '%s'", value.Number, current.Name, value.Name, name)
+		}
+	}
+	if forward {
+		for _, value := range current.Values {
+			if _, exists := previousNames[value.Number]; !exists {
+				c.report(path, "value '%s' was added to enum '%s'", value.Name, current.Name)
+			}
+		}
+	}
+}
+
+func (m *ProtoMessage) fieldByName(name string) *ProtoField {
+	for _, field := range m.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func describeProtoType(field *ProtoField) string {
+	if field.MapValue != nil {
+		return fmt.Sprintf("map<%s, %s>", field.MapKey, field.MapValue.Type)
+	}
+	return field.Type
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
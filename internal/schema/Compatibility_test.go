@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestDefinition(t *testing.T, schemaType, definition string) *Definition {
+	parsed, err := Parse(schemaType, definition)
+	assert.NoError(t, err)
+	return parsed
+}
+
+func Test_Compatibility_ParseMode(t *testing.T) {
+	mode, err := ParseCompatibility("")
+	assert.NoError(t, err)
+	assert.Equal(t, COMPATIBILITY_BACKWARD, mode)
+
+	mode, err = ParseCompatibility("full")
+	assert.NoError(t, err)
+	assert.Equal(t, COMPATIBILITY_FULL, mode)
+
+	_, err = ParseCompatibility("TRANSITIVE")
+	assert.Error(t, err)
+}
+
+func Test_Compatibility_AvroRenamedField(t *testing.T) {
+	previous := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Avro","fields":[{"name":"ProductName","type":"string","default":""},{"name":"SKU","type":"int","default":0}]}`)
+	current := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Avro","fields":[{"name":"ProductTitle","type":"string","default":""},{"name":"SKU","type":"int","default":0}]}`)
+
+	assert.Equal(t, []string{
+		`message: field 'ProductName' was renamed to 'ProductTitle', add "aliases": ["ProductName"] to keep reading it`,
+	}, CheckCompatibility(COMPATIBILITY_FULL, previous, current))
+
+	aliased := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Avro","fields":[{"name":"ProductTitle","type":"string","default":"","aliases":["ProductName"]},{"name":"SKU","type":"int","default":0}]}`)
+	assert.Empty(t, CheckCompatibility(COMPATIBILITY_FULL, previous, aliased))
+	assert.Empty(t, CheckCompatibility(COMPATIBILITY_NONE, previous, current))
+}
+
+func Test_Compatibility_AvroFields(t *testing.T) {
+	previous := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Product","fields":[
+    {"name":"name","type":"string"},
+    {"name":"sku","type":"int"},
+    {"name":"price","type":"string"},
+    {"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","RETIRED"]}}
+  ]}`)
+	current := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Product","fields":[
+    {"name":"sku","type":"long"},
+    {"name":"price","type":"double"},
+    {"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","SOLD"]}},
+    {"name":"stock","type":"int"}
+  ]}`)
+
+	assert.Equal(t, []string{
+		"field 'price': type changed from string to double",
+		"field 'status': symbol 'RETIRED' was removed from enum 'Status'",
+		"message: field 'stock' was added without a default",
+	}, CheckCompatibility(COMPATIBILITY_BACKWARD, previous, current))
+
+	assert.Equal(t, []string{
+		"message: field 'name' was removed and has no default",
+		"field 'sku': type changed from int to long",
+		"field 'price': type changed from string to double",
+		"field 'status': symbol 'SOLD' was added to enum 'Status'",
+	}, CheckCompatibility(COMPATIBILITY_FORWARD, previous, current))
+}
+
+func Test_Compatibility_AvroOptionalField(t *testing.T) {
+	previous := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Product","fields":[{"name":"name","type":"string"}]}`)
+	current := parseTestDefinition(t, TYPE_AVRO, `{"type":"record","name":"Product","fields":[{"name":"name","type":["null","string"]},{"name":"tags","type":{"type":"array","items":"string"},"default":[]}]}`)
+
+	assert.Empty(t, CheckCompatibility(COMPATIBILITY_BACKWARD, previous, current))
+	assert.Equal(t, []string{
+		"field 'name': null is not allowed by the previous revision",
+	}, CheckCompatibility(COMPATIBILITY_FORWARD, previous, current))
+}
+
+func Test_Compatibility_Protobuf(t *testing.T) {
+	previous := parseTestDefinition(t, TYPE_PROTOCOL_BUFFER, `
+    syntax = "proto3";
+    message Product {
+      enum Status { STATUS_UNSPECIFIED = 0; ACTIVE = 1; RETIRED = 2; }
+      string product_name = 1;
+      int32 sku = 2;
+      repeated string tags = 3;
+      Status status = 4;
+      string colour = 5;
+    }
+  `)
+	current := parseTestDefinition(t, TYPE_PROTOCOL_BUFFER, `
+    syntax = "proto3";
+    message Product {
+      enum Status { STATUS_UNSPECIFIED = 0; ACTIVE = 1; SOLD = 3; }
+      string product_title = 1;
+      int64 sku = 2;
+      string tags = 3;
+      Status status = 4;
+      double price = 6;
+    }
+  `)
+
+	assert.Equal(t, []string{
+		"message: field 1 was renamed from 'product_name' to 'product_title'",
+		"field 'tags': changed between repeated and singular",
+		"field 'status': value 'RETIRED' was removed from enum 'Status'",
+	}, CheckCompatibility(COMPATIBILITY_BACKWARD, previous, current))
+
+	assert.Equal(t, []string{
+		"message: field 1 was renamed from 'product_name' to 'product_title'",
+		"field 'tags': changed between repeated and singular",
+		"field 'status': value 'RETIRED' was removed from enum 'Status'",
+		"field 'status': value 'SOLD' was added to enum 'Status'",
+	}, CheckCompatibility(COMPATIBILITY_FULL, previous, current))
+
+	changedType := parseTestDefinition(t, TYPE_PROTOCOL_BUFFER, `syntax = "proto3"; message Product { double product_name = 1; }`)
+	assert.Equal(t, []string{
+		"field 'product_name': type changed from string to double",
+	}, CheckCompatibility(COMPATIBILITY_BACKWARD, previous, changedType))
+}
+
+func Test_Compatibility_DifferentTypes(t *testing.T) {
+	previous := parseTestDefinition(t, TYPE_AVRO, `"string"`)
+	current := parseTestDefinition(t, TYPE_PROTOCOL_BUFFER, `syntax = "proto3"; message Product { string name = 1; }`)
+
+	assert.Equal(t, []string{"message: type changed from AVRO to PROTOCOL_BUFFER"}, CheckCompatibility(COMPATIBILITY_BACKWARD, previous, current))
+}
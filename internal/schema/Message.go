@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Message encodings, as named by the Pub/Sub API.
+const (
+	ENCODING_UNSPECIFIED = "ENCODING_UNSPECIFIED"
+	ENCODING_JSON        = "JSON"
+	ENCODING_BINARY      = "BINARY"
+)
+
+// ValidationError lists every problem found when validating a message.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Definition is a parsed schema definition of any type.
+type Definition struct {
+	Type     string
+	Avro     *AvroSchema
+	Protobuf *ProtoFile
+}
+
+// Parse parses a definition of the given schema type.
+func Parse(schemaType, definition string) (*Definition, error) {
+	switch schemaType {
+	case TYPE_AVRO:
+		avroSchema, err := ParseAvro(definition)
+		if err != nil {
+			return nil, err
+		}
+		return &Definition{Type: schemaType, Avro: avroSchema}, nil
+	case TYPE_PROTOCOL_BUFFER:
+		protoFile, err := ParseProtobuf(definition)
+		if err != nil {
+			return nil, err
+		}
+		return &Definition{Type: schemaType, Protobuf: protoFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown schema type '%s'", schemaType)
+	}
+}
+
+// ProtobufMessage is the message the Protocol Buffers definition describes,
+// the single top level one.
+func (d *Definition) ProtobufMessage() *ProtoMessage {
+	return d.Protobuf.Messages[0]
+}
+
+// ValidateMessage checks the data of a message published with the given
+// encoding (JSON when unspecified) matches the definition. The problems
+// found are returned as a *ValidationError.
+func (d *Definition) ValidateMessage(encoding string, data []byte) error {
+	switch encoding {
+	case ENCODING_BINARY:
+		var err error
+		if d.Avro != nil {
+			_, err = DecodeAvroBinary(d.Avro, data)
+		} else {
+			_, err = DecodeProtobufBinary(d.ProtobufMessage(), data)
+		}
+		if err != nil {
+			return &ValidationError{Problems: []string{err.Error()}}
+		}
+		return nil
+	case ENCODING_JSON, ENCODING_UNSPECIFIED, "":
+		value, err := decodeJSONMessage(data)
+		if err != nil {
+			return err
+		}
+
+		problems := []string{}
+		if d.Avro != nil {
+			validateAvroJSON(d.Avro, value, "", &problems)
+		} else {
+			validateProtoJSON(d.ProtobufMessage(), value, "", &problems)
+		}
+		if len(problems) > 0 {
+			return &ValidationError{Problems: problems}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown encoding '%s'", encoding)
+	}
+}
+
+// decodeJSONMessage decodes a message in JSON keeping the numbers as
+// json.Number, so their precision and format can be checked.
+func decodeJSONMessage(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil, &ValidationError{Problems: []string{"message: invalid JSON"}}
+	}
+	return value, nil
+}
+
+func marshalJSONMessage(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+// EncodeMessage converts a message written as JSON into the payload to
+// publish with the given encoding: the binary encoding for BINARY and the
+// JSON encoding otherwise. Avro messages can be plain JSON, without the
+// {"type": value} wrapper of union values nor the fields with a default.
+// The problems found are returned as a *ValidationError.
+func (d *Definition) EncodeMessage(encoding string, data []byte) ([]byte, error) {
+	if encoding != ENCODING_BINARY && encoding != ENCODING_JSON && encoding != ENCODING_UNSPECIFIED && encoding != "" {
+		return nil, fmt.Errorf("unknown encoding '%s'", encoding)
+	}
+
+	value, err := decodeJSONMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	if d.Avro != nil {
+		normalized := normalizeAvroJSON(d.Avro, value, "", &problems)
+		if len(problems) > 0 {
+			return nil, &ValidationError{Problems: problems}
+		}
+		if encoding == ENCODING_BINARY {
+			return appendAvroBinary([]byte{}, d.Avro, normalized), nil
+		}
+		return marshalJSONMessage(normalized)
+	}
+
+	validateProtoJSON(d.ProtobufMessage(), value, "", &problems)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	if encoding == ENCODING_BINARY {
+		return appendProtoMessage([]byte{}, d.ProtobufMessage(), value.(map[string]interface{})), nil
+	}
+	return marshalJSONMessage(value)
+}
+
+// DecodeMessage returns the JSON representation of a payload published with
+// the given encoding, decoding the binary encoding.
+func (d *Definition) DecodeMessage(encoding string, data []byte) ([]byte, error) {
+	if encoding != ENCODING_BINARY {
+		if err := d.ValidateMessage(encoding, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	var value interface{}
+	var err error
+	if d.Avro != nil {
+		value, err = DecodeAvroBinary(d.Avro, data)
+	} else {
+		value, err = DecodeProtobufBinary(d.ProtobufMessage(), data)
+	}
+	if err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	return marshalJSONMessage(value)
+}
@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const productAvroDefinition = `{
+  "type": "record",
+  "name": "Product",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "sku", "type": "int"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["ACTIVE", "RETIRED"]}, "default": "ACTIVE"},
+    {"name": "tags", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "discount", "type": ["null", "double"], "default": null}
+  ]
+}`
+
+const productProtobufDefinition = `
+syntax = "proto3";
+
+message Product {
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    ACTIVE = 1;
+  }
+
+  string product_name = 1;
+  int32 sku = 2;
+  repeated int32 sizes = 3;
+  Status status = 4;
+  map<string, string> metadata = 5;
+}
+`
+
+func Test_Message_ValidateAvroJSON(t *testing.T) {
+	definition, err := Parse(TYPE_AVRO, productAvroDefinition)
+	assert.NoError(t, err)
+
+	assert.NoError(t, definition.ValidateMessage(ENCODING_JSON, []byte(`{"name": "Shoe", "sku": 42, "status": "ACTIVE", "tags": [], "discount": {"double": 0.5}}`)))
+	assert.NoError(t, definition.ValidateMessage(ENCODING_UNSPECIFIED, []byte(`{"name": "Shoe", "sku": 42, "status": "RETIRED", "tags": ["a"], "discount": null}`)))
+
+	err = definition.ValidateMessage(ENCODING_JSON, []byte(`{"sku": 3000000000, "status": "SOLD", "tags": [1], "discount": 0.5, "colour": "red"}`))
+	assert.Equal(t, &ValidationError{Problems: []string{
+		"message: missing field 'name'",
+		"field 'sku': 3000000000 is out of the range of int",
+		"field 'status': 'SOLD' is not a symbol of enum 'Status'",
+		"field 'tags[0]': expected string, found number 1",
+		"field 'discount': expected a union value like {\"<type>\": value}, found number 0.5",
+		"message: unknown field 'colour'",
+	}}, err)
+
+	// Pub/Sub rejects the JSON messages missing fields with a default
+	err = definition.ValidateMessage(ENCODING_JSON, []byte(`{"name": "Shoe", "sku": 42}`))
+	assert.Equal(t, &ValidationError{Problems: []string{
+		"message: missing field 'status', fields with a default are required in the JSON encoding too",
+		"message: missing field 'tags', fields with a default are required in the JSON encoding too",
+		"message: missing field 'discount', fields with a default are required in the JSON encoding too",
+	}}, err)
+
+	err = definition.ValidateMessage(ENCODING_JSON, []byte(`{"name": "Shoe"`))
+	assert.EqualError(t, err, "message: invalid JSON")
+}
+
+func Test_Message_ValidateAvroBinary(t *testing.T) {
+	definition, err := Parse(TYPE_AVRO, productAvroDefinition)
+	assert.NoError(t, err)
+
+	// "Shoe", 42, RETIRED, ["a"], 0.5
+	valid := []byte{0x08, 'S', 'h', 'o', 'e', 0x54, 0x02, 0x02, 0x02, 'a', 0x00, 0x02, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f}
+	assert.NoError(t, definition.ValidateMessage(ENCODING_BINARY, valid))
+
+	value, err := DecodeAvroBinary(definition.Avro, valid)
+	assert.NoError(t, err)
+	assert.Equal(t, "RETIRED", value.(map[string]interface{})["status"])
+	assert.Equal(t, map[string]interface{}{"double": jsonFloat(0.5)}, value.(map[string]interface{})["discount"])
+
+	err = definition.ValidateMessage(ENCODING_BINARY, []byte{0x08, 'S', 'h', 'o', 'e', 0x54, 0x06})
+	assert.EqualError(t, err, "field 'status': invalid index 3 for enum 'Status' (at byte 7)")
+
+	err = definition.ValidateMessage(ENCODING_BINARY, []byte{0x08, 'S', 'h'})
+	assert.EqualError(t, err, "field 'name': unexpected end of data (at byte 1)")
+
+	err = definition.ValidateMessage(ENCODING_BINARY, append(valid, 0x00))
+	assert.EqualError(t, err, "1 unexpected bytes after the message")
+}
+
+func Test_Message_ValidateProtobufJSON(t *testing.T) {
+	definition, err := Parse(TYPE_PROTOCOL_BUFFER, productProtobufDefinition)
+	assert.NoError(t, err)
+
+	assert.NoError(t, definition.ValidateMessage(ENCODING_JSON, []byte(`{"productName": "Shoe", "sku": 42, "sizes": [40, "41"], "status": "ACTIVE", "metadata": {"colour": "red"}}`)))
+	assert.NoError(t, definition.ValidateMessage(ENCODING_JSON, []byte(`{"product_name": "Shoe", "status": 1}`)))
+
+	err = definition.ValidateMessage(ENCODING_JSON, []byte(`{"productName": 1, "sku": 3000000000, "status": "SOLD", "colour": "red"}`))
+	assert.Equal(t, &ValidationError{Problems: []string{
+		"message: unknown field 'colour'",
+		"field 'product_name': expected string, found number 1",
+		"field 'sku': 3000000000 is out of the range of int32",
+		"field 'status': 'SOLD' is not a value of enum 'Status'",
+	}}, err)
+}
+
+func Test_Message_ValidateProtobufBinary(t *testing.T) {
+	definition, err := Parse(TYPE_PROTOCOL_BUFFER, productProtobufDefinition)
+	assert.NoError(t, err)
+
+	// product_name "Shoe", sku 42, packed sizes [40, 41], status ACTIVE
+	valid := []byte{0x0a, 0x04, 'S', 'h', 'o', 'e', 0x10, 0x2a, 0x1a, 0x02, 0x28, 0x29, 0x20, 0x01}
+	assert.NoError(t, definition.ValidateMessage(ENCODING_BINARY, valid))
+
+	value, err := DecodeProtobufBinary(definition.ProtobufMessage(), valid)
+	assert.NoError(t, err)
+	assert.Equal(t, "Shoe", value["productName"])
+	assert.Equal(t, "ACTIVE", value["status"])
+
+	// product_name declared with the varint wire type
+	err = definition.ValidateMessage(ENCODING_BINARY, []byte{0x08, 0x01})
+	assert.EqualError(t, err, "field 'product_name': invalid wire type 0 (at byte 1)")
+
+	err = definition.ValidateMessage(ENCODING_BINARY, []byte{0x0a, 0x10, 'S'})
+	assert.Error(t, err)
+}
+
+func Test_Message_ValidateUnknownEncoding(t *testing.T) {
+	definition, err := Parse(TYPE_AVRO, `"string"`)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, definition.ValidateMessage("XML", []byte(`"a"`)), "unknown encoding 'XML'")
+}
+
+func Test_Message_EncodeAvro(t *testing.T) {
+	definition, err := Parse(TYPE_AVRO, productAvroDefinition)
+	assert.NoError(t, err)
+
+	// Plain JSON: bare union value and defaults filled
+	payload, err := definition.EncodeMessage(ENCODING_BINARY, []byte(`{"name": "Shoe", "sku": 42, "discount": 0.5}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 'S', 'h', 'o', 'e', 0x54, 0x00, 0x00, 0x02, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f}, payload)
+
+	decoded, err := definition.DecodeMessage(ENCODING_BINARY, payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Shoe", "sku": 42, "status": "ACTIVE", "tags": [], "discount": {"double": 0.5}}`, string(decoded))
+
+	payload, err = definition.EncodeMessage(ENCODING_JSON, []byte(`{"name": "Shoe", "sku": 42, "discount": null}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Shoe", "sku": 42, "status": "ACTIVE", "tags": [], "discount": null}`, string(payload))
+
+	_, err = definition.EncodeMessage(ENCODING_BINARY, []byte(`{"name": "Shoe", "sku": 42, "discount": "half"}`))
+	assert.Equal(t, &ValidationError{Problems: []string{
+		`field 'discount': string "half" doesn't match any type of the union`,
+	}}, err)
+}
+
+func Test_Message_EncodeProtobuf(t *testing.T) {
+	definition, err := Parse(TYPE_PROTOCOL_BUFFER, productProtobufDefinition)
+	assert.NoError(t, err)
+
+	payload, err := definition.EncodeMessage(ENCODING_BINARY, []byte(`{"productName": "Shoe", "sku": 42, "sizes": [1, 2], "status": "ACTIVE", "metadata": {"a": "b"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x0a, 0x04, 'S', 'h', 'o', 'e',
+		0x10, 0x2a,
+		0x1a, 0x02, 0x01, 0x02,
+		0x20, 0x01,
+		0x2a, 0x06, 0x0a, 0x01, 'a', 0x12, 0x01, 'b',
+	}, payload)
+	assert.NoError(t, definition.ValidateMessage(ENCODING_BINARY, payload))
+
+	decoded, err := definition.DecodeMessage(ENCODING_BINARY, payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"productName": "Shoe", "sku": 42, "sizes": [1, 2], "status": "ACTIVE", "metadata": {"a": "b"}}`, string(decoded))
+
+	_, err = definition.EncodeMessage(ENCODING_BINARY, []byte(`{"sku": "many"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,830 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Scalar types of Protocol Buffers, any other type is a message or an enum.
+var protoScalarTypes = map[string]bool{
+	"double": true, "float": true,
+	"int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true,
+	"sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+const (
+	protoMaxFieldNumber           = 536_870_911
+	protoReservedFieldNumberStart = 19_000
+	protoReservedFieldNumberEnd   = 19_999
+)
+
+type ProtoFile struct {
+	Syntax   string
+	Package  string
+	Messages []*ProtoMessage
+	Enums    []*ProtoEnum
+}
+
+type ProtoMessage struct {
+	Name string
+	// FullName includes the package and the enclosing messages.
+	FullName string
+	Fields   []*ProtoField
+	Messages []*ProtoMessage
+	Enums    []*ProtoEnum
+}
+
+type ProtoField struct {
+	Name   string
+	Number int
+	// Label is "optional", "required", "repeated" or empty.
+	Label string
+	// Type is the type as written, scalar types are resolved by name and
+	// the others through Message or Enum.
+	Type    string
+	Message *ProtoMessage
+	Enum    *ProtoEnum
+	// OneOf is the name of the oneof the field belongs to, if any.
+	OneOf string
+	// MapKey and MapValue are set for map<key, value> fields.
+	MapKey   string
+	MapValue *ProtoField
+}
+
+type ProtoEnum struct {
+	Name     string
+	FullName string
+	Values   []ProtoEnumValue
+}
+
+type ProtoEnumValue struct {
+	Name   string
+	Number int
+}
+
+// Message returns the message with the given name, full or relative to the
+// package.
+func (f *ProtoFile) Message(name string) *ProtoMessage {
+	var found *ProtoMessage
+	var search func(messages []*ProtoMessage)
+	search = func(messages []*ProtoMessage) {
+		for _, message := range messages {
+			if found != nil {
+				return
+			}
+			if message.FullName == name || message.FullName == f.qualify(name) {
+				found = message
+				return
+			}
+			search(message.Messages)
+		}
+	}
+	search(f.Messages)
+	return found
+}
+
+func (f *ProtoFile) qualify(name string) string {
+	if f.Package == "" {
+		return name
+	}
+	return f.Package + "." + name
+}
+
+// ParseProtobuf parses a Protocol Buffers definition (the content of a .proto
+// file) returning an error describing the first problem found. As required
+// by Pub/Sub, the definition must have a single top level message and can't
+// import other files.
+func ParseProtobuf(definition string) (*ProtoFile, error) {
+	tokens, err := tokenizeProto(definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Protocol Buffers schema: %w", err)
+	}
+
+	parser := protoParser{tokens: tokens, file: &ProtoFile{Syntax: "proto2"}}
+	if err := parser.parseFile(); err != nil {
+		return nil, fmt.Errorf("invalid Protocol Buffers schema: %w", err)
+	}
+	if err := parser.resolve(); err != nil {
+		return nil, fmt.Errorf("invalid Protocol Buffers schema: %w", err)
+	}
+
+	if len(parser.file.Messages) != 1 {
+		return nil, fmt.Errorf("invalid Protocol Buffers schema: exactly one top level message is required, found %d", len(parser.file.Messages))
+	}
+
+	return parser.file, nil
+}
+
+type protoTokenKind int
+
+const (
+	protoTokenIdentifier protoTokenKind = iota
+	protoTokenNumber
+	protoTokenString
+	protoTokenSymbol
+)
+
+type protoToken struct {
+	kind  protoTokenKind
+	value string
+	line  int
+}
+
+func tokenizeProto(definition string) ([]protoToken, error) {
+	tokens := []protoToken{}
+	runes := []rune(definition)
+	line := 1
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			for i += 2; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+				if runes[i] == '\n' {
+					line++
+				}
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", start)
+			}
+			i += 2
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' {
+					i++
+				}
+				if i < len(runes) && runes[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated string", line)
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			i++
+			tokens = append(tokens, protoToken{protoTokenString, string(runes[start:i]), line})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, protoToken{protoTokenIdentifier, string(runes[start:i]), line})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, protoToken{protoTokenNumber, string(runes[start:i]), line})
+		case r == '.':
+			// Leading dot of a fully qualified type name.
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, protoToken{protoTokenIdentifier, string(runes[start:i]), line})
+		case strings.ContainsRune("{}[]()<>;,=-+:", r):
+			tokens = append(tokens, protoToken{protoTokenSymbol, string(r), line})
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character '%c'", line, r)
+		}
+	}
+
+	return tokens, nil
+}
+
+type protoParser struct {
+	tokens   []protoToken
+	position int
+	file     *ProtoFile
+}
+
+func (p *protoParser) peek() (protoToken, bool) {
+	if p.position >= len(p.tokens) {
+		return protoToken{}, false
+	}
+	return p.tokens[p.position], true
+}
+
+func (p *protoParser) next() (protoToken, error) {
+	token, exists := p.peek()
+	if !exists {
+		return protoToken{}, fmt.Errorf("unexpected end of the definition")
+	}
+	p.position++
+	return token, nil
+}
+
+func (p *protoParser) errorAt(token protoToken, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", token.line, fmt.Sprintf(format, args...))
+}
+
+func (p *protoParser) expect(value string) error {
+	token, err := p.next()
+	if err != nil {
+		return fmt.Errorf("expected '%s': %w", value, err)
+	}
+	if token.value != value || token.kind == protoTokenString {
+		return p.errorAt(token, "expected '%s', found '%s'", value, token.value)
+	}
+	return nil
+}
+
+func (p *protoParser) nextIs(value string) bool {
+	token, exists := p.peek()
+	return exists && token.kind != protoTokenString && token.value == value
+}
+
+func (p *protoParser) identifier() (protoToken, error) {
+	token, err := p.next()
+	if err != nil {
+		return token, err
+	}
+	if token.kind != protoTokenIdentifier {
+		return token, p.errorAt(token, "expected an identifier, found '%s'", token.value)
+	}
+	return token, nil
+}
+
+func (p *protoParser) integer() (int, error) {
+	negative := false
+	if p.nextIs("-") {
+		p.position++
+		negative = true
+	}
+
+	token, err := p.next()
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(token.value, 0, 64)
+	if token.kind != protoTokenNumber || err != nil {
+		return 0, p.errorAt(token, "expected an integer, found '%s'", token.value)
+	}
+	if negative {
+		value = -value
+	}
+	return int(value), nil
+}
+
+// skipConstant skips an option value, including aggregates between braces.
+func (p *protoParser) skipConstant() error {
+	if p.nextIs("-") || p.nextIs("+") {
+		p.position++
+	}
+
+	token, err := p.next()
+	if err != nil {
+		return err
+	}
+	if token.kind == protoTokenSymbol {
+		if token.value != "{" {
+			return p.errorAt(token, "unexpected '%s'", token.value)
+		}
+		return p.skipBlock()
+	}
+	// Adjacent strings are concatenated.
+	for token.kind == protoTokenString {
+		next, exists := p.peek()
+		if !exists || next.kind != protoTokenString {
+			break
+		}
+		token, _ = p.next()
+	}
+	return nil
+}
+
+// skipBlock skips until the brace closing an already consumed '{'.
+func (p *protoParser) skipBlock() error {
+	depth := 1
+	for depth > 0 {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		if token.kind == protoTokenSymbol {
+			switch token.value {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// skipStatement skips until the semicolon ending the current statement.
+func (p *protoParser) skipStatement() error {
+	for {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		if token.kind == protoTokenSymbol && token.value == ";" {
+			return nil
+		}
+	}
+}
+
+// skipOptionName skips names like `deprecated` or `(custom.option).field`.
+func (p *protoParser) skipOptionName() error {
+	for {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		if token.kind == protoTokenSymbol && token.value == "(" {
+			if _, err := p.identifier(); err != nil {
+				return err
+			}
+			if err := p.expect(")"); err != nil {
+				return err
+			}
+		} else if token.kind != protoTokenIdentifier {
+			return p.errorAt(token, "invalid option name '%s'", token.value)
+		}
+
+		next, exists := p.peek()
+		if !exists || next.kind != protoTokenIdentifier || !strings.HasPrefix(next.value, ".") {
+			return nil
+		}
+	}
+}
+
+func (p *protoParser) skipOption() error {
+	if err := p.skipOptionName(); err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	if err := p.skipConstant(); err != nil {
+		return err
+	}
+	return p.expect(";")
+}
+
+// skipFieldOptions skips the `[option = value, ...]` of fields and enum values.
+func (p *protoParser) skipFieldOptions() error {
+	if !p.nextIs("[") {
+		return nil
+	}
+	p.position++
+
+	for {
+		if err := p.skipOptionName(); err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		if err := p.skipConstant(); err != nil {
+			return err
+		}
+		if p.nextIs("]") {
+			p.position++
+			return nil
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *protoParser) parseFile() error {
+	if p.nextIs("syntax") {
+		p.position++
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+		syntax := strings.Trim(token.value, `"'`)
+		if token.kind != protoTokenString || (syntax != "proto2" && syntax != "proto3") {
+			return p.errorAt(token, "unsupported syntax %s", token.value)
+		}
+		p.file.Syntax = syntax
+		if err := p.expect(";"); err != nil {
+			return err
+		}
+	}
+
+	for {
+		token, exists := p.peek()
+		if !exists {
+			return nil
+		}
+
+		switch {
+		case token.kind == protoTokenSymbol && token.value == ";":
+			p.position++
+		case token.value == "package":
+			p.position++
+			name, err := p.identifier()
+			if err != nil {
+				return err
+			}
+			if p.file.Package != "" {
+				return p.errorAt(name, "package is defined more than once")
+			}
+			p.file.Package = name.value
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case token.value == "import":
+			return p.errorAt(token, "imports are not supported")
+		case token.value == "option":
+			p.position++
+			if err := p.skipOption(); err != nil {
+				return err
+			}
+		case token.value == "message":
+			p.position++
+			message, err := p.parseMessage(p.file.Package)
+			if err != nil {
+				return err
+			}
+			p.file.Messages = append(p.file.Messages, message)
+		case token.value == "enum":
+			p.position++
+			enum, err := p.parseEnum(p.file.Package)
+			if err != nil {
+				return err
+			}
+			p.file.Enums = append(p.file.Enums, enum)
+		case token.value == "service" || token.value == "extend":
+			p.position++
+			if _, err := p.identifier(); err != nil {
+				return err
+			}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		default:
+			return p.errorAt(token, "unexpected '%s'", token.value)
+		}
+	}
+}
+
+func qualifyProtoName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (p *protoParser) declarationName() (protoToken, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return name, err
+	}
+	if strings.Contains(name.value, ".") {
+		return name, p.errorAt(name, "invalid name '%s'", name.value)
+	}
+	return name, nil
+}
+
+func (p *protoParser) parseMessage(scope string) (*ProtoMessage, error) {
+	name, err := p.declarationName()
+	if err != nil {
+		return nil, err
+	}
+	message := &ProtoMessage{Name: name.value, FullName: qualifyProtoName(scope, name.value)}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if err := p.parseMessageBody(message, ""); err != nil {
+		return nil, err
+	}
+
+	return message, p.checkMessage(message, name)
+}
+
+// parseMessageBody parses the declarations of a message, or of one of its
+// oneofs when oneOf is set, until the closing brace.
+func (p *protoParser) parseMessageBody(message *ProtoMessage, oneOf string) error {
+	for {
+		token, err := p.next()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case token.kind == protoTokenSymbol && token.value == "}":
+			return nil
+		case token.kind == protoTokenSymbol && token.value == ";":
+		case token.value == "option":
+			if err := p.skipOption(); err != nil {
+				return err
+			}
+		case oneOf != "":
+			p.position--
+			field, err := p.parseField(message, "")
+			if err != nil {
+				return err
+			}
+			field.OneOf = oneOf
+		case token.value == "message":
+			nested, err := p.parseMessage(message.FullName)
+			if err != nil {
+				return err
+			}
+			message.Messages = append(message.Messages, nested)
+		case token.value == "enum":
+			enum, err := p.parseEnum(message.FullName)
+			if err != nil {
+				return err
+			}
+			message.Enums = append(message.Enums, enum)
+		case token.value == "oneof":
+			name, err := p.declarationName()
+			if err != nil {
+				return err
+			}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseMessageBody(message, name.value); err != nil {
+				return err
+			}
+		case token.value == "reserved" || token.value == "extensions":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case token.value == "extend":
+			if _, err := p.identifier(); err != nil {
+				return err
+			}
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case token.value == "group":
+			return p.errorAt(token, "groups are not supported")
+		case token.value == "map" && p.nextIs("<"):
+			if err := p.parseMapField(message); err != nil {
+				return err
+			}
+		case token.value == "optional" || token.value == "required" || token.value == "repeated":
+			if token.value == "required" && p.file.Syntax == "proto3" {
+				return p.errorAt(token, "required fields are not allowed in proto3")
+			}
+			if _, err := p.parseField(message, token.value); err != nil {
+				return err
+			}
+		case token.kind == protoTokenIdentifier:
+			if p.file.Syntax == "proto2" {
+				return p.errorAt(token, "missing label (optional, required or repeated) before '%s' in proto2", token.value)
+			}
+			p.position--
+			if _, err := p.parseField(message, ""); err != nil {
+				return err
+			}
+		default:
+			return p.errorAt(token, "unexpected '%s'", token.value)
+		}
+	}
+}
+
+func (p *protoParser) parseField(message *ProtoMessage, label string) (*ProtoField, error) {
+	fieldType, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseFieldDeclaration(message, label, fieldType.value)
+}
+
+// parseFieldDeclaration parses the rest of a field once its type is known.
+func (p *protoParser) parseFieldDeclaration(message *ProtoMessage, label, fieldType string) (*ProtoField, error) {
+	name, err := p.declarationName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	number, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipFieldOptions(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	field := &ProtoField{Name: name.value, Number: number, Label: label, Type: fieldType}
+	message.Fields = append(message.Fields, field)
+	return field, nil
+}
+
+func (p *protoParser) parseMapField(message *ProtoMessage) error {
+	if err := p.expect("<"); err != nil {
+		return err
+	}
+	keyType, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if !protoScalarTypes[keyType.value] || keyType.value == "double" || keyType.value == "float" || keyType.value == "bytes" {
+		return p.errorAt(keyType, "invalid map key type '%s'", keyType.value)
+	}
+	if err := p.expect(","); err != nil {
+		return err
+	}
+	valueType, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if err := p.expect(">"); err != nil {
+		return err
+	}
+
+	field, err := p.parseFieldDeclaration(message, "repeated", "map")
+	if err != nil {
+		return err
+	}
+	field.MapKey = keyType.value
+	field.MapValue = &ProtoField{Name: "value", Number: 2, Type: valueType.value}
+	return nil
+}
+
+func (p *protoParser) checkMessage(message *ProtoMessage, token protoToken) error {
+	names := map[string]bool{}
+	numbers := map[int]string{}
+
+	for _, field := range message.Fields {
+		if names[field.Name] {
+			return p.errorAt(token, "field '%s' is defined more than once in message '%s'", field.Name, message.Name)
+		}
+		names[field.Name] = true
+
+		if field.Number < 1 || field.Number > protoMaxFieldNumber {
+			return p.errorAt(token, "invalid number %d for field '%s' in message '%s'", field.Number, field.Name, message.Name)
+		}
+		if field.Number >= protoReservedFieldNumberStart && field.Number <= protoReservedFieldNumberEnd {
+			return p.errorAt(token, "number %d of field '%s' in message '%s' is reserved", field.Number, field.Name, message.Name)
+		}
+		if other, exists := numbers[field.Number]; exists {
+			return p.errorAt(token, "fields '%s' and '%s' use the same number %d in message '%s'", other, field.Name, field.Number, message.Name)
+		}
+		numbers[field.Number] = field.Name
+	}
+
+	return nil
+}
+
+func (p *protoParser) parseEnum(scope string) (*ProtoEnum, error) {
+	name, err := p.declarationName()
+	if err != nil {
+		return nil, err
+	}
+	enum := &ProtoEnum{Name: name.value, FullName: qualifyProtoName(scope, name.value)}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		token, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case token.kind == protoTokenSymbol && token.value == "}":
+			if len(enum.Values) == 0 {
+				return nil, p.errorAt(token, "enum '%s' has no values", enum.Name)
+			}
+			if p.file.Syntax == "proto3" && enum.Values[0].Number != 0 {
+				return nil, p.errorAt(token, "the first value of enum '%s' must be zero in proto3", enum.Name)
+			}
+			return enum, nil
+		case token.kind == protoTokenSymbol && token.value == ";":
+		case token.value == "option":
+			if err := p.skipOption(); err != nil {
+				return nil, err
+			}
+		case token.value == "reserved":
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		case token.kind == protoTokenIdentifier && !strings.Contains(token.value, "."):
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			number, err := p.integer()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.skipFieldOptions(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			enum.Values = append(enum.Values, ProtoEnumValue{Name: token.value, Number: number})
+		default:
+			return nil, p.errorAt(token, "unexpected '%s'", token.value)
+		}
+	}
+}
+
+// resolve links every field with the message or enum of its type, searching
+// from the innermost scope as protoc does.
+func (p *protoParser) resolve() error {
+	messages := map[string]*ProtoMessage{}
+	enums := map[string]*ProtoEnum{}
+	all := []*ProtoMessage{}
+
+	var index func(nestedMessages []*ProtoMessage, nestedEnums []*ProtoEnum)
+	index = func(nestedMessages []*ProtoMessage, nestedEnums []*ProtoEnum) {
+		for _, enum := range nestedEnums {
+			enums[enum.FullName] = enum
+		}
+		for _, message := range nestedMessages {
+			messages[message.FullName] = message
+			all = append(all, message)
+			index(message.Messages, message.Enums)
+		}
+	}
+	index(p.file.Messages, p.file.Enums)
+
+	resolveField := func(field *ProtoField, scope string) error {
+		if protoScalarTypes[field.Type] {
+			return nil
+		}
+
+		candidates := []string{}
+		if strings.HasPrefix(field.Type, ".") {
+			candidates = append(candidates, strings.TrimPrefix(field.Type, "."))
+		} else {
+			for {
+				candidates = append(candidates, qualifyProtoName(scope, field.Type))
+				if scope == "" {
+					break
+				}
+				if index := strings.LastIndex(scope, "."); index >= 0 {
+					scope = scope[:index]
+				} else {
+					scope = ""
+				}
+			}
+		}
+
+		for _, candidate := range candidates {
+			if message, exists := messages[candidate]; exists {
+				field.Message = message
+				return nil
+			}
+			if enum, exists := enums[candidate]; exists {
+				field.Enum = enum
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown type '%s' for field '%s'", field.Type, field.Name)
+	}
+
+	for _, message := range all {
+		for _, field := range message.Fields {
+			target := field
+			if field.MapValue != nil {
+				target = field.MapValue
+			}
+			if err := resolveField(target, message.FullName); err != nil {
+				return fmt.Errorf("message '%s': %w", message.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,553 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	protoWireVarint = 0
+	protoWireI64    = 1
+	protoWireLen    = 2
+	protoWireI32    = 5
+)
+
+// JSONName is the name of the field in the JSON encoding, its lowerCamelCase
+// version as protoc generates it.
+func (f *ProtoField) JSONName() string {
+	var builder strings.Builder
+	upper := false
+	for _, r := range f.Name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			builder.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// fieldByJSONKey finds a field by its JSON name or its original name, both
+// are accepted by the JSON encoding.
+func (m *ProtoMessage) fieldByJSONKey(key string) *ProtoField {
+	for _, field := range m.Fields {
+		if field.JSONName() == key || field.Name == key {
+			return field
+		}
+	}
+	return nil
+}
+
+func (m *ProtoMessage) fieldByNumber(number int) *ProtoField {
+	for _, field := range m.Fields {
+		if field.Number == number {
+			return field
+		}
+	}
+	return nil
+}
+
+// protoIntegerRanges are the limits of the integer scalar types.
+var protoIntegerRanges = map[string][2]float64{
+	"int32":    {math.MinInt32, math.MaxInt32},
+	"sint32":   {math.MinInt32, math.MaxInt32},
+	"sfixed32": {math.MinInt32, math.MaxInt32},
+	"uint32":   {0, math.MaxUint32},
+	"fixed32":  {0, math.MaxUint32},
+	"int64":    {math.MinInt64, math.MaxInt64},
+	"sint64":   {math.MinInt64, math.MaxInt64},
+	"sfixed64": {math.MinInt64, math.MaxInt64},
+	"uint64":   {0, math.MaxUint64},
+	"fixed64":  {0, math.MaxUint64},
+}
+
+// validateProtoJSON checks a value decoded (using json.Number) from the JSON
+// encoding of Protocol Buffers, adding a problem for every value not
+// matching.
+func validateProtoJSON(message *ProtoMessage, value interface{}, path string, problems *[]string) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		*problems = append(*problems, fmt.Sprintf("%s: expected message '%s', found %s", displayPath(path), message.Name, describeJSONValue(value)))
+		return
+	}
+
+	oneOfs := map[string]string{}
+	for _, key := range sortedJSONKeys(object) {
+		field := message.fieldByJSONKey(key)
+		if field == nil {
+			*problems = append(*problems, fmt.Sprintf("%s: unknown field '%s'", displayPath(path), key))
+			continue
+		}
+
+		fieldValue := object[key]
+		if fieldValue == nil {
+			continue
+		}
+
+		if field.OneOf != "" {
+			if other, exists := oneOfs[field.OneOf]; exists {
+				*problems = append(*problems, fmt.Sprintf("%s: fields '%s' and '%s' of oneof '%s' can't be set together", displayPath(path), other, field.Name, field.OneOf))
+			}
+			oneOfs[field.OneOf] = field.Name
+		}
+
+		fieldPath := joinPath(path, field.Name)
+		switch {
+		case field.MapValue != nil:
+			entries, ok := fieldValue.(map[string]interface{})
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected map, found %s", displayPath(fieldPath), describeJSONValue(fieldValue)))
+				continue
+			}
+			for _, entryKey := range sortedJSONKeys(entries) {
+				entryPath := fmt.Sprintf("%s[%q]", fieldPath, entryKey)
+				validateProtoJSONScalar(field.MapKey, entryKey, entryPath, problems)
+				validateProtoJSONValue(field.MapValue, entries[entryKey], entryPath, problems)
+			}
+		case field.Label == "repeated":
+			items, ok := fieldValue.([]interface{})
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected array, found %s", displayPath(fieldPath), describeJSONValue(fieldValue)))
+				continue
+			}
+			for i, item := range items {
+				validateProtoJSONValue(field, item, fmt.Sprintf("%s[%d]", fieldPath, i), problems)
+			}
+		default:
+			validateProtoJSONValue(field, fieldValue, fieldPath, problems)
+		}
+	}
+}
+
+func validateProtoJSONValue(field *ProtoField, value interface{}, path string, problems *[]string) {
+	switch {
+	case field.Message != nil:
+		validateProtoJSON(field.Message, value, path, problems)
+	case field.Enum != nil:
+		switch typed := value.(type) {
+		case string:
+			for _, enumValue := range field.Enum.Values {
+				if enumValue.Name == typed {
+					return
+				}
+			}
+			*problems = append(*problems, fmt.Sprintf("%s: '%s' is not a value of enum '%s'", displayPath(path), typed, field.Enum.Name))
+		case json.Number:
+			if _, err := typed.Int64(); err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s: invalid value %s for enum '%s'", displayPath(path), typed, field.Enum.Name))
+			}
+		default:
+			*problems = append(*problems, fmt.Sprintf("%s: expected a value of enum '%s', found %s", displayPath(path), field.Enum.Name, describeJSONValue(value)))
+		}
+	default:
+		validateProtoJSONScalar(field.Type, value, path, problems)
+	}
+}
+
+func validateProtoJSONScalar(scalarType string, value interface{}, path string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s", displayPath(path), fmt.Sprintf(format, args...)))
+	}
+
+	switch scalarType {
+	case "string":
+		if _, ok := value.(string); !ok {
+			report("expected string, found %s", describeJSONValue(value))
+		}
+	case "bool":
+		switch typed := value.(type) {
+		case bool:
+		case string:
+			// Map keys are always strings
+			if typed != "true" && typed != "false" {
+				report("expected bool, found %s", describeJSONValue(value))
+			}
+		default:
+			report("expected bool, found %s", describeJSONValue(value))
+		}
+	case "bytes":
+		text, ok := value.(string)
+		if !ok {
+			report("expected bytes (base64), found %s", describeJSONValue(value))
+			return
+		}
+		if _, err := decodeProtoJSONBytes(text); err != nil {
+			report("invalid base64 bytes")
+		}
+	case "float", "double":
+		switch typed := value.(type) {
+		case json.Number:
+		case string:
+			if _, err := strconv.ParseFloat(typed, 64); err != nil && typed != "NaN" && typed != "Infinity" && typed != "-Infinity" {
+				report("expected %s, found %s", scalarType, describeJSONValue(value))
+			}
+		default:
+			report("expected %s, found %s", scalarType, describeJSONValue(value))
+		}
+	default:
+		limits := protoIntegerRanges[scalarType]
+		var text string
+		switch typed := value.(type) {
+		case json.Number:
+			text = typed.String()
+		case string:
+			text = typed
+		default:
+			report("expected %s, found %s", scalarType, describeJSONValue(value))
+			return
+		}
+
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil || number != math.Trunc(number) {
+			report("expected %s, found %s", scalarType, describeJSONValue(value))
+			return
+		}
+		if number < limits[0] || number > limits[1] {
+			report("%s is out of the range of %s", text, scalarType)
+		}
+	}
+}
+
+func decodeProtoJSONBytes(text string) ([]byte, error) {
+	text = strings.TrimRight(text, "=")
+	if strings.ContainsAny(text, "-_") {
+		return base64.RawURLEncoding.DecodeString(text)
+	}
+	return base64.RawStdEncoding.DecodeString(text)
+}
+
+// protoBinaryDecoder reads the Protocol Buffers wire format returning the
+// values as represented in the JSON encoding.
+type protoBinaryDecoder struct {
+	data     []byte
+	position int
+}
+
+func (d *protoBinaryDecoder) errorf(path, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s (at byte %d)", displayPath(path), fmt.Sprintf(format, args...), d.position)
+}
+
+func (d *protoBinaryDecoder) readVarint(path string) (uint64, error) {
+	value, length := binary.Uvarint(d.data[d.position:])
+	if length <= 0 {
+		return 0, d.errorf(path, "invalid varint")
+	}
+	d.position += length
+	return value, nil
+}
+
+func (d *protoBinaryDecoder) read(path string, length int) ([]byte, error) {
+	if length < 0 || length > len(d.data)-d.position {
+		return nil, d.errorf(path, "unexpected end of data")
+	}
+	bytes := d.data[d.position : d.position+length]
+	d.position += length
+	return bytes, nil
+}
+
+// expectedWireType returns the wire type of a non packed value of the field.
+func expectedWireType(field *ProtoField) int {
+	switch {
+	case field.Message != nil || field.MapValue != nil:
+		return protoWireLen
+	case field.Enum != nil:
+		return protoWireVarint
+	}
+
+	switch field.Type {
+	case "string", "bytes":
+		return protoWireLen
+	case "double", "fixed64", "sfixed64":
+		return protoWireI64
+	case "float", "fixed32", "sfixed32":
+		return protoWireI32
+	default:
+		return protoWireVarint
+	}
+}
+
+func (d *protoBinaryDecoder) decodeMessage(message *ProtoMessage, path string, end int) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	present := map[int]bool{}
+
+	for d.position < end {
+		key, err := d.readVarint(path)
+		if err != nil {
+			return nil, err
+		}
+		number := int(key >> 3)
+		wireType := int(key & 7)
+
+		field := message.fieldByNumber(number)
+		if field == nil {
+			// Unknown fields are accepted, as any parser does
+			if err := d.skip(path, wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fieldPath := joinPath(path, field.Name)
+		present[number] = true
+		expected := expectedWireType(field)
+
+		switch {
+		case field.MapValue != nil:
+			if wireType != protoWireLen {
+				return nil, d.errorf(fieldPath, "invalid wire type %d", wireType)
+			}
+			entryKey, entryValue, err := d.decodeMapEntry(field, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			entries, _ := result[field.JSONName()].(map[string]interface{})
+			if entries == nil {
+				entries = map[string]interface{}{}
+				result[field.JSONName()] = entries
+			}
+			entries[entryKey] = entryValue
+		case field.Label == "repeated":
+			items, _ := result[field.JSONName()].([]interface{})
+			if wireType == protoWireLen && expected != protoWireLen {
+				// Packed repeated scalars
+				length, err := d.readVarint(fieldPath)
+				if err != nil {
+					return nil, err
+				}
+				if length > uint64(end-d.position) {
+					return nil, d.errorf(fieldPath, "unexpected end of data")
+				}
+				packedEnd := d.position + int(length)
+				for d.position < packedEnd {
+					value, err := d.decodeValue(field, fieldPath, expected)
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, value)
+				}
+			} else {
+				if wireType != expected {
+					return nil, d.errorf(fieldPath, "invalid wire type %d", wireType)
+				}
+				value, err := d.decodeValue(field, fmt.Sprintf("%s[%d]", fieldPath, len(items)), wireType)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, value)
+			}
+			result[field.JSONName()] = items
+		default:
+			if wireType != expected {
+				return nil, d.errorf(fieldPath, "invalid wire type %d", wireType)
+			}
+			value, err := d.decodeValue(field, fieldPath, wireType)
+			if err != nil {
+				return nil, err
+			}
+			if field.OneOf != "" {
+				for _, other := range message.Fields {
+					if other.OneOf == field.OneOf && other != field {
+						delete(result, other.JSONName())
+					}
+				}
+			}
+			result[field.JSONName()] = value
+		}
+	}
+
+	if d.position != end {
+		return nil, d.errorf(path, "message ends in the middle of a field")
+	}
+
+	for _, field := range message.Fields {
+		if field.Label == "required" && !present[field.Number] {
+			return nil, fmt.Errorf("%s: missing required field '%s'", displayPath(path), field.Name)
+		}
+	}
+
+	return result, nil
+}
+
+func (d *protoBinaryDecoder) decodeMapEntry(field *ProtoField, path string) (string, interface{}, error) {
+	length, err := d.readVarint(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if length > uint64(len(d.data)-d.position) {
+		return "", nil, d.errorf(path, "unexpected end of data")
+	}
+	end := d.position + int(length)
+
+	entry := &ProtoMessage{
+		Name: field.Name + "Entry",
+		Fields: []*ProtoField{
+			{Name: "key", Number: 1, Type: field.MapKey},
+			field.MapValue,
+		},
+	}
+	values, err := d.decodeMessage(entry, path, end)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := fmt.Sprint(values["key"])
+	if values["key"] == nil {
+		key = fmt.Sprint(protoDefaultJSONValue(&ProtoField{Type: field.MapKey}))
+	}
+	value, exists := values["value"]
+	if !exists {
+		value = protoDefaultJSONValue(field.MapValue)
+	}
+	return key, value, nil
+}
+
+func (d *protoBinaryDecoder) decodeValue(field *ProtoField, path string, wireType int) (interface{}, error) {
+	switch wireType {
+	case protoWireVarint:
+		value, err := d.readVarint(path)
+		if err != nil {
+			return nil, err
+		}
+		if field.Enum != nil {
+			for _, enumValue := range field.Enum.Values {
+				if enumValue.Number == int(int32(value)) {
+					return enumValue.Name, nil
+				}
+			}
+			return json.Number(fmt.Sprint(int32(value))), nil
+		}
+		switch field.Type {
+		case "bool":
+			return value != 0, nil
+		case "int32":
+			return json.Number(fmt.Sprint(int32(value))), nil
+		case "uint32":
+			return json.Number(fmt.Sprint(uint32(value))), nil
+		case "sint32":
+			return json.Number(fmt.Sprint(int32(uint32(value)>>1) ^ -int32(value&1))), nil
+		case "int64":
+			return fmt.Sprint(int64(value)), nil
+		case "uint64":
+			return fmt.Sprint(value), nil
+		case "sint64":
+			return fmt.Sprint(int64(value>>1) ^ -int64(value&1)), nil
+		}
+	case protoWireI64:
+		bytes, err := d.read(path, 8)
+		if err != nil {
+			return nil, err
+		}
+		value := binary.LittleEndian.Uint64(bytes)
+		switch field.Type {
+		case "double":
+			return jsonFloat(math.Float64frombits(value)), nil
+		case "fixed64":
+			return fmt.Sprint(value), nil
+		case "sfixed64":
+			return fmt.Sprint(int64(value)), nil
+		}
+	case protoWireI32:
+		bytes, err := d.read(path, 4)
+		if err != nil {
+			return nil, err
+		}
+		value := binary.LittleEndian.Uint32(bytes)
+		switch field.Type {
+		case "float":
+			return jsonFloat(float64(math.Float32frombits(value))), nil
+		case "fixed32":
+			return json.Number(fmt.Sprint(value)), nil
+		case "sfixed32":
+			return json.Number(fmt.Sprint(int32(value))), nil
+		}
+	case protoWireLen:
+		length, err := d.readVarint(path)
+		if err != nil {
+			return nil, err
+		}
+		if length > uint64(len(d.data)-d.position) {
+			return nil, d.errorf(path, "unexpected end of data")
+		}
+		if field.Message != nil {
+			return d.decodeMessage(field.Message, path, d.position+int(length))
+		}
+		bytes, err := d.read(path, int(length))
+		if err != nil {
+			return nil, err
+		}
+		if field.Type == "string" {
+			if !utf8.Valid(bytes) {
+				return nil, d.errorf(path, "invalid UTF-8 string")
+			}
+			return string(bytes), nil
+		}
+		return base64.StdEncoding.EncodeToString(bytes), nil
+	}
+
+	return nil, d.errorf(path, "invalid wire type %d", wireType)
+}
+
+func (d *protoBinaryDecoder) skip(path string, wireType int) error {
+	switch wireType {
+	case protoWireVarint:
+		_, err := d.readVarint(path)
+		return err
+	case protoWireI64:
+		_, err := d.read(path, 8)
+		return err
+	case protoWireI32:
+		_, err := d.read(path, 4)
+		return err
+	case protoWireLen:
+		length, err := d.readVarint(path)
+		if err != nil {
+			return err
+		}
+		if length > uint64(len(d.data)-d.position) {
+			return d.errorf(path, "unexpected end of data")
+		}
+		_, err = d.read(path, int(length))
+		return err
+	default:
+		return d.errorf(path, "unsupported wire type %d", wireType)
+	}
+}
+
+// protoDefaultJSONValue is the value of a field not present in the message.
+func protoDefaultJSONValue(field *ProtoField) interface{} {
+	switch {
+	case field.Message != nil:
+		return map[string]interface{}{}
+	case field.Enum != nil:
+		return field.Enum.Values[0].Name
+	}
+
+	switch field.Type {
+	case "string", "bytes":
+		return ""
+	case "bool":
+		return false
+	case "int64", "uint64", "sint64", "fixed64", "sfixed64":
+		return "0"
+	default:
+		return json.Number("0")
+	}
+}
+
+// DecodeProtobufBinary decodes data in the Protocol Buffers wire format,
+// returning the message as represented in the JSON encoding.
+func DecodeProtobufBinary(message *ProtoMessage, data []byte) (map[string]interface{}, error) {
+	decoder := protoBinaryDecoder{data: data}
+	return decoder.decodeMessage(message, "", len(data))
+}
@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+)
+
+func appendProtoKey(data []byte, number, wireType int) []byte {
+	return binary.AppendUvarint(data, uint64(number)<<3|uint64(wireType))
+}
+
+func appendProtoLengthDelimited(data []byte, value []byte) []byte {
+	return append(binary.AppendUvarint(data, uint64(len(value))), value...)
+}
+
+// protoJSONNumber reads a number of the JSON encoding, either a number or a
+// string as used by 64 bits integers and the special float values.
+func protoJSONNumber(value interface{}) string {
+	switch typed := value.(type) {
+	case json.Number:
+		return typed.String()
+	case string:
+		return typed
+	case bool:
+		return strconv.FormatBool(typed)
+	}
+	return ""
+}
+
+// appendProtoValue appends a single value of the field, without its key, in
+// the encoding of its wire type. The value is already validated.
+func appendProtoValue(data []byte, field *ProtoField, value interface{}) []byte {
+	switch {
+	case field.Message != nil:
+		return appendProtoLengthDelimited(data, appendProtoMessage([]byte{}, field.Message, value.(map[string]interface{})))
+	case field.Enum != nil:
+		if name, ok := value.(string); ok {
+			for _, enumValue := range field.Enum.Values {
+				if enumValue.Name == name {
+					return binary.AppendUvarint(data, uint64(int64(enumValue.Number)))
+				}
+			}
+		}
+		number, _ := strconv.ParseInt(protoJSONNumber(value), 10, 64)
+		return binary.AppendUvarint(data, uint64(number))
+	}
+
+	text := protoJSONNumber(value)
+	switch field.Type {
+	case "string":
+		return appendProtoLengthDelimited(data, []byte(value.(string)))
+	case "bytes":
+		bytes, _ := decodeProtoJSONBytes(value.(string))
+		return appendProtoLengthDelimited(data, bytes)
+	case "bool":
+		if text == "true" {
+			return append(data, 1)
+		}
+		return append(data, 0)
+	case "double":
+		return binary.LittleEndian.AppendUint64(data, math.Float64bits(parseProtoFloat(text)))
+	case "float":
+		return binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(parseProtoFloat(text))))
+	case "uint32", "uint64":
+		number, _ := strconv.ParseUint(text, 10, 64)
+		return binary.AppendUvarint(data, number)
+	case "fixed32":
+		number, _ := strconv.ParseUint(text, 10, 32)
+		return binary.LittleEndian.AppendUint32(data, uint32(number))
+	case "fixed64":
+		number, _ := strconv.ParseUint(text, 10, 64)
+		return binary.LittleEndian.AppendUint64(data, number)
+	}
+
+	number, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		// Integers written with an exponent, like 1e3
+		float, _ := strconv.ParseFloat(text, 64)
+		number = int64(float)
+	}
+	switch field.Type {
+	case "sint32", "sint64":
+		return binary.AppendUvarint(data, uint64((number<<1)^(number>>63)))
+	case "sfixed32":
+		return binary.LittleEndian.AppendUint32(data, uint32(int32(number)))
+	case "sfixed64":
+		return binary.LittleEndian.AppendUint64(data, uint64(number))
+	default:
+		// int32 and int64, negative values use 10 bytes
+		return binary.AppendUvarint(data, uint64(number))
+	}
+}
+
+func parseProtoFloat(text string) float64 {
+	switch text {
+	case "Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+	number, _ := strconv.ParseFloat(text, 64)
+	return number
+}
+
+// appendProtoMessage appends the fields of a message, in the order of their
+// numbers, skipping the null ones.
+func appendProtoMessage(data []byte, message *ProtoMessage, object map[string]interface{}) []byte {
+	fields := []*ProtoField{}
+	values := map[*ProtoField]interface{}{}
+	for key, value := range object {
+		field := message.fieldByJSONKey(key)
+		if field == nil || value == nil {
+			continue
+		}
+		fields = append(fields, field)
+		values[field] = value
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Number < fields[j].Number })
+
+	for _, field := range fields {
+		value := values[field]
+		switch {
+		case field.MapValue != nil:
+			entries := value.(map[string]interface{})
+			keyField := &ProtoField{Name: "key", Number: 1, Type: field.MapKey}
+			for _, entryKey := range sortedJSONKeys(entries) {
+				entry := appendProtoKey([]byte{}, 1, expectedWireType(keyField))
+				if field.MapKey == "string" {
+					entry = appendProtoValue(entry, keyField, entryKey)
+				} else {
+					entry = appendProtoValue(entry, keyField, json.Number(entryKey))
+				}
+				entry = appendProtoKey(entry, 2, expectedWireType(field.MapValue))
+				entry = appendProtoValue(entry, field.MapValue, entries[entryKey])
+
+				data = appendProtoKey(data, field.Number, protoWireLen)
+				data = appendProtoLengthDelimited(data, entry)
+			}
+		case field.Label == "repeated":
+			items := value.([]interface{})
+			if expectedWireType(field) == protoWireLen {
+				for _, item := range items {
+					data = appendProtoKey(data, field.Number, protoWireLen)
+					data = appendProtoValue(data, field, item)
+				}
+				continue
+			}
+			if len(items) == 0 {
+				continue
+			}
+			packed := []byte{}
+			for _, item := range items {
+				packed = appendProtoValue(packed, field, item)
+			}
+			data = appendProtoKey(data, field.Number, protoWireLen)
+			data = appendProtoLengthDelimited(data, packed)
+		default:
+			data = appendProtoKey(data, field.Number, expectedWireType(field))
+			data = appendProtoValue(data, field, value)
+		}
+	}
+	return data
+}
+
+// EncodeProtobufBinary encodes a message in the JSON encoding (decoded using
+// json.Number) in the Protocol Buffers wire format. The problems found are
+// returned as a *ValidationError.
+func EncodeProtobufBinary(message *ProtoMessage, value interface{}) ([]byte, error) {
+	problems := []string{}
+	validateProtoJSON(message, value, "", &problems)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return appendProtoMessage([]byte{}, message, value.(map[string]interface{})), nil
+}
@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Protobuf_Parse(t *testing.T) {
+	file, err := ParseProtobuf(`
+    syntax = "proto3";
+    package example.v1;
+
+    option go_package = "example/v1";
+
+    /* Products sold in the catalog. */
+    message Product {
+      enum Status {
+        STATUS_UNSPECIFIED = 0;
+        ACTIVE = 1;
+      }
+      message Price { int64 cents = 1; string currency = 2; }
+
+      string name = 1 [deprecated = true];
+      repeated string tags = 2;
+      Status status = 3;
+      map<string, Price> prices = 4;
+      oneof code {
+        string sku = 5;
+        int32 ean = 6;
+      }
+      reserved 7, 9 to 11;
+    }
+  `)
+	assert.NoError(t, err)
+	assert.Equal(t, "proto3", file.Syntax)
+
+	product := file.Message("Product")
+	assert.NotNil(t, product)
+	assert.Equal(t, "example.v1.Product", product.FullName)
+	assert.Equal(t, 6, len(product.Fields))
+	assert.Equal(t, "repeated", product.Fields[1].Label)
+	assert.Same(t, product.Enums[0], product.Fields[2].Enum)
+	assert.Equal(t, "string", product.Fields[3].MapKey)
+	assert.Same(t, product.Messages[0], product.Fields[3].MapValue.Message)
+	assert.Equal(t, "code", product.Fields[5].OneOf)
+}
+
+func Test_Protobuf_Parse_Invalid(t *testing.T) {
+	definitions := map[string]string{
+		"missing semicolon":       `syntax = "proto3"; message A { int32 b = 1 }`,
+		"unknown type":            `syntax = "proto3"; message A { Missing b = 1; }`,
+		"duplicated number":       `syntax = "proto3"; message A { int32 b = 1; int32 c = 1; }`,
+		"reserved number":         `syntax = "proto3"; message A { int32 b = 19000; }`,
+		"required in proto3":      `syntax = "proto3"; message A { required int32 b = 1; }`,
+		"missing label in proto2": `syntax = "proto2"; message A { int32 b = 1; }`,
+		"two top level messages":  `syntax = "proto3"; message A {} message B {}`,
+		"imports":                 `syntax = "proto3"; import "other.proto"; message A {}`,
+		"unterminated comment":    `syntax = "proto3"; /* message A {}`,
+		"enum not starting at 0":  `syntax = "proto3"; message A { enum B { ONE = 1; } }`,
+	}
+
+	for description, definition := range definitions {
+		_, err := ParseProtobuf(definition)
+		assert.Error(t, err, description)
+	}
+}
@@ -0,0 +1,45 @@
+// Package schema parses, validates and encodes Avro and Protocol Buffers
+// schemas without depending on their official libraries, following the
+// documented specifications and the behaviour of Pub/Sub.
+//
+// Avro definitions support the primitive, record, enum, array, map, fixed
+// and union types. Logical types are kept as an annotation and their values
+// are checked as the underlying type. The JSON encoding requires every field
+// of a record, even the ones with a default, and the union values written as
+// {"<type>": value}, as the decoder of Pub/Sub does.
+//
+// Protocol Buffers definitions must be proto2 or proto3 with exactly one top
+// level message. Nested messages and enums, oneof and map fields are
+// supported, imports and groups are rejected, and services, extensions,
+// options and reserved ranges are skipped.
+package schema
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Schema types, as named by the Pub/Sub API.
+const (
+	TYPE_AVRO            = "AVRO"
+	TYPE_PROTOCOL_BUFFER = "PROTOCOL_BUFFER"
+)
+
+// TypeForFile returns the schema type matching the extension of a definition
+// file, or an empty string if it's unknown.
+func TypeForFile(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".avsc":
+		return TYPE_AVRO
+	case ".proto":
+		return TYPE_PROTOCOL_BUFFER
+	default:
+		return ""
+	}
+}
+
+// Validate checks the syntax of a definition of the given schema type.
+func Validate(schemaType, definition string) error {
+	_, err := Parse(schemaType, definition)
+	return err
+}
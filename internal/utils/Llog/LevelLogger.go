@@ -1,7 +1,7 @@
 package Llog
 
 import (
-	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -24,6 +24,13 @@ var LLs = map[string]int{
 
 var currentLL = LLInfo
 
+var logger = log.New(os.Stderr, "", log.LstdFlags)
+
+// SetOutput sets where the messages are logged, stderr by default.
+func SetOutput(w io.Writer) {
+	logger.SetOutput(w)
+}
+
 func Init() {
 	// Leer variable de entorno LOG_LEVEL
 	levelStr := os.Getenv("LOG_LEVEL")
@@ -33,7 +40,7 @@ func Init() {
 		currentLL = level
 	} else {
 		if levelStr != "" {
-			fmt.Println("LOG_LEVEL not valid, using INFO instead!")
+			logger.Printf("[WARN] LOG_LEVEL not valid, using INFO instead!")
 		}
 	}
 }
@@ -47,7 +54,7 @@ func Llog(level int, message string) {
 				break
 			}
 		}
-		log.Printf("[%s] %s", levelName, message)
+		logger.Printf("[%s] %s", levelName, message)
 	}
 }
 
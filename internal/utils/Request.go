@@ -7,6 +7,9 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
 )
 
 type ClientInterface interface {
@@ -48,7 +51,7 @@ func (c Client) makeCall(
 	body []byte,
 ) (Response, error) {
 
-	fmt.Printf("%s %s\n", method, url)
+	Llog.Debug(fmt.Sprintf("%s %s", method, url))
 
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
 	if err != nil {
@@ -169,9 +172,14 @@ type MockClientHistoryResponse struct {
 type MockClient struct {
 	RequestHistory  []MockClientHistoryRequest
 	ResponseHistory []MockClientHistoryResponse
+
+	mutex sync.Mutex
 }
 
 func (m *MockClient) makeCall(method string, path string, body []byte) (Response, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	m.RequestHistory = append(m.RequestHistory, MockClientHistoryRequest{
 		Method: method,
 		Path:   path,
@@ -0,0 +1,49 @@
+package utils
+
+import "sync"
+
+// DefaultConcurrency is the number of tasks run at the same time when no
+// concurrency is configured.
+const DefaultConcurrency = 8
+
+// Concurrency returns the concurrency configured, or DefaultConcurrency when
+// it's not set (zero or negative).
+func Concurrency(concurrency int) int {
+	if concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return concurrency
+}
+
+// RunConcurrently runs the tasks with at most `concurrency` of them running
+// at the same time. The returned errors keep the order of the tasks, with nil
+// for the successful ones, so the result doesn't depend on the scheduling.
+func RunConcurrently(concurrency int, tasks []func() error) []error {
+	errs := make([]error, len(tasks))
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < concurrency && worker < len(tasks); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				errs[index] = tasks[index]()
+			}
+		}()
+	}
+
+	for index := range tasks {
+		indexes <- index
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WorkerPool_KeepsErrorsOrder(t *testing.T) {
+	tasks := []func() error{
+		func() error { time.Sleep(5 * time.Millisecond); return errors.New("first") },
+		func() error { return nil },
+		func() error { return errors.New("third") },
+	}
+
+	errs := RunConcurrently(3, tasks)
+	assert.Equal(t, 3, len(errs))
+	assert.EqualError(t, errs[0], "first")
+	assert.NoError(t, errs[1])
+	assert.EqualError(t, errs[2], "third")
+}
+
+func Test_WorkerPool_BoundsConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+
+	tasks := []func() error{}
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, func() error {
+			current := running.Add(1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	RunConcurrently(4, tasks)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(4))
+}
+
+func Test_WorkerPool_Concurrency(t *testing.T) {
+	assert.Equal(t, 3, Concurrency(3))
+	assert.Equal(t, DefaultConcurrency, Concurrency(0))
+	assert.Equal(t, DefaultConcurrency, Concurrency(-1))
+}
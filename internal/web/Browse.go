@@ -0,0 +1,116 @@
+package web
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+)
+
+// TopicView is a topic of the emulator as shown in the UI, with its
+// subscriptions.
+type TopicView struct {
+	Name           string                 `json:"name"`
+	ResourceName   string                 `json:"resourceName"`
+	Labels         pubsub.Labels          `json:"labels"`
+	KmsKeyName     string                 `json:"kmsKeyName,omitempty"`
+	SchemaSettings *pubsub.SchemaSettings `json:"schemaSettings,omitempty"`
+	Subscriptions  []SubscriptionView     `json:"subscriptions"`
+}
+
+type SubscriptionView struct {
+	Name             string                   `json:"name"`
+	ResourceName     string                   `json:"resourceName"`
+	Labels           pubsub.Labels            `json:"labels"`
+	DeadLetterPolicy *pubsub.DeadLetterPolicy `json:"deadLetterPolicy,omitempty"`
+}
+
+type SchemaView struct {
+	Name               string `json:"name"`
+	ResourceName       string `json:"resourceName"`
+	Type               string `json:"type"`
+	Definition         string `json:"definition"`
+	RevisionId         string `json:"revisionId,omitempty"`
+	RevisionCreateTime string `json:"revisionCreateTime,omitempty"`
+}
+
+func newSchemaView(schema pubsub.Schema) SchemaView {
+	return SchemaView{
+		Name:               path.Base(schema.Name),
+		ResourceName:       schema.Name,
+		Type:               schema.Type,
+		Definition:         schema.Definition,
+		RevisionId:         schema.RevisionId,
+		RevisionCreateTime: schema.RevisionCreateTime,
+	}
+}
+
+// BrowseTopics returns the topics of a project in the emulator, sorted by
+// name, with their subscriptions.
+func BrowseTopics(client utils.ClientInterface, project string) ([]TopicView, error) {
+	topics, err := pubsub.ListTopics(client, project)
+	if err != nil {
+		return nil, fmt.Errorf("can't list the topics of project '%s': %w", project, err)
+	}
+
+	subscriptions, err := pubsub.ListSubscriptions(client, project)
+	if err != nil {
+		return nil, fmt.Errorf("can't list the subscriptions of project '%s': %w", project, err)
+	}
+	subscriptionsByName := map[string]pubsub.Subscription{}
+	for _, subscription := range subscriptions {
+		subscriptionsByName[subscription.Name] = subscription
+	}
+
+	views := []TopicView{}
+	for _, topic := range topics {
+		view := TopicView{
+			Name:           path.Base(topic.Name),
+			ResourceName:   topic.Name,
+			Labels:         topic.Labels,
+			KmsKeyName:     topic.KmsKeyName,
+			SchemaSettings: topic.SchemaSettings,
+			Subscriptions:  []SubscriptionView{},
+		}
+
+		subscriptionNames, err := pubsub.ListTopicSubscriptions(client, project, topic.Name)
+		if err != nil {
+			return nil, fmt.Errorf("can't list the subscriptions of topic '%s': %w", topic.Name, err)
+		}
+		sort.Strings(subscriptionNames)
+
+		for _, subscriptionName := range subscriptionNames {
+			subscription := subscriptionsByName[subscriptionName]
+			view.Subscriptions = append(view.Subscriptions, SubscriptionView{
+				Name:             path.Base(subscriptionName),
+				ResourceName:     subscriptionName,
+				Labels:           subscription.Labels,
+				DeadLetterPolicy: subscription.DeadLetterPolicy,
+			})
+		}
+
+		views = append(views, view)
+	}
+
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views, nil
+}
+
+// BrowseSchemas returns the schemas of a project in the emulator, sorted by
+// name, with the definition of their last revision.
+func BrowseSchemas(client utils.ClientInterface, project string) ([]SchemaView, error) {
+	schemas, err := pubsub.ListSchemas(client, project)
+	if err != nil {
+		return nil, fmt.Errorf("can't list the schemas of project '%s': %w", project, err)
+	}
+
+	views := []SchemaView{}
+	for _, schema := range schemas {
+		views = append(views, newSchemaView(schema))
+	}
+
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views, nil
+}
@@ -0,0 +1,182 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/capture"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// StreamedMessage is a message sent to the browser by the stream, with its
+// data decoded.
+type StreamedMessage struct {
+	internal.DecodedMessage
+	Topic string `json:"topic"`
+}
+
+// eventWriter writes Server-Sent Events, flushing every event.
+type eventWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (e eventWriter) send(event, id string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(e.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// handleStream sends the messages of a subscription as Server-Sent Events
+// until the browser disconnects. With "topic", or with "subscription" unless
+// "ack" is true, a tap subscription is created on the topic for the stream and
+// deleted once it ends, so the messages of the subscription are left to its
+// consumers. With "ack", the messages of the subscription are consumed.
+// Every pulled message is acknowledged, so it's sent once.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+	query := r.URL.Query()
+	subscription, topic := query.Get("subscription"), query.Get("topic")
+	if (subscription == "") == (topic == "") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("either subscription or topic is required"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	var subscriptionResourceName, topicResourceName string
+	ack, _ := strconv.ParseBool(query.Get("ack"))
+	if subscription != "" {
+		subscriptionResourceName = pubsub.GetResourceNameForSubscription(project, subscription)
+		var err error
+		if topicResourceName, err = pubsub.GetSubscriptionTopic(s.Client, project, subscriptionResourceName); err != nil {
+			writeError(w, http.StatusBadGateway, fmt.Errorf("subscription '%s': %w", subscription, err))
+			return
+		}
+	} else {
+		topicResourceName = pubsub.GetResourceNameForTopic(project, topic)
+	}
+	topicProject, topicName := capture.SplitTopicResourceName(topicResourceName)
+
+	if topic != "" || !ack {
+		tapName := topic
+		if tapName == "" {
+			tapName = subscription
+		}
+		subscriptionResourceName = pubsub.GetResourceNameForSubscription(project, fmt.Sprintf("%s-tap-%d", tapName, time.Now().UnixNano()))
+		if err := pubsub.CreateSubscription(s.Client, project, subscriptionResourceName, topicResourceName, &pubsub.Labels{"purpose": "tap"}, nil, nil); err != nil {
+			writeError(w, http.StatusBadGateway, fmt.Errorf("can't tap topic '%s': %w", topicName, err))
+			return
+		}
+		defer func() {
+			if err := pubsub.DeleteSubscription(s.Client, project, subscriptionResourceName); err != nil {
+				Llog.Warn(fmt.Sprintf("Can't delete the tap subscription '%s': %s", subscriptionResourceName, err))
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	events := eventWriter{w: w, flusher: flusher}
+	if err := events.send("ready", "", map[string]string{"subscription": subscriptionResourceName, "topic": topicResourceName}); err != nil {
+		return
+	}
+
+	interval := s.PullInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		if err := s.streamPull(events, project, subscriptionResourceName, topicProject, topicName); err != nil {
+			events.send("error", "", map[string]string{"error": err.Error()})
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *Server) streamPull(events eventWriter, project, subscriptionResourceName, topicProject, topicName string) error {
+	received, err := pubsub.Pull(s.Client, project, subscriptionResourceName, 100)
+	if err != nil {
+		return err
+	}
+
+	ackIds := []string{}
+	for _, receivedMessage := range received {
+		ackIds = append(ackIds, receivedMessage.AckId)
+
+		message := StreamedMessage{
+			DecodedMessage: s.Configuration.DecodeTopicMessage(topicProject, topicName, receivedMessage.Message),
+			Topic:          topicName,
+		}
+		if err := events.send("message", receivedMessage.Message.MessageId, message); err != nil {
+			return err
+		}
+	}
+
+	if len(ackIds) == 0 {
+		return nil
+	}
+	return pubsub.Acknowledge(s.Client, project, subscriptionResourceName, ackIds)
+}
+
+// handlePublish publishes the messages of the body, in the format of the
+// messages of the configuration (a message, a JSON array or JSON Lines),
+// encoded with the schema of the topic when it's in the configuration.
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	messages, err := internal.ParseMessages(body)
+	if err == nil {
+		messages, err = s.Configuration.ExpandMessages(messages)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid messages: %w", err))
+		return
+	}
+	if len(messages) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no messages to publish"))
+		return
+	}
+
+	messageIds, err := s.Configuration.PublishMessages(s.Client, r.PathValue("project"), r.PathValue("topic"), messages)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"messageIds": messageIds})
+}
@@ -0,0 +1,142 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamOnce runs a stream whose request is already cancelled, so a single
+// pull is done.
+func streamOnce(server *Server, target string) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+	return recorder
+}
+
+func Test_Live_StreamTopic(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"eyJpZCI6MX0=","attributes":{"source":"test"},"messageId":"1","publishTime":"2024-01-01T00:00:00Z"}}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := streamOnce(server, "/api/projects/test-project/stream?topic=orders")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "event: ready\n")
+	assert.Contains(t, recorder.Body.String(), "id: 1\nevent: message\ndata: {\"messageId\":\"1\",\"publishTime\":\"2024-01-01T00:00:00Z\",\"data\":{\"id\":1},\"attributes\":{\"source\":\"test\"},\"topic\":\"orders\"}\n\n")
+
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[0].Method)
+	assert.True(t, strings.HasPrefix(mockClient.RequestHistory[0].Path, "projects/test-project/subscriptions/orders-tap-"))
+	assert.Contains(t, string(mockClient.RequestHistory[0].Body), `"purpose":"tap"`)
+	assert.True(t, strings.HasSuffix(mockClient.RequestHistory[2].Path, ":acknowledge"))
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[3].Method)
+	assert.Equal(t, mockClient.RequestHistory[0].Path, mockClient.RequestHistory[3].Path)
+}
+
+func Test_Live_StreamSubscriptionKeepsMessages(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/subscriptions/orders-sub","topic":"projects/test-project/topics/orders"}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"aGVsbG8=","messageId":"1"}}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := streamOnce(server, "/api/projects/test-project/stream?subscription=orders-sub")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 1, strings.Count(recorder.Body.String(), "event: message\n"))
+	assert.Contains(t, recorder.Body.String(), `"data":"hello"`)
+
+	assert.Equal(t, 5, len(mockClient.RequestHistory))
+	assert.Equal(t, http.MethodPut, mockClient.RequestHistory[1].Method)
+	assert.True(t, strings.HasPrefix(mockClient.RequestHistory[1].Path, "projects/test-project/subscriptions/orders-sub-tap-"))
+	assert.Contains(t, string(mockClient.RequestHistory[1].Body), `"topic":"projects/test-project/topics/orders"`)
+	for _, request := range mockClient.RequestHistory {
+		assert.NotContains(t, request.Path, "subscriptions/orders-sub:")
+		assert.NotContains(t, request.Path, ":modifyAckDeadline")
+	}
+	assert.Equal(t, mockClient.RequestHistory[1].Path+":acknowledge", mockClient.RequestHistory[3].Path)
+	assert.Equal(t, http.MethodDelete, mockClient.RequestHistory[4].Method)
+	assert.Equal(t, mockClient.RequestHistory[1].Path, mockClient.RequestHistory[4].Path)
+}
+
+func Test_Live_StreamSubscriptionWithAck(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"name":"projects/test-project/subscriptions/orders-sub","topic":"projects/test-project/topics/orders"}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[
+				{"ackId":"a1","message":{"data":"aGVsbG8=","messageId":"1"}},
+				{"ackId":"a2","message":{"data":"aGVsbG8=","messageId":"2"}}
+			]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := streamOnce(server, "/api/projects/test-project/stream?subscription=orders-sub&ack=true")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 2, strings.Count(recorder.Body.String(), "event: message\n"))
+
+	assert.Equal(t, 3, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/subscriptions/orders-sub:pull", mockClient.RequestHistory[1].Path)
+	assert.Equal(t, "projects/test-project/subscriptions/orders-sub:acknowledge", mockClient.RequestHistory[2].Path)
+	assert.JSONEq(t, `{"ackIds":["a1","a2"]}`, string(mockClient.RequestHistory[2].Body))
+}
+
+func Test_Live_StreamRequiresSource(t *testing.T) {
+	server := &Server{Client: &utils.MockClient{}}
+
+	recorder := streamOnce(server, "/api/projects/test-project/stream")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "either subscription or topic is required")
+}
+
+func Test_Live_Publish(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["42"]}`)}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader(`{"data":{"id":1},"attributes":{"source":"ui"}}`)
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/projects/test-project/topics/orders/messages", body))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"messageIds":["42"]}`, recorder.Body.String())
+	assert.Equal(t, "projects/test-project/topics/orders:publish", mockClient.RequestHistory[0].Path)
+	assert.JSONEq(t, `{"messages":[{"data":"eyJpZCI6MX0=","attributes":{"source":"ui"}}]}`, string(mockClient.RequestHistory[0].Body))
+}
+
+func Test_Live_PublishInvalidMessages(t *testing.T) {
+	server := &Server{Client: &utils.MockClient{}}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/projects/test-project/topics/orders/messages", strings.NewReader(`{"data":`)))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid messages")
+}
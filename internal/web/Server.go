@@ -0,0 +1,104 @@
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+//go:embed static
+var static embed.FS
+
+// Server serves the single page UI browsing the emulator and the JSON API it
+// uses. The emulator can't list its projects, so they are given.
+type Server struct {
+	Client   utils.ClientInterface
+	Projects []string
+
+	// Configuration whose schemas encode the messages published and decode
+	// the messages streamed, when their topics are in it.
+	Configuration internal.Configuration
+
+	// Time between pulls of the streamed subscriptions, 1s when 0.
+	PullInterval time.Duration
+}
+
+// Handler returns the handler of the UI (/) and of its API (/api/).
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("GET /", http.FileServerFS(assets))
+
+	mux.HandleFunc("GET /api/projects", s.handleProjects)
+	mux.HandleFunc("GET /api/projects/{project}/topics", s.handleTopics)
+	mux.HandleFunc("GET /api/projects/{project}/schemas", s.handleSchemas)
+	mux.HandleFunc("GET /api/projects/{project}/schemas/{schema}/revisions", s.handleSchemaRevisions)
+	mux.HandleFunc("GET /api/projects/{project}/stream", s.handleStream)
+	mux.HandleFunc("POST /api/projects/{project}/topics/{topic}/messages", s.handlePublish)
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		Llog.Warn(fmt.Sprintf("Can't write the response: %s", err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	projects := s.Projects
+	if projects == nil {
+		projects = []string{}
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) handleTopics(w http.ResponseWriter, r *http.Request) {
+	topics, err := BrowseTopics(s.Client, r.PathValue("project"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, topics)
+}
+
+func (s *Server) handleSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := BrowseSchemas(s.Client, r.PathValue("project"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schemas)
+}
+
+func (s *Server) handleSchemaRevisions(w http.ResponseWriter, r *http.Request) {
+	revisions, err := pubsub.ListSchemaRevisions(s.Client, r.PathValue("project"), r.PathValue("schema"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	views := []SchemaView{}
+	for _, revision := range revisions {
+		views = append(views, newSchemaView(revision))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
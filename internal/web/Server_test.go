@@ -0,0 +1,101 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Server_Projects(t *testing.T) {
+	server := &Server{Client: &utils.MockClient{}, Projects: []string{"project-a", "project-b"}}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/projects", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `["project-a","project-b"]`, recorder.Body.String())
+}
+
+func Test_Server_Topics(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"topics":[
+				{"name":"projects/test-project/topics/orders","labels":{"team":"sales"},"schemaSettings":{"schema":"projects/test-project/schemas/order","encoding":"JSON"}},
+				{"name":"projects/test-project/topics/dead-orders"}
+			]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":[
+				{"name":"projects/test-project/subscriptions/orders-sub","labels":{"env":"dev"},"deadLetterPolicy":{"deadLetterTopic":"projects/test-project/topics/dead-orders","maxDeliveryAttempts":5}}
+			]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"subscriptions":["projects/test-project/subscriptions/orders-sub"]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/projects/test-project/topics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var topics []TopicView
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &topics))
+	assert.Equal(t, 2, len(topics))
+
+	assert.Equal(t, "dead-orders", topics[0].Name)
+	assert.Equal(t, []SubscriptionView{}, topics[0].Subscriptions)
+
+	assert.Equal(t, "orders", topics[1].Name)
+	assert.Equal(t, "sales", topics[1].Labels["team"])
+	assert.Equal(t, "projects/test-project/schemas/order", topics[1].SchemaSettings.Schema)
+	assert.Equal(t, 1, len(topics[1].Subscriptions))
+	assert.Equal(t, "orders-sub", topics[1].Subscriptions[0].Name)
+	assert.Equal(t, "dev", topics[1].Subscriptions[0].Labels["env"])
+	assert.Equal(t, 5, topics[1].Subscriptions[0].DeadLetterPolicy.MaxDeliveryAttempts)
+
+	assert.Equal(t, "projects/test-project/topics/orders/subscriptions", mockClient.RequestHistory[2].Path)
+}
+
+func Test_Server_Schemas(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"schemas":[
+				{"name":"projects/test-project/schemas/order","type":"AVRO","definition":"{}","revisionId":"abc"}
+			]}`)}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/projects/test-project/schemas", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[{"name":"order","resourceName":"projects/test-project/schemas/order","type":"AVRO","definition":"{}","revisionId":"abc"}]`, recorder.Body.String())
+}
+
+func Test_Server_TopicsError(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusNotFound}},
+		},
+	}
+	server := &Server{Client: mockClient, Projects: []string{"test-project"}}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/projects/missing/topics", nil))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "project not found")
+}
+
+func Test_Server_ServesUI(t *testing.T) {
+	server := &Server{Client: &utils.MockClient{}}
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "app.js")
+}
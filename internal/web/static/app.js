@@ -0,0 +1,293 @@
+"use strict";
+
+// Single page UI browsing the emulator through the API of the server. The
+// view is kept in the hash (#topics, #schemas or #live) and the project in the
+// "project" query parameter of the hash, so pages can be linked.
+
+const projectSelect = document.getElementById("project");
+const content = document.getElementById("content");
+const errorBox = document.getElementById("error");
+
+// Stream of the live view, closed when leaving it.
+let stream = null;
+
+function element(tag, attributes, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attributes || {})) {
+    node.setAttribute(name, value);
+  }
+  for (const child of children) {
+    if (child === null || child === undefined) {
+      continue;
+    }
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+async function getJSON(url) {
+  const response = await fetch(url);
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function currentRoute() {
+  const [view, query] = location.hash.replace(/^#/, "").split("?");
+  const params = new URLSearchParams(query || "");
+  return { view: view || "topics", project: params.get("project") || projectSelect.value };
+}
+
+function navigate(view, project) {
+  location.hash = `${view}?project=${encodeURIComponent(project)}`;
+}
+
+function renderLabels(labels) {
+  const entries = Object.entries(labels || {});
+  if (entries.length === 0) {
+    return null;
+  }
+  return element("div", {}, ...entries.map(([key, value]) => element("span", { class: "label" }, `${key}: ${value}`)));
+}
+
+function renderTopic(topic) {
+  const card = element("div", { class: "card" },
+    element("h2", {}, topic.name),
+    element("div", { class: "muted" }, topic.resourceName),
+    renderLabels(topic.labels));
+
+  if (topic.schemaSettings) {
+    const settings = topic.schemaSettings;
+    const revisions = [settings.firstRevisionId, settings.lastRevisionId].filter(Boolean).join(" … ");
+    card.append(element("div", {},
+      "Schema ",
+      element("a", { href: "#schemas?project=" + encodeURIComponent(projectSelect.value) }, settings.schema),
+      ` (${settings.encoding || "ENCODING_UNSPECIFIED"}${revisions ? ", revisions " + revisions : ""})`));
+  }
+  if (topic.kmsKeyName) {
+    card.append(element("div", { class: "muted" }, "KMS key " + topic.kmsKeyName));
+  }
+
+  if (topic.subscriptions.length === 0) {
+    card.append(element("div", { class: "muted" }, "No subscriptions"));
+    return card;
+  }
+  const list = element("ul", { class: "subscriptions" });
+  for (const subscription of topic.subscriptions) {
+    const deadLetter = subscription.deadLetterPolicy
+      ? element("div", { class: "muted" },
+        `Dead letters to ${subscription.deadLetterPolicy.deadLetterTopic}` +
+        (subscription.deadLetterPolicy.maxDeliveryAttempts ? ` after ${subscription.deadLetterPolicy.maxDeliveryAttempts} attempts` : ""))
+      : null;
+    list.append(element("li", {}, subscription.name, renderLabels(subscription.labels), deadLetter));
+  }
+  card.append(list);
+  return card;
+}
+
+function renderSchema(project, schema) {
+  const revisions = element("div");
+  const button = element("button", { type: "button" }, "Show revisions");
+  button.addEventListener("click", async () => {
+    button.disabled = true;
+    try {
+      const list = await getJSON(`api/projects/${encodeURIComponent(project)}/schemas/${encodeURIComponent(schema.name)}/revisions`);
+      revisions.replaceChildren(...list.map((revision) => element("details", {},
+        element("summary", {}, `${revision.revisionId} (${revision.revisionCreateTime || "unknown time"})`),
+        element("pre", {}, revision.definition))));
+    } catch (error) {
+      showError(error);
+    }
+  });
+
+  return element("div", { class: "card" },
+    element("h2", {}, schema.name),
+    element("div", { class: "muted" }, `${schema.type}, revision ${schema.revisionId || "unknown"}`),
+    element("pre", {}, schema.definition),
+    button,
+    revisions);
+}
+
+function parseAttributes(text) {
+  const attributes = {};
+  for (const line of text.split("\n")) {
+    if (line.trim() === "") {
+      continue;
+    }
+    const separator = line.indexOf("=");
+    if (separator < 1) {
+      throw new Error(`invalid attribute '${line}', use key=value`);
+    }
+    attributes[line.slice(0, separator).trim()] = line.slice(separator + 1).trim();
+  }
+  return attributes;
+}
+
+function renderMessage(message) {
+  const data = message.data !== undefined ? JSON.stringify(message.data, null, 2) : `base64: ${message.dataBase64 || ""}`;
+  return element("div", { class: "card message" },
+    element("div", { class: "muted" },
+      `${message.publishTime || ""} · ${message.topic} · ${message.messageId}` +
+      (message.orderingKey ? ` · ordering key ${message.orderingKey}` : "")),
+    renderLabels(message.attributes),
+    element("pre", {}, data));
+}
+
+function renderPublishForm(project, topics) {
+  const topicSelect = element("select", { name: "topic" }, ...topics.map((topic) => element("option", { value: topic.name }, topic.name)));
+  const data = element("textarea", { name: "data", placeholder: "JSON or text" });
+  const attributes = element("textarea", { name: "attributes", placeholder: "key=value, one per line" });
+  const orderingKey = element("input", { name: "orderingKey", placeholder: "Ordering key" });
+  const result = element("span", { class: "muted" });
+  const form = element("form", {},
+    element("label", {}, "Topic ", topicSelect),
+    data,
+    attributes,
+    orderingKey,
+    element("div", { class: "row" }, element("button", { type: "submit" }, "Publish"), result));
+
+  form.addEventListener("submit", async (event) => {
+    event.preventDefault();
+    errorBox.hidden = true;
+    try {
+      let payload;
+      try {
+        payload = JSON.parse(data.value);
+      } catch {
+        payload = data.value;
+      }
+      const message = { data: payload, attributes: parseAttributes(attributes.value) };
+      if (orderingKey.value) {
+        message.orderingKey = orderingKey.value;
+      }
+
+      const response = await fetch(`api/projects/${encodeURIComponent(project)}/topics/${encodeURIComponent(topicSelect.value)}/messages`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(message),
+      });
+      const body = await response.json();
+      if (!response.ok) {
+        throw new Error(body.error || response.statusText);
+      }
+      result.textContent = `Published ${body.messageIds.join(", ")}`;
+    } catch (error) {
+      showError(error);
+    }
+  });
+
+  return element("div", { class: "card" }, element("h2", {}, "Publish"), form);
+}
+
+function renderLiveViewer(project, topics) {
+  const source = element("select", { name: "source" });
+  for (const topic of topics) {
+    source.append(element("option", { value: "topic=" + encodeURIComponent(topic.name) }, `Tap topic ${topic.name}`));
+    for (const subscription of topic.subscriptions) {
+      source.append(element("option", { value: "subscription=" + encodeURIComponent(subscription.name) }, `Subscription ${subscription.name}`));
+    }
+  }
+  const ack = element("input", { type: "checkbox", name: "ack" });
+  const button = element("button", { type: "button" }, "Start");
+  const status = element("span", { class: "muted" });
+  const messages = element("div");
+
+  button.addEventListener("click", () => {
+    if (stream) {
+      closeStream();
+      button.textContent = "Start";
+      status.textContent = "Stopped";
+      return;
+    }
+
+    const query = source.value + (source.value.startsWith("subscription=") && ack.checked ? "&ack=true" : "");
+    stream = new EventSource(`api/projects/${encodeURIComponent(project)}/stream?${query}`);
+    button.textContent = "Stop";
+    status.textContent = "Connecting…";
+    stream.addEventListener("ready", (event) => {
+      status.textContent = `Streaming ${JSON.parse(event.data).subscription}`;
+    });
+    stream.addEventListener("message", (event) => {
+      messages.prepend(renderMessage(JSON.parse(event.data)));
+    });
+    stream.addEventListener("error", (event) => {
+      if (event.data) {
+        showError(new Error(JSON.parse(event.data).error));
+      }
+      closeStream();
+      button.textContent = "Start";
+      status.textContent = "Stopped";
+    });
+  });
+
+  return element("div", { class: "card" },
+    element("h2", {}, "Messages"),
+    element("div", { class: "row" },
+      source,
+      element("label", { title: "Consume and acknowledge the messages of the subscription instead of tapping its topic" }, ack, " Acknowledge"),
+      button,
+      status),
+    messages);
+}
+
+function closeStream() {
+  if (stream) {
+    stream.close();
+    stream = null;
+  }
+}
+
+function showError(error) {
+  errorBox.textContent = error.message;
+  errorBox.hidden = false;
+}
+
+async function render() {
+  const { view, project } = currentRoute();
+  errorBox.hidden = true;
+  closeStream();
+  for (const link of document.querySelectorAll("nav a")) {
+    link.classList.toggle("active", link.dataset.view === view);
+    link.href = `#${link.dataset.view}?project=${encodeURIComponent(project)}`;
+  }
+  if (!project) {
+    content.replaceChildren(element("p", {}, "No projects configured, use -projects or -config."));
+    return;
+  }
+  projectSelect.value = project;
+
+  content.replaceChildren(element("p", { class: "muted" }, "Loading…"));
+  try {
+    if (view === "live") {
+      const topics = await getJSON(`api/projects/${encodeURIComponent(project)}/topics`);
+      content.replaceChildren(renderPublishForm(project, topics), renderLiveViewer(project, topics));
+    } else if (view === "schemas") {
+      const schemas = await getJSON(`api/projects/${encodeURIComponent(project)}/schemas`);
+      content.replaceChildren(...(schemas.length ? schemas.map((schema) => renderSchema(project, schema)) : [element("p", {}, "No schemas")]));
+    } else {
+      const topics = await getJSON(`api/projects/${encodeURIComponent(project)}/topics`);
+      content.replaceChildren(...(topics.length ? topics.map(renderTopic) : [element("p", {}, "No topics")]));
+    }
+  } catch (error) {
+    content.replaceChildren();
+    showError(error);
+  }
+}
+
+async function start() {
+  try {
+    const projects = await getJSON("api/projects");
+    projectSelect.replaceChildren(...projects.map((project) => element("option", { value: project }, project)));
+  } catch (error) {
+    showError(error);
+  }
+
+  projectSelect.addEventListener("change", () => navigate(currentRoute().view, projectSelect.value));
+  document.getElementById("refresh").addEventListener("click", render);
+  window.addEventListener("hashchange", render);
+  render();
+}
+
+start();
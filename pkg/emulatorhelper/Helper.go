@@ -0,0 +1,200 @@
+// Package emulatorhelper provisions the Pub/Sub emulator from the
+// configuration files of the helper, and publishes and pulls messages
+// encoded with the schemas of the topics, so Go tests can drive the emulator
+// without the executable.
+//
+// Errors are always returned, nothing exits the process or prints to stdout.
+// Progress is logged to stderr, see SetLogOutput.
+package emulatorhelper
+
+import (
+	"io"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils/Llog"
+)
+
+// The types of the configuration file and of the emulator resources,
+// including every type of their fields, so a configuration can be built in
+// Go.
+type (
+	Configuration    = internal.Configuration
+	EmulatorSettings = internal.EmulatorSettings
+	Change           = internal.Change
+	ChangeAction     = internal.ChangeAction
+	ResourceKind     = internal.ResourceKind
+	DecodedMessage   = internal.DecodedMessage
+	Project          = pubsub.Project
+	Schema           = pubsub.Schema
+	SchemaRevision   = pubsub.SchemaRevision
+	Topic            = pubsub.Topic
+	SchemaSettings   = pubsub.SchemaSettings
+	SchemaEncoding   = pubsub.SchemaEncoding
+	Subscription     = pubsub.Subscription
+	DeadLetterPolicy = pubsub.DeadLetterPolicy
+	PushConfig       = pubsub.PushConfig
+	Message          = pubsub.Message
+	Labels           = pubsub.Labels
+
+	TopicMessageStoragePolicy                            = pubsub.TopicMessageStoragePolicy
+	TopicIngestionDataSourceSettings                     = pubsub.TopicIngestionDataSourceSettings
+	TopicIngestionDataSourceSettingsAwsKinesis           = pubsub.TopicIngestionDataSourceSettingsAwsKinesis
+	TopicIngestionDataSourceSettingsCloudStorage         = pubsub.TopicIngestionDataSourceSettingsCloudStorage
+	TopicIngestionDataSourceSettingsPlatformLogsSettings = pubsub.TopicIngestionDataSourceSettingsPlatformLogsSettings
+	AwsKinesisState                                      = pubsub.AwsKinesisState
+	CloudStorageState                                    = pubsub.CloudStorageState
+	CloudStorageTextFormat                               = pubsub.CloudStorageTextFormat
+)
+
+// The encodings of the messages of topics with a schema.
+const (
+	SchemaEncodingJSON   = pubsub.SCHEMA_ENCODING_JSON
+	SchemaEncodingBinary = pubsub.SCHEMA_ENCODING_BINARY
+)
+
+// The actions and kinds of resources of the changes returned by Plan.
+const (
+	ChangeCreate   = internal.CHANGE_ACTION_CREATE
+	ChangeDelete   = internal.CHANGE_ACTION_DELETE
+	ChangeRecreate = internal.CHANGE_ACTION_RECREATE
+	ChangeUpdate   = internal.CHANGE_ACTION_UPDATE
+
+	ResourceSchema       = internal.RESOURCE_KIND_SCHEMA
+	ResourceTopic        = internal.RESOURCE_KIND_TOPIC
+	ResourceSubscription = internal.RESOURCE_KIND_SUBSCRIPTION
+)
+
+// Client sends the requests to the REST API of the emulator.
+type Client = utils.ClientInterface
+
+// The ways of removing the pending messages of subscriptions.
+const (
+	PurgeAuto = internal.PURGE_METHOD_AUTO
+	PurgeSeek = internal.PURGE_METHOD_SEEK
+	PurgePull = internal.PURGE_METHOD_PULL
+)
+
+// NewClient returns a client of the emulator listening on host ("host:port").
+func NewClient(host string) Client {
+	return utils.NewClient(host, "v1")
+}
+
+// LoadConfiguration loads a configuration file. The schema definitions and
+// messages files it references are read relative to it.
+func LoadConfiguration(path string) (Configuration, error) {
+	return internal.LoadConfigurationFromFile(&utils.FileReader{}, path)
+}
+
+// ParseConfiguration parses the content of a configuration file. The files it
+// references are read relative to the working directory.
+func ParseConfiguration(content []byte) (Configuration, error) {
+	return internal.ParseConfiguration(&utils.FileReader{}, content, "")
+}
+
+// SetLogOutput sets where the progress of every Helper is logged, stderr by
+// default. Use io.Discard to silence it.
+func SetLogOutput(w io.Writer) {
+	Llog.SetOutput(w)
+}
+
+// Helper provisions a configuration in the emulator and exchanges messages
+// with it. It's not safe for concurrent use.
+type Helper struct {
+	client        Client
+	configuration Configuration
+	reconcile     bool
+}
+
+// New returns a Helper of the configuration, talking to the host of the
+// configuration unless an option says otherwise.
+func New(configuration Configuration, options ...Option) (*Helper, error) {
+	h := &Helper{configuration: configuration}
+	for _, option := range options {
+		if err := option(h); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.client == nil {
+		h.client = NewClient(h.configuration.Host)
+	}
+	return h, nil
+}
+
+// Open loads a configuration file and returns its Helper.
+func Open(path string, options ...Option) (*Helper, error) {
+	configuration, err := LoadConfiguration(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(configuration, options...)
+}
+
+// Configuration returns the configuration of the helper.
+func (h *Helper) Configuration() Configuration {
+	return h.configuration
+}
+
+// Client returns the client used to talk to the emulator.
+func (h *Helper) Client() Client {
+	return h.client
+}
+
+// Sync provisions the configuration like the executable does: every resource
+// in its projects is deleted and created again and the messages of the topics
+// are published, or with WithReconcile, the existing resources are updated in
+// place.
+func (h *Helper) Sync() error {
+	if h.reconcile {
+		return h.configuration.Reconcile(h.client)
+	}
+	return h.configuration.Sync(h.client)
+}
+
+// Teardown deletes every snapshot, subscription, topic and schema of the
+// projects of the configuration, including the ones not in it.
+func (h *Helper) Teardown() error {
+	return h.configuration.Teardown(h.client)
+}
+
+// Plan returns the changes a reconcile (see WithReconcile) would make,
+// without touching the emulator. Existing resources are only listed as
+// updated when some of their settings differ.
+func (h *Helper) Plan() ([]Change, error) {
+	return h.configuration.Plan(h.client)
+}
+
+// Purge removes the pending messages of every subscription of the
+// configuration, keeping the subscriptions. method is one of PurgeAuto,
+// PurgeSeek or PurgePull.
+func (h *Helper) Purge(method string) error {
+	return h.configuration.Purge(h.client, h.configuration.PurgeTargets(), method)
+}
+
+// PurgeSubscription removes the pending messages of a subscription.
+func (h *Helper) PurgeSubscription(project, subscription, method string) error {
+	return h.configuration.Purge(h.client, []internal.PurgeTarget{{Project: project, Subscription: subscription}}, method)
+}
+
+// Publish publishes messages to a topic, returning their ids. Their data is
+// written as JSON and encoded with the schema of the topic when it's in the
+// configuration, and their templates are expanded.
+func (h *Helper) Publish(project, topic string, messages ...Message) ([]string, error) {
+	expanded, err := h.configuration.ExpandMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	return h.configuration.PublishMessages(h.client, project, topic, expanded)
+}
+
+// Pull returns the pending messages of a subscription with their data
+// decoded. They are made available again right away unless WithAck is given.
+func (h *Helper) Pull(project, subscription string, options ...PullOption) ([]DecodedMessage, error) {
+	pull := pullOptions{maxMessages: defaultMaxMessages}
+	for _, option := range options {
+		option(&pull)
+	}
+	return h.configuration.PullMessages(h.client, project, subscription, pull.maxMessages, pull.ack)
+}
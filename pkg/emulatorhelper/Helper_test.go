@@ -0,0 +1,82 @@
+package emulatorhelper
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfiguration = `{
+	"host": "localhost:8085",
+	"avoidStartupCheck": true,
+	"provisioningConcurrency": 1,
+	"projects": [{"name": "test-project", "topics": [{"name": "test-topic", "subscriptions": [{"name": "test-subscription"}]}]}]
+}`
+
+func Test_Helper_New(t *testing.T) {
+	configuration, err := ParseConfiguration([]byte(testConfiguration))
+	assert.NoError(t, err)
+
+	helper, err := New(configuration, WithHost("127.0.0.1:9000"))
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9000", helper.Configuration().Host)
+	assert.NotNil(t, helper.Client())
+
+	_, err = New(configuration, WithHost("not a host"))
+	assert.EqualError(t, err, "the given host 'not a host' is invalid")
+
+	_, err = New(configuration, WithClient(nil))
+	assert.Error(t, err)
+}
+
+func Test_Helper_Open_Missing(t *testing.T) {
+	_, err := Open("does-not-exist.json")
+	assert.Error(t, err)
+}
+
+func Test_Helper_Sync(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+		},
+	}
+	configuration, err := ParseConfiguration([]byte(testConfiguration))
+	assert.NoError(t, err)
+	helper, err := New(configuration, WithClient(mockClient))
+	assert.NoError(t, err)
+
+	assert.NoError(t, helper.Sync())
+	assert.Equal(t, 4, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/test-project/topics/test-topic", mockClient.RequestHistory[2].Path)
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription", mockClient.RequestHistory[3].Path)
+}
+
+func Test_Helper_PublishAndPull(t *testing.T) {
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"receivedMessages":[{"ackId":"a1","message":{"data":"eyJpZCI6MX0=","messageId":"1"}}]}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+		},
+	}
+	configuration, err := ParseConfiguration([]byte(testConfiguration))
+	assert.NoError(t, err)
+	helper, err := New(configuration, WithClient(mockClient))
+	assert.NoError(t, err)
+
+	messageIds, err := helper.Publish("test-project", "test-topic", Message{Data: json.RawMessage(`{"id":1}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, messageIds)
+
+	messages, err := helper.Pull("test-project", "test-subscription", WithMaxMessages(3), WithAck())
+	assert.NoError(t, err)
+	assert.Equal(t, []DecodedMessage{{MessageId: "1", Data: json.RawMessage(`{"id":1}`)}}, messages)
+	assert.JSONEq(t, `{"returnImmediately":true,"maxMessages":3}`, string(mockClient.RequestHistory[1].Body))
+	assert.Equal(t, "projects/test-project/subscriptions/test-subscription:acknowledge", mockClient.RequestHistory[2].Path)
+}
@@ -0,0 +1,75 @@
+package emulatorhelper
+
+import "fmt"
+
+// Option configures a Helper.
+type Option func(*Helper) error
+
+// WithHost talks to the emulator listening on host ("host:port") instead of
+// the host of the configuration.
+func WithHost(host string) Option {
+	return func(h *Helper) error {
+		configuration, err := h.configuration.ReplaceHost(host)
+		if err != nil {
+			return err
+		}
+		h.configuration = configuration
+		return nil
+	}
+}
+
+// WithClient sends the requests through client, e.g. to use a fake emulator.
+func WithClient(client Client) Option {
+	return func(h *Helper) error {
+		if client == nil {
+			return fmt.Errorf("the client can't be nil")
+		}
+		h.client = client
+		return nil
+	}
+}
+
+// WithReconcile makes Sync keep the existing topics and subscriptions,
+// updating them in place, and delete only the resources not in the
+// configuration.
+func WithReconcile() Option {
+	return func(h *Helper) error {
+		h.reconcile = true
+		return nil
+	}
+}
+
+// WithoutStartupCheck makes Sync start right away, without waiting for the
+// emulator to answer.
+func WithoutStartupCheck() Option {
+	return func(h *Helper) error {
+		h.configuration.AvoidStartupCheck = true
+		return nil
+	}
+}
+
+const defaultMaxMessages = 10
+
+type pullOptions struct {
+	maxMessages int
+	ack         bool
+}
+
+// PullOption configures a Pull.
+type PullOption func(*pullOptions)
+
+// WithMaxMessages sets the maximum number of messages pulled, 10 by default.
+func WithMaxMessages(maxMessages int) PullOption {
+	return func(o *pullOptions) {
+		if maxMessages > 0 {
+			o.maxMessages = maxMessages
+		}
+	}
+}
+
+// WithAck acknowledges the messages pulled.
+func WithAck() PullOption {
+	return func(o *pullOptions) {
+		o.ack = true
+	}
+}
@@ -0,0 +1,69 @@
+package emulatorhelper_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/pkg/emulatorhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_Public_BuildConfiguration only uses the public package, like a module
+// depending on the helper would.
+func Test_Public_BuildConfiguration(t *testing.T) {
+	configuration := emulatorhelper.Configuration{
+		Host:              "localhost:8085",
+		AvoidStartupCheck: true,
+		Emulator:          &emulatorhelper.EmulatorSettings{Port: 8085},
+		Projects: []emulatorhelper.Project{{
+			Name: "shop",
+			Schemas: []emulatorhelper.Schema{{
+				Name: "order",
+				Type: "AVRO",
+				Revisions: []emulatorhelper.SchemaRevision{
+					{Alias: "v1", Definition: `{"type":"string"}`},
+				},
+			}},
+			Topics: []emulatorhelper.Topic{
+				{
+					Name:                 "orders",
+					Labels:               emulatorhelper.Labels{"team": "checkout"},
+					MessageStoragePolicy: emulatorhelper.TopicMessageStoragePolicy{AllowedPersistenceRegions: []string{"europe-west1"}},
+					SchemaSettings: &emulatorhelper.SchemaSettings{
+						Schema:          "order",
+						Encoding:        emulatorhelper.SchemaEncodingJSON,
+						FirstRevisionId: "v1",
+						LastRevisionId:  "v1",
+					},
+					Subscriptions: []emulatorhelper.Subscription{
+						{
+							Name:             "orders-billing",
+							DeadLetterPolicy: &emulatorhelper.DeadLetterPolicy{DeadLetterTopic: "orders-dead-letter", MaxDeliveryAttempts: 5},
+						},
+						{
+							Name:       "orders-webhook",
+							PushConfig: &emulatorhelper.PushConfig{PushEndpoint: "http://localhost:8080/orders"},
+						},
+					},
+					Messages: []emulatorhelper.Message{{Data: json.RawMessage(`"first"`)}},
+				},
+				{Name: "orders-dead-letter"},
+			},
+		}},
+	}
+
+	// The configuration built in Go is the same as the one of the file
+	content, err := json.Marshal(configuration)
+	assert.NoError(t, err)
+	parsed, err := emulatorhelper.ParseConfiguration(content)
+	assert.NoError(t, err)
+
+	topic := parsed.Projects[0].Topics[0]
+	assert.Equal(t, configuration.Projects[0].Topics[0].SchemaSettings, topic.SchemaSettings)
+	assert.Equal(t, configuration.Projects[0].Topics[0].Subscriptions, topic.Subscriptions)
+	assert.Equal(t, configuration.Projects[0].Schemas[0].Revisions, parsed.Projects[0].Schemas[0].Revisions)
+
+	helper, err := emulatorhelper.New(parsed)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:8085", helper.Configuration().Host)
+}
@@ -0,0 +1,158 @@
+// Package emulatortest provisions a configuration in the Pub/Sub emulator for
+// a single test. Every project of the configuration gets a unique name, so
+// tests sharing topic and subscription names can run in parallel against the
+// same emulator, and everything is deleted when the test ends.
+//
+//	func TestOrders(t *testing.T) {
+//		t.Parallel()
+//		configuration, _ := emulatorhelper.LoadConfiguration("testdata/config.json")
+//		emulator := emulatortest.New(t, configuration)
+//		emulator.Publish("shop", "orders", emulatorhelper.Message{Data: json.RawMessage(`{"id": 1}`)})
+//		runService(t, emulator.Project("shop"))
+//	}
+package emulatortest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/pkg/emulatorhelper"
+)
+
+// HostEnvironmentVariable is the variable the Pub/Sub client libraries read
+// the host of the emulator from. When set, it's used instead of the host of
+// the configuration.
+const HostEnvironmentVariable = "PUBSUB_EMULATOR_HOST"
+
+// Emulator is the topology of a configuration provisioned for a test.
+type Emulator struct {
+	t        testing.TB
+	helper   *emulatorhelper.Helper
+	projects map[string]string
+}
+
+// New provisions the configuration in projects named after the configured
+// ones plus a unique suffix, and deletes everything in them in t.Cleanup. The
+// test fails right away if it can't be provisioned. The options are given to
+// emulatorhelper.New, after the host of HostEnvironmentVariable.
+func New(t testing.TB, configuration emulatorhelper.Configuration, options ...emulatorhelper.Option) *Emulator {
+	t.Helper()
+
+	suffix, err := uniqueSuffix()
+	if err != nil {
+		t.Fatalf("can't generate the name of the projects: %s", err)
+	}
+	isolated, projects := isolateProjects(configuration, suffix)
+
+	if host := os.Getenv(HostEnvironmentVariable); host != "" {
+		options = append([]emulatorhelper.Option{emulatorhelper.WithHost(host)}, options...)
+	}
+	helper, err := emulatorhelper.New(isolated, options...)
+	if err != nil {
+		t.Fatalf("can't create the emulator helper: %s", err)
+	}
+
+	// Registered before syncing, so a partial provisioning is deleted as well
+	t.Cleanup(func() {
+		if err := helper.Teardown(); err != nil {
+			t.Errorf("error tearing down the emulator projects: %s", err)
+		}
+	})
+
+	if err := helper.Sync(); err != nil {
+		t.Fatalf("error provisioning the emulator: %s", err)
+	}
+
+	return &Emulator{t: t, helper: helper, projects: projects}
+}
+
+// uniqueSuffix returns 8 random hexadecimal characters.
+func uniqueSuffix() (string, error) {
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// Helper returns the helper of the provisioned configuration, whose project
+// names are the unique ones.
+func (e *Emulator) Helper() *emulatorhelper.Helper {
+	return e.helper
+}
+
+// Configuration returns the provisioned configuration, whose project names
+// are the unique ones.
+func (e *Emulator) Configuration() emulatorhelper.Configuration {
+	return e.helper.Configuration()
+}
+
+// Host returns the host of the emulator.
+func (e *Emulator) Host() string {
+	return e.helper.Configuration().Host
+}
+
+// Project returns the unique name given to a project of the configuration.
+func (e *Emulator) Project(name string) string {
+	e.t.Helper()
+	project, exists := e.projects[name]
+	if !exists {
+		e.t.Fatalf("project '%s' is not in the configuration", name)
+	}
+	return project
+}
+
+// Topic returns the resource name of a topic of a project of the
+// configuration, like projects/{project}-{suffix}/topics/{topic}.
+func (e *Emulator) Topic(project, topic string) string {
+	e.t.Helper()
+	return pubsub.GetResourceNameForTopic(e.Project(project), topic)
+}
+
+// Subscription returns the resource name of a subscription of a project of
+// the configuration.
+func (e *Emulator) Subscription(project, subscription string) string {
+	e.t.Helper()
+	return pubsub.GetResourceNameForSubscription(e.Project(project), subscription)
+}
+
+// Schema returns the resource name of a schema of a project of the
+// configuration.
+func (e *Emulator) Schema(project, schema string) string {
+	e.t.Helper()
+	return pubsub.GetResourceNameForSchema(e.Project(project), schema)
+}
+
+// Publish publishes messages to a topic of a project of the configuration,
+// returning their ids. The test fails right away if they can't be published.
+func (e *Emulator) Publish(project, topic string, messages ...emulatorhelper.Message) []string {
+	e.t.Helper()
+	messageIds, err := e.helper.Publish(e.Project(project), topic, messages...)
+	if err != nil {
+		e.t.Fatalf("error publishing to topic '%s' of project '%s': %s", topic, project, err)
+	}
+	return messageIds
+}
+
+// Pull returns the pending messages of a subscription of a project of the
+// configuration. The test fails right away if they can't be pulled.
+func (e *Emulator) Pull(project, subscription string, options ...emulatorhelper.PullOption) []emulatorhelper.DecodedMessage {
+	e.t.Helper()
+	messages, err := e.helper.Pull(e.Project(project), subscription, options...)
+	if err != nil {
+		e.t.Fatalf("error pulling from subscription '%s' of project '%s': %s", subscription, project, err)
+	}
+	return messages
+}
+
+// Purge removes the pending messages of every subscription, so the topology
+// can be reused between the steps of a test.
+func (e *Emulator) Purge() {
+	e.t.Helper()
+	if err := e.helper.Purge(emulatorhelper.PurgeAuto); err != nil {
+		e.t.Fatalf("error purging the subscriptions: %s", err)
+	}
+}
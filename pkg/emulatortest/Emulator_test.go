@@ -0,0 +1,105 @@
+package emulatortest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/utils"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/pkg/emulatorhelper"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfiguration() emulatorhelper.Configuration {
+	return emulatorhelper.Configuration{
+		Host:                    "localhost:8085",
+		AvoidStartupCheck:       true,
+		ProvisioningConcurrency: 1,
+		Projects: []emulatorhelper.Project{{
+			Name: "shop",
+			Topics: []emulatorhelper.Topic{
+				{Name: "orders", Subscriptions: []emulatorhelper.Subscription{{
+					Name:             "orders-subscription",
+					DeadLetterPolicy: &emulatorhelper.DeadLetterPolicy{DeadLetterTopic: "projects/shop/topics/orders-dead-letter"},
+				}}},
+				{Name: "orders-dead-letter"},
+			},
+		}},
+	}
+}
+
+func Test_Emulator_IsolateProjects(t *testing.T) {
+	configuration := testConfiguration()
+
+	isolated, projects := isolateProjects(configuration, "1a2b3c4d")
+
+	assert.Equal(t, map[string]string{"shop": "shop-1a2b3c4d"}, projects)
+	assert.Equal(t, "shop-1a2b3c4d", isolated.Projects[0].Name)
+	assert.Equal(t, "projects/shop-1a2b3c4d/topics/orders-dead-letter", isolated.Projects[0].Topics[0].Subscriptions[0].DeadLetterPolicy.DeadLetterTopic)
+
+	// The original configuration is kept
+	assert.Equal(t, "shop", configuration.Projects[0].Name)
+	assert.Equal(t, "projects/shop/topics/orders-dead-letter", configuration.Projects[0].Topics[0].Subscriptions[0].DeadLetterPolicy.DeadLetterTopic)
+}
+
+func Test_Emulator_MoveResourceName(t *testing.T) {
+	projects := map[string]string{"shop": "shop-1a2b3c4d"}
+
+	assert.Equal(t, "projects/shop-1a2b3c4d/schemas/order", moveResourceName("projects/shop/schemas/order", projects))
+	assert.Equal(t, "projects/other/topics/orders", moveResourceName("projects/other/topics/orders", projects))
+	assert.Equal(t, "orders-dead-letter", moveResourceName("orders-dead-letter", projects))
+}
+
+func Test_Emulator_New(t *testing.T) {
+	t.Setenv(HostEnvironmentVariable, "127.0.0.1:9085")
+	mockClient := &utils.MockClient{
+		ResponseHistory: []utils.MockClientHistoryResponse{
+			// Sync
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+			{Response: utils.Response{StatusCode: http.StatusOK}},
+			// Publish
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{"messageIds":["1"]}`)}},
+			// Teardown
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+			{Response: utils.Response{StatusCode: http.StatusOK, Body: []byte(`{}`)}},
+		},
+	}
+
+	var project string
+	t.Run("provisioning", func(t *testing.T) {
+		emulator := New(t, testConfiguration(), emulatorhelper.WithClient(mockClient))
+
+		project = emulator.Project("shop")
+		assert.True(t, strings.HasPrefix(project, "shop-"))
+		assert.Equal(t, 13, len(project))
+		assert.Equal(t, "127.0.0.1:9085", emulator.Host())
+		assert.Equal(t, "projects/"+project+"/topics/orders", emulator.Topic("shop", "orders"))
+		assert.Equal(t, "projects/"+project+"/subscriptions/orders-subscription", emulator.Subscription("shop", "orders-subscription"))
+		assert.Equal(t, "projects/"+project+"/topics/orders", mockClient.RequestHistory[2].Path)
+
+		messageIds := emulator.Publish("shop", "orders", emulatorhelper.Message{Data: json.RawMessage(`{"id":1}`)})
+		assert.Equal(t, []string{"1"}, messageIds)
+		assert.Equal(t, "projects/"+project+"/topics/orders:publish", mockClient.RequestHistory[5].Path)
+	})
+
+	// Everything is deleted once the test ends
+	assert.Equal(t, 10, len(mockClient.RequestHistory))
+	assert.Equal(t, "projects/"+project+"/snapshots", mockClient.RequestHistory[6].Path)
+	assert.Equal(t, "projects/"+project+"/schemas?view=FULL", mockClient.RequestHistory[9].Path)
+}
+
+func Test_Emulator_UniqueProjects(t *testing.T) {
+	first, err := uniqueSuffix()
+	assert.NoError(t, err)
+	second, err := uniqueSuffix()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 8, len(first))
+	assert.NotEqual(t, first, second)
+}
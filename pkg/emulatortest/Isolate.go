@@ -0,0 +1,63 @@
+package emulatortest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/internal/pubsub"
+	"github.com/EyLuismi/gcloud-pubsub-emulator-helper/pkg/emulatorhelper"
+)
+
+// isolateProjects returns a copy of the configuration whose projects are
+// named "{project}-{suffix}", and the new name of every project. Dead letter
+// topics and schemas referenced by their resource names are moved to the
+// new projects as well. The given configuration is not modified.
+func isolateProjects(configuration emulatorhelper.Configuration, suffix string) (emulatorhelper.Configuration, map[string]string) {
+	projects := map[string]string{}
+	for _, project := range configuration.Projects {
+		projects[project.Name] = fmt.Sprintf("%s-%s", project.Name, suffix)
+	}
+
+	isolated := configuration
+	isolated.Projects = make([]pubsub.Project, len(configuration.Projects))
+	for i, project := range configuration.Projects {
+		project.Name = projects[project.Name]
+		project.Schemas = append([]pubsub.Schema(nil), project.Schemas...)
+		project.Topics = append([]pubsub.Topic(nil), project.Topics...)
+
+		for j, topic := range project.Topics {
+			if topic.SchemaSettings != nil {
+				schemaSettings := *topic.SchemaSettings
+				schemaSettings.Schema = moveResourceName(schemaSettings.Schema, projects)
+				topic.SchemaSettings = &schemaSettings
+			}
+
+			topic.Subscriptions = append([]pubsub.Subscription(nil), topic.Subscriptions...)
+			for k, subscription := range topic.Subscriptions {
+				if subscription.DeadLetterPolicy != nil {
+					deadLetterPolicy := *subscription.DeadLetterPolicy
+					deadLetterPolicy.DeadLetterTopic = moveResourceName(deadLetterPolicy.DeadLetterTopic, projects)
+					subscription.DeadLetterPolicy = &deadLetterPolicy
+				}
+				topic.Subscriptions[k] = subscription
+			}
+			project.Topics[j] = topic
+		}
+		isolated.Projects[i] = project
+	}
+
+	return isolated, projects
+}
+
+// moveResourceName renames the project of a resource name like
+// projects/{project}/topics/{topic}. Anything else is returned as is.
+func moveResourceName(resourceName string, projects map[string]string) string {
+	parts := strings.SplitN(resourceName, "/", 3)
+	if len(parts) != 3 || parts[0] != "projects" {
+		return resourceName
+	}
+	if project, exists := projects[parts[1]]; exists {
+		return fmt.Sprintf("projects/%s/%s", project, parts[2])
+	}
+	return resourceName
+}